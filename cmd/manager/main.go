@@ -14,6 +14,7 @@ import (
 	"github.com/ucloud/redis-operator/pkg/apis"
 	"github.com/ucloud/redis-operator/pkg/controller"
 	"github.com/ucloud/redis-operator/pkg/controller/rediscluster"
+	"github.com/ucloud/redis-operator/pkg/controller/redisfailover"
 	clusterMetrics "github.com/ucloud/redis-operator/pkg/metrics"
 	"github.com/ucloud/redis-operator/pkg/util"
 
@@ -55,6 +56,7 @@ func main() {
 	pflag.CommandLine.AddFlagSet(zap.FlagSet())
 
 	pflag.CommandLine.AddFlagSet(rediscluster.FlagSet())
+	pflag.CommandLine.AddFlagSet(redisfailover.FlagSet())
 
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
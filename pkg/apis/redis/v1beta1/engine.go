@@ -0,0 +1,123 @@
+package v1beta1
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// imageVersionRE matches the version at the start of the tag of an image, like 7.2.4-alpine or v6.3.4
+var imageVersionRE = regexp.MustCompile(`^v?([0-9]+)(\.[0-9]+)*(-|$)`)
+
+// Engine is the redis compatible server run by the cluster
+type Engine string
+
+const (
+	EngineRedis  Engine = "redis"
+	EngineValkey Engine = "valkey"
+	EngineKeyDB  Engine = "keydb"
+)
+
+// EngineProfile describes how to run an engine and what it is able to do
+type EngineProfile struct {
+	// ServerBinary starts the server
+	ServerBinary string
+	// CliBinary is used by the probes and the shutdown script
+	CliBinary string
+	// SentinelBinary starts a sentinel, with the config file and the SentinelArgs
+	SentinelBinary string
+	SentinelArgs   []string
+	// DefaultImage is used when spec.image or spec.sentinel.image is empty
+	DefaultImage string
+	// ActiveReplica is true if all the nodes can accept writes and replicate each other
+	ActiveReplica bool
+	// CheckAOFBinary truncates the AOF files to a timestamp, empty if the engine has no multi part AOF
+	CheckAOFBinary string
+	// MultiPartAOFMajor is the major version of the image from which the engine has multi part AOF,
+	// 0 if all its versions have it
+	MultiPartAOFMajor int
+}
+
+var engineProfiles = map[Engine]EngineProfile{
+	EngineRedis: {
+		ServerBinary:      "redis-server",
+		CliBinary:         "redis-cli",
+		SentinelBinary:    "redis-server",
+		SentinelArgs:      []string{"--sentinel"},
+		DefaultImage:      defaultRedisImage,
+		CheckAOFBinary:    "redis-check-aof",
+		MultiPartAOFMajor: 7,
+	},
+	EngineValkey: {
+		ServerBinary:   "valkey-server",
+		CliBinary:      "valkey-cli",
+		SentinelBinary: "valkey-server",
+		SentinelArgs:   []string{"--sentinel"},
+		DefaultImage:   "valkey/valkey:7.2.5-alpine",
+		CheckAOFBinary: "valkey-check-aof",
+	},
+	EngineKeyDB: {
+		ServerBinary:   "keydb-server",
+		CliBinary:      "keydb-cli",
+		SentinelBinary: "keydb-sentinel",
+		DefaultImage:   "eqalpha/keydb:alpine_x86_64_v6.3.4",
+		ActiveReplica:  true,
+	},
+}
+
+// hasMultiPartAOF is true when the image runs a version of the engine with multi part AOF, the version is
+// read from the tag of the image, like 7.2.4-alpine
+func (p EngineProfile) hasMultiPartAOF(image string) bool {
+	if p.CheckAOFBinary == "" {
+		return false
+	}
+	if p.MultiPartAOFMajor == 0 {
+		return true
+	}
+	major, ok := getImageMajorVersion(image)
+	return ok && major >= p.MultiPartAOFMajor
+}
+
+// getImageMajorVersion returns the major version in the tag of the image, false if the tag has no version
+func getImageMajorVersion(image string) (int, bool) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	// a port of the registry, the image has no tag
+	if i < 0 || strings.Contains(image[i+1:], "/") {
+		return 0, false
+	}
+	match := imageVersionRE.FindStringSubmatch(image[i+1:])
+	if len(match) == 0 {
+		return 0, false
+	}
+	major, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return major, true
+}
+
+// GetEngineProfile returns the profile of the engine, redis if it is not set
+func (r *RedisCluster) GetEngineProfile() EngineProfile {
+	if profile, ok := engineProfiles[r.Spec.Engine]; ok {
+		return profile
+	}
+	return engineProfiles[EngineRedis]
+}
+
+// IsActiveReplica is true when all the nodes are masters replicating each other, without sentinel
+func (r *RedisCluster) IsActiveReplica() bool {
+	return r.Spec.ActiveReplica
+}
+
+// IsStandby is true when the master of the cluster replicates from an external master
+func (r *RedisCluster) IsStandby() bool {
+	return r.Spec.ReplicaOf != nil
+}
+
+// IsPointInTimeRestore is true when the cluster is restored from the AOF files shipped by spec.pitr
+func (r *RedisCluster) IsPointInTimeRestore() bool {
+	return r.Spec.Restore != nil && r.Spec.Restore.PointInTime != ""
+}
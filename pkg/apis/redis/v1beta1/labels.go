@@ -4,4 +4,8 @@ const (
 	OperatorName      = "redis-operator"
 	LabelManagedByKey = "app.kubernetes.io/managed-by"
 	LabelNameKey      = "redis.kun/v1beta1"
+	LabelRoleKey      = "redis.kun/role"
+
+	RoleMaster  = "master"
+	RoleReplica = "replica"
 )
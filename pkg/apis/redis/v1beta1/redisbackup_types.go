@@ -0,0 +1,99 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	BackupKind = "RedisBackup"
+)
+
+// RedisBackupSpec defines the desired state of RedisBackup
+// +k8s:openapi-gen=true
+type RedisBackupSpec struct {
+	// ClusterName is the RedisCluster to back up, in the same namespace
+	ClusterName string `json:"clusterName"`
+	// Storage is where the snapshot is uploaded
+	Storage BackupStorage `json:"storage"`
+	// Image runs the upload job, it needs sh, sha256sum and the minio client mc
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// BackupStorage defines where the backups are stored
+type BackupStorage struct {
+	S3 *S3Storage `json:"s3,omitempty"`
+}
+
+// S3Storage defines an S3 compatible bucket
+type S3Storage struct {
+	// Endpoint is the url of the S3 service, like https://s3.amazonaws.com or http://minio:9000
+	Endpoint string `json:"endpoint"`
+	Bucket   string `json:"bucket"`
+	// Prefix is prepended to the object name
+	Prefix string `json:"prefix,omitempty"`
+	// CredentialsSecret is the name of the secret with the keys AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// BackupPhase is the phase of a backup
+type BackupPhase string
+
+const (
+	BackupPhasePending   BackupPhase = "Pending"
+	BackupPhaseSnapshot  BackupPhase = "Snapshotting"
+	BackupPhaseUploading BackupPhase = "Uploading"
+	BackupPhaseCompleted BackupPhase = "Completed"
+	BackupPhaseFailed    BackupPhase = "Failed"
+)
+
+// RedisBackupStatus defines the observed state of RedisBackup
+// +k8s:openapi-gen=true
+type RedisBackupStatus struct {
+	Phase   BackupPhase `json:"phase,omitempty"`
+	Message string      `json:"message,omitempty"`
+	// SourcePod is the replica the snapshot is taken from
+	SourcePod string `json:"sourcePod,omitempty"`
+	// SourceOffset is the replication offset of the replica when the snapshot started
+	SourceOffset int64 `json:"sourceOffset,omitempty"`
+	// Location is the url of the uploaded snapshot
+	Location string `json:"location,omitempty"`
+	// Size is the size of the snapshot in bytes
+	Size int64 `json:"size,omitempty"`
+	// Checksum is the sha256 of the snapshot
+	Checksum       string `json:"checksum,omitempty"`
+	StartTime      string `json:"startTime,omitempty"`
+	SnapshotTime   string `json:"snapshotTime,omitempty"`
+	CompletionTime string `json:"completionTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedisBackup is the Schema for the redisbackups API
+// +k8s:openapi-gen=true
+type RedisBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RedisBackupSpec   `json:"spec,omitempty"`
+	Status RedisBackupStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedisBackupList contains a list of RedisBackup
+type RedisBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RedisBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RedisBackup{}, &RedisBackupList{})
+}
+
+// IsFinished is true when the backup completed or failed, it is never retried
+func (b *RedisBackup) IsFinished() bool {
+	return b.Status.Phase == BackupPhaseCompleted || b.Status.Phase == BackupPhaseFailed
+}
@@ -0,0 +1,79 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	BackupScheduleKind = "RedisBackupSchedule"
+
+	// LabelBackupScheduleKey is set on the backups created by a schedule
+	LabelBackupScheduleKey = "redis.kun/backup-schedule"
+	// BackupCleanupFinalizer deletes the snapshot from the storage before the backup is deleted
+	BackupCleanupFinalizer = "redis.kun/backup-cleanup"
+)
+
+// RedisBackupScheduleSpec defines the desired state of RedisBackupSchedule
+// +k8s:openapi-gen=true
+type RedisBackupScheduleSpec struct {
+	// ClusterName is the RedisCluster to back up, in the same namespace
+	ClusterName string `json:"clusterName"`
+	// Schedule is a cron expression in UTC, like "0 3 * * *" or "@daily"
+	Schedule string `json:"schedule"`
+	// Storage is where the snapshots are uploaded
+	Storage BackupStorage `json:"storage"`
+	// Retention defines when the backups are deleted, with their snapshot
+	Retention BackupRetention `json:"retention,omitempty"`
+	// Suspend stops creating new backups, the retention is still applied
+	Suspend bool `json:"suspend,omitempty"`
+	// Image runs the upload job, it needs sh, sha256sum and the minio client mc
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// BackupRetention defines how many completed backups are kept
+type BackupRetention struct {
+	// MaxCount is the number of completed backups kept, 0 keeps all of them
+	MaxCount int32 `json:"maxCount,omitempty"`
+	// MaxAge is the duration a completed backup is kept, like "168h", empty keeps them forever
+	MaxAge string `json:"maxAge,omitempty"`
+}
+
+// RedisBackupScheduleStatus defines the observed state of RedisBackupSchedule
+// +k8s:openapi-gen=true
+type RedisBackupScheduleStatus struct {
+	// LastScheduleTime is the last time a backup was due, created or skipped
+	LastScheduleTime string `json:"lastScheduleTime,omitempty"`
+	// LastBackup is the name of the last backup created
+	LastBackup string `json:"lastBackup,omitempty"`
+	// LastSuccessfulTime is the completion time of the last completed backup
+	LastSuccessfulTime string `json:"lastSuccessfulTime,omitempty"`
+	// Message tells why the last run was skipped
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedisBackupSchedule is the Schema for the redisbackupschedules API
+// +k8s:openapi-gen=true
+type RedisBackupSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RedisBackupScheduleSpec   `json:"spec,omitempty"`
+	Status RedisBackupScheduleStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedisBackupScheduleList contains a list of RedisBackupSchedule
+type RedisBackupScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RedisBackupSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RedisBackupSchedule{}, &RedisBackupScheduleList{})
+}
@@ -12,6 +12,7 @@ import (
 // +k8s:openapi-gen=true
 type RedisClusterSpec struct {
 	Size               int32                         `json:"size,omitempty"`
+	Engine             Engine                        `json:"engine,omitempty"`
 	Resources          corev1.ResourceRequirements   `json:"resources,omitempty"`
 	Image              string                        `json:"image,omitempty"`
 	ImagePullPolicy    corev1.PullPolicy             `json:"imagePullPolicy,omitempty"`
@@ -29,8 +30,45 @@ type RedisClusterSpec struct {
 	Annotations        map[string]string             `json:"annotations,omitempty"`
 	DisablePersistence bool                          `json:"disablePersistence,omitempty"`
 
+	// ActiveReplica makes every node a master replicating all the others, sentinel is not deployed.
+	// Only supported by the keydb engine.
+	ActiveReplica bool `json:"activeReplica,omitempty"`
+
 	// Sentinel defines its cluster settings
 	Sentinel SentinelSettings `json:"sentinel,omitempty"`
+
+	// ReadReplicas defines a group of hidden replicas that are never promoted to master
+	ReadReplicas *ReadReplicaSettings `json:"readReplicas,omitempty"`
+
+	// UpgradeStrategy defines how an image change is rolled out
+	UpgradeStrategy *UpgradeStrategy `json:"upgradeStrategy,omitempty"`
+
+	// Restore loads the data of a new cluster from an RDB, it is ignored once the cluster exists
+	Restore *RestoreSpec `json:"restore,omitempty"`
+
+	// PITR ships the AOF files of the master to an S3 compatible storage, for a point-in-time restore
+	PITR *PITRSpec `json:"pitr,omitempty"`
+
+	// CloneFrom copies the data of another live cluster into a new cluster, it is ignored once the cluster exists
+	CloneFrom *CloneSource `json:"cloneFrom,omitempty"`
+
+	// ReplicaOf runs the cluster as a standby, its master replicates from an external master.
+	// Removing it promotes the cluster.
+	ReplicaOf *ReplicaOfSpec `json:"replicaOf,omitempty"`
+
+	// DeletionPolicy is what happens to the data when the cluster is deleted, Delete, Retain or Snapshot.
+	// It defaults to Retain with storage.keepAfterDeletion, Delete otherwise.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// FinalBackup is where the backup taken before the deletion is uploaded, with the Snapshot policy
+	FinalBackup *FinalBackupSpec `json:"finalBackup,omitempty"`
+
+	// Suspended saves the data and scales the cluster down to zero pod, the persistent volume claims are kept.
+	// Setting it back to false resumes the cluster with its data.
+	Suspended bool `json:"suspended,omitempty"`
+
+	// Memory derives the maxmemory of the redis from their memory limit
+	Memory *MemorySettings `json:"memory,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -81,6 +119,158 @@ type SentinelSettings struct {
 	Annotations      map[string]string             `json:"annotations,omitempty"`
 }
 
+// ReadReplicaSettings defines the specification of the read replicas, they are started
+// with slave-priority 0 so sentinel never promotes them
+type ReadReplicaSettings struct {
+	Replicas     int32                       `json:"replicas,omitempty"`
+	Resources    corev1.ResourceRequirements `json:"resources,omitempty"`
+	Affinity     *corev1.Affinity            `json:"affinity,omitempty"`
+	ToleRations  []corev1.Toleration         `json:"tolerations,omitempty"`
+	NodeSelector map[string]string           `json:"nodeSelector,omitempty"`
+	Annotations  map[string]string           `json:"annotations,omitempty"`
+}
+
+// UpgradeStrategy defines how the redis pods are upgraded
+type UpgradeStrategy struct {
+	// Canary upgrades a single replica and checks it before upgrading the others
+	Canary *CanaryUpgrade `json:"canary,omitempty"`
+}
+
+// CanaryUpgrade defines the health gates the canary replica must pass
+type CanaryUpgrade struct {
+	// SoakSeconds is how long the canary must stay healthy before the upgrade continues
+	SoakSeconds int32 `json:"soakSeconds,omitempty"`
+	// MaxErrors is the number of new error replies tolerated on the canary during the soak
+	MaxErrors int64 `json:"maxErrors,omitempty"`
+	// SmokeCommand is a redis command run on the canary, the canary fails if it returns an error
+	SmokeCommand []string `json:"smokeCommand,omitempty"`
+}
+
+// RestoreSpec defines where the data of a new cluster comes from
+type RestoreSpec struct {
+	From RestoreSource `json:"from"`
+	// PointInTime replays the AOF files shipped by spec.pitr up to this time, like "2019-08-01T10:00:00Z".
+	// The url is then the location of the AOF files of a cluster, like s3://bucket/prefix/namespace/name/aof
+	PointInTime string `json:"pointInTime,omitempty"`
+	// Image downloads the RDB, it needs sh and the minio client mc for an s3 url, or curl for an http url
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// RestoreSource defines an RDB in an S3 compatible storage, or behind an http url
+type RestoreSource struct {
+	// URL of the RDB, like s3://bucket/key as in the location of a RedisBackup, or https://host/dump.rdb
+	URL string `json:"url"`
+	// Endpoint is the url of the S3 service, required by an s3 url
+	Endpoint string `json:"endpoint,omitempty"`
+	// CredentialsSecret is the name of the secret with the keys AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY,
+	// required by an s3 url
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// CloneSource defines the RedisCluster a new cluster is cloned from
+type CloneSource struct {
+	// Namespace of the source, by default the namespace of the cluster. Another namespace needs a cluster scoped operator
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// ReplicaOfSpec defines the external master of a standby cluster
+type ReplicaOfSpec struct {
+	Host string `json:"host"`
+	Port int32  `json:"port,omitempty"`
+	// AuthSecret is the name of the secret with the key password, the password of the external master
+	AuthSecret string `json:"authSecret,omitempty"`
+}
+
+// DeletionPolicy defines what happens to the data of a deleted cluster
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the persistent volume claims with the cluster
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the persistent volume claims, a new cluster with the same name adopts them
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicySnapshot takes a final backup, then deletes the persistent volume claims
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+const (
+	// ClusterDeletionFinalizer applies the deletion policy before the cluster is deleted
+	ClusterDeletionFinalizer = "redis.kun/deletion-policy"
+	// AnnotationDeletionProtection set to "true" keeps the cluster and its data when it is deleted
+	AnnotationDeletionProtection = "redis.kun/deletion-protection"
+	// LabelRetainedKey is set on the persistent volume claims kept after the deletion of the cluster
+	LabelRetainedKey = "redis.kun/retained-from"
+	// LabelFinalBackupKey is set on the backup taken before the deletion of the cluster
+	LabelFinalBackupKey = "redis.kun/final-backup-of"
+)
+
+const (
+	// AnnotationReconcile changes how the operator reconciles the cluster, while it is set
+	// the spec is not applied and the deletion of the cluster waits
+	AnnotationReconcile = "redis.kun/reconcile"
+	// ReconcilePaused skips the reconciliation of the cluster
+	ReconcilePaused = "paused"
+	// ReconcileObserve runs the checks of the cluster and reports the drift instead of healing it
+	ReconcileObserve = "observe"
+)
+
+// MemoryPreset sets the maxmemory-policy of the redis
+type MemoryPreset string
+
+const (
+	// MemoryPresetCache evicts any key, the least recently used first
+	MemoryPresetCache MemoryPreset = "Cache"
+	// MemoryPresetVolatileCache only evicts the keys with an expire, the least recently used first
+	MemoryPresetVolatileCache MemoryPreset = "VolatileCache"
+	// MemoryPresetStore never evicts, the writes fail once the maxmemory is reached
+	MemoryPresetStore MemoryPreset = "Store"
+)
+
+// MemoryOvercommit is what happens when the maxmemory of the redis does not fit in their memory limit
+type MemoryOvercommit string
+
+const (
+	// MemoryOvercommitWarn applies the config and sends a warning event
+	MemoryOvercommitWarn MemoryOvercommit = "Warn"
+	// MemoryOvercommitReject refuses to reconcile the cluster
+	MemoryOvercommitReject MemoryOvercommit = "Reject"
+)
+
+// MemorySettings defines how the maxmemory of the redis is derived from their memory limit
+type MemorySettings struct {
+	// MaxMemoryPercent is the part of the memory limit used as maxmemory when config has none. It defaults
+	// to 50 with persistence, the forks of BGSAVE and of the AOF rewrite need memory for the copy-on-write,
+	// 75 otherwise.
+	MaxMemoryPercent int32 `json:"maxMemoryPercent,omitempty"`
+	// Preset sets the maxmemory-policy when config has none: Cache, VolatileCache or Store
+	Preset MemoryPreset `json:"preset,omitempty"`
+	// Overcommit is what happens when the maxmemory of config plus the repl-backlog-size exceed
+	// the memory limit: Warn, the default, or Reject
+	Overcommit MemoryOvercommit `json:"overcommit,omitempty"`
+}
+
+// FinalBackupSpec defines the backup taken before the deletion of a cluster
+type FinalBackupSpec struct {
+	// Storage is where the snapshot is uploaded
+	Storage BackupStorage `json:"storage"`
+	// Image runs the upload job, it needs sh, sha256sum and the minio client mc
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// PITRSpec defines where the AOF files are shipped
+type PITRSpec struct {
+	// Storage is where the AOF files are uploaded, under <prefix>/<namespace>/<name>/aof
+	Storage BackupStorage `json:"storage"`
+	// IntervalSeconds is the time between two uploads, the writes of the last interval can be lost
+	IntervalSeconds int32 `json:"intervalSeconds,omitempty"`
+	// Image runs the shipper sidecar, it needs sh, md5sum and the minio client mc
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
 // RedisStorage defines the structure used to store the Redis Data
 type RedisStorage struct {
 	KeepAfterDeletion     bool                          `json:"keepAfterDeletion,omitempty"`
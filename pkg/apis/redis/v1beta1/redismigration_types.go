@@ -0,0 +1,90 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	MigrationKind = "RedisMigration"
+)
+
+// RedisMigrationSpec defines the desired state of RedisMigration
+// +k8s:openapi-gen=true
+type RedisMigrationSpec struct {
+	// ClusterName is the RedisCluster the data is migrated to, in the same namespace
+	ClusterName string `json:"clusterName"`
+	// Source is the external redis the data is migrated from
+	Source ReplicaOfSpec `json:"source"`
+	// Cutover makes the source read-only, waits for zero lag and promotes the cluster
+	Cutover bool `json:"cutover,omitempty"`
+	// RollbackWindow keeps the source in sync with the cluster after the cutover for this duration, like "24h"
+	RollbackWindow string `json:"rollbackWindow,omitempty"`
+	// Rollback makes the source a writable master again during the rollback window
+	Rollback bool `json:"rollback,omitempty"`
+	// AdvertiseAddress is the host:port the source replicates from during the rollback window, like a LoadBalancer
+	// or NodePort service that selects the master of the cluster. Defaults to the pod ip of the master.
+	AdvertiseAddress string `json:"advertiseAddress,omitempty"`
+}
+
+// MigrationPhase is the phase of a migration
+type MigrationPhase string
+
+const (
+	MigrationPhasePending    MigrationPhase = "Pending"
+	MigrationPhaseSyncing    MigrationPhase = "Syncing"
+	MigrationPhaseCutover    MigrationPhase = "CuttingOver"
+	MigrationPhaseRollback   MigrationPhase = "RollbackWindow"
+	MigrationPhaseCompleted  MigrationPhase = "Completed"
+	MigrationPhaseRolledBack MigrationPhase = "RolledBack"
+	MigrationPhaseFailed     MigrationPhase = "Failed"
+)
+
+// RedisMigrationStatus defines the observed state of RedisMigration
+// +k8s:openapi-gen=true
+type RedisMigrationStatus struct {
+	Phase MigrationPhase `json:"phase,omitempty"`
+	// Lag is the number of bytes of the replication stream not applied yet by the cluster,
+	// or by the source during the rollback window
+	Lag     int64  `json:"lag,omitempty"`
+	Message string `json:"message,omitempty"`
+	// SourceMinReplicasToWrite is the min-replicas-to-write of the source before the cutover made it read-only
+	SourceMinReplicasToWrite string `json:"sourceMinReplicasToWrite,omitempty"`
+	CutoverTime              string `json:"cutoverTime,omitempty"`
+	// RollbackDeadline is the end of the rollback window
+	RollbackDeadline string `json:"rollbackDeadline,omitempty"`
+	CompletionTime   string `json:"completionTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedisMigration is the Schema for the redismigrations API
+// +k8s:openapi-gen=true
+type RedisMigration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RedisMigrationSpec   `json:"spec,omitempty"`
+	Status RedisMigrationStatus `json:"status,omitempty"`
+}
+
+// IsFinished is true once the migration can't move to another phase
+func (m *RedisMigration) IsFinished() bool {
+	switch m.Status.Phase {
+	case MigrationPhaseCompleted, MigrationPhaseRolledBack, MigrationPhaseFailed:
+		return true
+	}
+	return false
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RedisMigrationList contains a list of RedisMigration
+type RedisMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RedisMigration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RedisMigration{}, &RedisMigrationList{})
+}
@@ -1,6 +1,8 @@
 package v1beta1
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"sort"
 	"time"
 
@@ -41,6 +43,11 @@ const (
 	ClusterConditionUpgrading                 = "Upgrading"
 	ClusterConditionUpdating                  = "Updating"
 	ClusterConditionFailed                    = "Failed"
+	ClusterConditionDeleting                  = "Deleting"
+	ClusterConditionResizing                  = "Resizing"
+	ClusterConditionSuspended                 = "Suspended"
+	ClusterConditionPaused                    = "Paused"
+	ClusterConditionObserving                 = "Observing"
 )
 
 // RedisClusterStatus defines the observed state of RedisCluster
@@ -52,6 +59,189 @@ type RedisClusterStatus struct {
 	Conditions []Condition `json:"conditions,omitempty"`
 	MasterIP   string      `json:"masterIP,omitempty"`
 	SentinelIP string      `json:"sentinelIP,omitempty"`
+	// ObservedGeneration is the generation of the last successfully applied spec
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastAppliedSpec is the last successfully applied spec, it is used to detect the
+	// changes made while the operator was not running. The password is left out.
+	LastAppliedSpec *RedisClusterSpec `json:"lastAppliedSpec,omitempty"`
+	// PasswordHash is the sha256 of the password of the last applied spec
+	PasswordHash string `json:"passwordHash,omitempty"`
+	// Upgrade is the progress of a canary upgrade
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+	// Restore is the progress of the restore of the cluster from an RDB
+	Restore *RestoreStatus `json:"restore,omitempty"`
+	// Clone is the progress of the clone of the cluster from another cluster
+	Clone *CloneStatus `json:"clone,omitempty"`
+	// ReplicaOf is the host:port of the external master of a standby cluster
+	ReplicaOf string `json:"replicaOf,omitempty"`
+	// VolumeExpansion is the progress of the expansion of the persistent volumes of the redis
+	VolumeExpansion *VolumeExpansionStatus `json:"volumeExpansion,omitempty"`
+	// Suspension records the suspension of the cluster until it is resumed
+	Suspension *SuspensionStatus `json:"suspension,omitempty"`
+	// Drift is what the operator would have healed, the last time the cluster was observed
+	Drift []string `json:"drift,omitempty"`
+	// MemoryOvercommit is why the maxmemory of the redis does not fit in their memory limit
+	MemoryOvercommit string `json:"memoryOvercommit,omitempty"`
+}
+
+// UpgradePhase is the phase of a canary upgrade
+type UpgradePhase string
+
+const (
+	UpgradePhaseCanary   UpgradePhase = "Canary"
+	UpgradePhasePromoted UpgradePhase = "Promoted"
+	UpgradePhaseFailed   UpgradePhase = "Failed"
+)
+
+// UpgradeStatus records the progress of a canary upgrade
+type UpgradeStatus struct {
+	PreviousImage string       `json:"previousImage,omitempty"`
+	TargetImage   string       `json:"targetImage,omitempty"`
+	CanaryPod     string       `json:"canaryPod,omitempty"`
+	Phase         UpgradePhase `json:"phase,omitempty"`
+	// SoakStartTime is when the canary passed the first health check
+	SoakStartTime string `json:"soakStartTime,omitempty"`
+	// ErrorBaseline is the number of error replies of the canary when the soak started
+	ErrorBaseline int64  `json:"errorBaseline,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
+
+// RestorePhase is the phase of the restore of a cluster
+type RestorePhase string
+
+const (
+	RestorePhaseDownloading RestorePhase = "Downloading"
+	RestorePhaseLoading     RestorePhase = "Loading"
+	RestorePhaseCompleted   RestorePhase = "Completed"
+	// RestorePhaseSkipped means the restore was added to a cluster that already existed
+	RestorePhaseSkipped RestorePhase = "Skipped"
+)
+
+// RestoreStatus records the progress of the restore of a cluster from an RDB
+type RestoreStatus struct {
+	Source  string       `json:"source,omitempty"`
+	Phase   RestorePhase `json:"phase,omitempty"`
+	Message string       `json:"message,omitempty"`
+	// Keys is the number of keys of the restored master
+	Keys           int64  `json:"keys,omitempty"`
+	StartTime      string `json:"startTime,omitempty"`
+	CompletionTime string `json:"completionTime,omitempty"`
+}
+
+// ClonePhase is the phase of the clone of a cluster
+type ClonePhase string
+
+const (
+	ClonePhaseSyncing   ClonePhase = "Syncing"
+	ClonePhaseCompleted ClonePhase = "Completed"
+	// ClonePhaseSkipped means the clone was added to a cluster that already existed
+	ClonePhaseSkipped ClonePhase = "Skipped"
+)
+
+// CloneStatus records the progress of the clone of a cluster from another cluster
+type CloneStatus struct {
+	// Source is the namespace/name of the source cluster
+	Source string     `json:"source,omitempty"`
+	Phase  ClonePhase `json:"phase,omitempty"`
+	// SourceMaster is the address of the master of the source the first redis replicates from
+	SourceMaster   string `json:"sourceMaster,omitempty"`
+	Message        string `json:"message,omitempty"`
+	StartTime      string `json:"startTime,omitempty"`
+	CompletionTime string `json:"completionTime,omitempty"`
+}
+
+// VolumeExpansionPhase is the phase of the expansion of the persistent volumes
+type VolumeExpansionPhase string
+
+const (
+	// VolumeExpansionPhaseResizing means the persistent volume claims are expanded
+	VolumeExpansionPhaseResizing VolumeExpansionPhase = "Resizing"
+	// VolumeExpansionPhaseRecreating means the redis statefulset is recreated with the new volume claim template
+	VolumeExpansionPhaseRecreating VolumeExpansionPhase = "Recreating"
+	VolumeExpansionPhaseCompleted  VolumeExpansionPhase = "Completed"
+	VolumeExpansionPhaseFailed     VolumeExpansionPhase = "Failed"
+)
+
+// VolumeExpansionStatus records the progress of the expansion of the persistent volumes of the redis
+type VolumeExpansionStatus struct {
+	// Size is the requested size of the volumes
+	Size           string               `json:"size,omitempty"`
+	Phase          VolumeExpansionPhase `json:"phase,omitempty"`
+	Message        string               `json:"message,omitempty"`
+	StartTime      string               `json:"startTime,omitempty"`
+	CompletionTime string               `json:"completionTime,omitempty"`
+}
+
+// SuspensionPhase is the phase of the suspension of a cluster
+type SuspensionPhase string
+
+const (
+	// SuspensionPhaseSaving means a background save was started on the redis, they are scaled down once it is written
+	SuspensionPhaseSaving SuspensionPhase = "Saving"
+	// SuspensionPhaseSuspended means the data is saved and the pods are scaled down
+	SuspensionPhaseSuspended SuspensionPhase = "Suspended"
+	// SuspensionPhaseResuming means the pods are scaled up, the saved master is not the master yet
+	SuspensionPhaseResuming SuspensionPhase = "Resuming"
+)
+
+// SuspensionStatus records the suspension of a cluster
+type SuspensionStatus struct {
+	Phase SuspensionPhase `json:"phase,omitempty"`
+	// MasterPod is the master when the cluster was suspended, it has the newest RDB and is the master when resumed
+	MasterPod string `json:"masterPod,omitempty"`
+	// SaveTime is when the data of the redis was saved
+	SaveTime string `json:"saveTime,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// IsDeletionProtected is true when the deletion of the cluster waits for the removal of the protection annotation
+func (r *RedisCluster) IsDeletionProtected() bool {
+	return r.Annotations[AnnotationDeletionProtection] == "true"
+}
+
+// IsSuspended is true once the data of a suspended cluster is saved, its pods are then scaled down to zero
+func (r *RedisCluster) IsSuspended() bool {
+	return r.Spec.Suspended && r.Status.Suspension != nil && r.Status.Suspension.Phase == SuspensionPhaseSuspended
+}
+
+// IsResuming is true until the master of a resumed cluster is the one saved when it was suspended
+func (r *RedisCluster) IsResuming() bool {
+	return !r.Spec.Suspended && r.Status.Suspension != nil && r.Status.Suspension.Phase == SuspensionPhaseResuming
+}
+
+// IsCloning is true until the first redis has synced from the source and is made the master of the cluster
+func (r *RedisCluster) IsCloning() bool {
+	if r.Spec.CloneFrom == nil {
+		return false
+	}
+	status := r.Status.Clone
+	return status == nil || status.Phase == ClonePhaseSyncing
+}
+
+// IsRestoring is true until the first redis, loaded from the RDB, is made the master of the cluster
+func (r *RedisCluster) IsRestoring() bool {
+	if r.Spec.Restore == nil {
+		return false
+	}
+	status := r.Status.Restore
+	return status == nil || (status.Phase != RestorePhaseCompleted && status.Phase != RestorePhaseSkipped)
+}
+
+// IsPasswordChanged is true when the password of the spec is not the one the cluster was applied with
+func (r *RedisCluster) IsPasswordChanged() bool {
+	return r.Status.PasswordHash != "" && hashPassword(r.Spec.Password) != r.Status.PasswordHash
+}
+
+// SetLastAppliedSpec records the applied spec, only the hash of its password is kept
+func (cs *RedisClusterStatus) SetLastAppliedSpec(spec *RedisClusterSpec) {
+	cs.LastAppliedSpec = spec.DeepCopy()
+	cs.LastAppliedSpec.Password = ""
+	cs.PasswordHash = hashPassword(spec.Password)
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
 }
 
 func (cs *RedisClusterStatus) DescConditionsByTime() {
@@ -60,6 +250,11 @@ func (cs *RedisClusterStatus) DescConditionsByTime() {
 	})
 }
 
+// IsHealthy returns true when the last condition of the cluster is Healthy, the conditions are stored newest first
+func (cs *RedisClusterStatus) IsHealthy() bool {
+	return len(cs.Conditions) > 0 && cs.Conditions[0].Type == ClusterConditionHealthy
+}
+
 func (cs *RedisClusterStatus) SetScalingUpCondition(message string) {
 	c := newClusterCondition(ClusterConditionScaling, corev1.ConditionTrue, "Scaling up", message)
 	cs.setClusterCondition(*c)
@@ -98,6 +293,41 @@ func (cs *RedisClusterStatus) SetFailedCondition(message string) {
 	cs.setClusterCondition(*c)
 }
 
+// SetResizingCondition records the expansion of the persistent volumes
+func (cs *RedisClusterStatus) SetResizingCondition(message string) {
+	c := newClusterCondition(ClusterConditionResizing, corev1.ConditionTrue,
+		"Volumes resizing", message)
+	cs.setClusterCondition(*c)
+}
+
+// SetSuspendedCondition records that the cluster is intentionally scaled down
+func (cs *RedisClusterStatus) SetSuspendedCondition(message string) {
+	c := newClusterCondition(ClusterConditionSuspended, corev1.ConditionTrue,
+		"Cluster suspended", message)
+	cs.setClusterCondition(*c)
+}
+
+// SetPausedCondition records that the reconciliation of the cluster is paused
+func (cs *RedisClusterStatus) SetPausedCondition(message string) {
+	c := newClusterCondition(ClusterConditionPaused, corev1.ConditionTrue,
+		"Reconciliation paused", message)
+	cs.setClusterCondition(*c)
+}
+
+// SetObservingCondition records that the cluster is only observed
+func (cs *RedisClusterStatus) SetObservingCondition(message string) {
+	c := newClusterCondition(ClusterConditionObserving, corev1.ConditionTrue,
+		"Cluster observed", message)
+	cs.setClusterCondition(*c)
+}
+
+// SetDeletingCondition records why the deletion of the cluster is waiting
+func (cs *RedisClusterStatus) SetDeletingCondition(message string) {
+	c := newClusterCondition(ClusterConditionDeleting, corev1.ConditionTrue,
+		"Cluster deleting", message)
+	cs.setClusterCondition(*c)
+}
+
 func (cs *RedisClusterStatus) ClearCondition(t ConditionType) {
 	pos, _ := getClusterCondition(cs, t)
 	if pos == -1 {
@@ -3,6 +3,10 @@ package v1beta1
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
 
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -16,10 +20,39 @@ const (
 	defaultRedisImage     = "redis:5.0.4-alpine"
 
 	defaultSlavePriority = "1"
+
+	defaultCanarySoakSeconds = 300
+
+	defaultPITRIntervalSeconds = 60
+
+	defaultReplicaOfPort = 6379
+
+	defaultMaxMemoryPercent           = 75
+	defaultPersistentMaxMemoryPercent = 50
+
+	defaultBackupJobImage   = "minio/mc:RELEASE.2024-06-12T14-34-03Z"
+	defaultRestoreHTTPImage = "curlimages/curl:8.8.0"
 )
 
 var (
 	defaultSentinelCustomConfig = []string{"down-after-milliseconds 5000", "failover-timeout 10000"}
+
+	// operatorConfigs are the configs of redis set by the operator, they can't be in spec.config
+	operatorConfigs = map[string]bool{
+		"port":        true,
+		"bind":        true,
+		"dir":         true,
+		"requirepass": true,
+		"masterauth":  true,
+		"slaveof":     true,
+		"replicaof":   true,
+	}
+
+	memoryPresetPolicies = map[MemoryPreset]string{
+		MemoryPresetCache:         "allkeys-lru",
+		MemoryPresetVolatileCache: "volatile-lru",
+		MemoryPresetStore:         "noeviction",
+	}
 )
 
 // Validate set the values by default if not defined and checks if the values given are valid
@@ -28,6 +61,11 @@ func (r *RedisCluster) Validate() error {
 		return fmt.Errorf("name length can't be higher than %d", maxNameLength)
 	}
 
+	// the running redis keep the password they were created with
+	if r.IsPasswordChanged() {
+		return errors.New("password change is not allowed")
+	}
+
 	if r.Spec.Size == 0 {
 		r.Spec.Size = defaultRedisNumber
 	} else if r.Spec.Size < defaultRedisNumber {
@@ -40,31 +78,319 @@ func (r *RedisCluster) Validate() error {
 		return errors.New("number of sentinels in spec is less than the minimum")
 	}
 
+	if r.Spec.Engine == "" {
+		r.Spec.Engine = EngineRedis
+	} else if _, ok := engineProfiles[r.Spec.Engine]; !ok {
+		return fmt.Errorf("engine %s is not supported", r.Spec.Engine)
+	}
+	profile := r.GetEngineProfile()
+
+	if r.Spec.ActiveReplica && !profile.ActiveReplica {
+		return fmt.Errorf("active replica is not supported by engine %s", r.Spec.Engine)
+	}
+	if r.Spec.ActiveReplica && r.Spec.ReadReplicas != nil {
+		return errors.New("read replicas can't be used with active replica")
+	}
+	if r.Spec.ActiveReplica && r.Spec.UpgradeStrategy != nil && r.Spec.UpgradeStrategy.Canary != nil {
+		return errors.New("canary upgrade can't be used with active replica")
+	}
+
+	if r.Spec.Restore != nil {
+		if r.Spec.ActiveReplica {
+			return errors.New("restore can't be used with active replica")
+		}
+		if err := r.Spec.Restore.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Spec.CloneFrom != nil {
+		if r.Spec.ActiveReplica {
+			return errors.New("cloneFrom can't be used with active replica")
+		}
+		if r.Spec.Restore != nil {
+			return errors.New("cloneFrom can't be used with restore")
+		}
+		if r.Spec.CloneFrom.Name == "" {
+			return errors.New("cloneFrom needs the name of the source cluster")
+		}
+		if r.Spec.CloneFrom.Namespace == "" {
+			r.Spec.CloneFrom.Namespace = r.Namespace
+		}
+		if r.Spec.CloneFrom.Namespace == r.Namespace && r.Spec.CloneFrom.Name == r.Name {
+			return errors.New("a cluster can't be cloned from itself")
+		}
+	}
+
+	if r.Spec.ReplicaOf != nil {
+		if r.Spec.ActiveReplica {
+			return errors.New("replicaOf can't be used with active replica")
+		}
+		if r.Spec.Restore != nil || r.Spec.CloneFrom != nil {
+			return errors.New("replicaOf can't be used with restore or cloneFrom, the data comes from the external master")
+		}
+		if r.Spec.ReplicaOf.Host == "" {
+			return errors.New("replicaOf needs the host of the external master")
+		}
+		if r.Spec.ReplicaOf.Port == 0 {
+			r.Spec.ReplicaOf.Port = defaultReplicaOfPort
+		}
+	}
+
 	if r.Spec.Image == "" {
-		r.Spec.Image = defaultRedisImage
+		r.Spec.Image = profile.DefaultImage
 	}
 
 	if r.Spec.Sentinel.Image == "" {
-		r.Spec.Sentinel.Image = defaultRedisImage
+		r.Spec.Sentinel.Image = profile.DefaultImage
+	}
+
+	if r.Spec.PITR != nil || r.IsPointInTimeRestore() {
+		// the multi part AOF and its timestamp annotations are only known from the version in the tag of the image
+		if !profile.hasMultiPartAOF(r.Spec.Image) {
+			return fmt.Errorf("pitr needs the multi part AOF of redis 7.0 or later, image %s of engine %s has none or is not tagged with its version", r.Spec.Image, r.Spec.Engine)
+		}
+	}
+	if r.Spec.PITR != nil {
+		if err := r.validatePITR(); err != nil {
+			return err
+		}
+	}
+
+	if err := r.validateDeletionPolicy(); err != nil {
+		return err
 	}
 
 	if r.Spec.Sentinel.Resources.Size() == 0 {
 		r.Spec.Sentinel.Resources = defaultSentinelResource()
 	}
 
+	if r.Spec.UpgradeStrategy != nil && r.Spec.UpgradeStrategy.Canary != nil {
+		if r.Spec.UpgradeStrategy.Canary.SoakSeconds == 0 {
+			r.Spec.UpgradeStrategy.Canary.SoakSeconds = defaultCanarySoakSeconds
+		} else if r.Spec.UpgradeStrategy.Canary.SoakSeconds < 0 {
+			return errors.New("canary soakSeconds can't be negative")
+		}
+	}
+
 	if r.Spec.Config == nil {
 		r.Spec.Config = make(map[string]string)
 	}
+	for name := range r.Spec.Config {
+		if operatorConfigs[strings.ToLower(name)] {
+			return fmt.Errorf("config %s is set by the operator", name)
+		}
+	}
 
 	// https://github.com/ucloud/redis-operator/issues/6
 	r.Spec.Config["slave-priority"] = defaultSlavePriority
+	// same config since redis 5.0
+	delete(r.Spec.Config, "replica-priority")
+	if r.IsStandby() {
+		// a sentinel takes a master reporting itself as a replica for down, no replica may be promoted
+		// to an independent master, the operator moves the link to the external master instead
+		r.Spec.Config["slave-priority"] = "0"
+	}
 
 	if !r.Spec.DisablePersistence {
 		enablePersistence(r.Spec.Config)
 	} else {
 		disablePersistence(r.Spec.Config)
 	}
+	if r.Spec.PITR != nil {
+		// the shipped AOF files are replayed up to the timestamp annotations
+		r.Spec.Config["appendonly"] = "yes"
+		r.Spec.Config["aof-timestamp-enabled"] = "yes"
+	}
+
+	return r.validateMemory()
+}
+
+// validateMemory sets the defaults of the memory settings and the maxmemory-policy of the preset,
+// the maxmemory is derived from the memory limit by the operator
+func (r *RedisCluster) validateMemory() error {
+	// the maxmemory is only derived for the clusters that ask for it
+	if r.Spec.Memory == nil {
+		return nil
+	}
+	memory := r.Spec.Memory
+	if memory.MaxMemoryPercent == 0 {
+		memory.MaxMemoryPercent = defaultPersistentMaxMemoryPercent
+		if r.Spec.DisablePersistence {
+			memory.MaxMemoryPercent = defaultMaxMemoryPercent
+		}
+	} else if memory.MaxMemoryPercent < 0 || memory.MaxMemoryPercent > 100 {
+		return errors.New("memory maxMemoryPercent must be between 1 and 100")
+	}
+	switch memory.Overcommit {
+	case "":
+		memory.Overcommit = MemoryOvercommitWarn
+	case MemoryOvercommitWarn, MemoryOvercommitReject:
+	default:
+		return fmt.Errorf("memory overcommit %s is not supported", memory.Overcommit)
+	}
+	if memory.Preset != "" {
+		policy, ok := memoryPresetPolicies[memory.Preset]
+		if !ok {
+			return fmt.Errorf("memory preset %s is not supported", memory.Preset)
+		}
+		setConfigMapIfNotExist("maxmemory-policy", policy, r.Spec.Config)
+	}
+	return nil
+}
+
+func (r *RedisCluster) validateDeletionPolicy() error {
+	switch r.Spec.DeletionPolicy {
+	case "":
+		if r.Spec.Storage.KeepAfterDeletion {
+			r.Spec.DeletionPolicy = DeletionPolicyRetain
+		} else {
+			r.Spec.DeletionPolicy = DeletionPolicyDelete
+		}
+	case DeletionPolicyDelete, DeletionPolicyRetain:
+	case DeletionPolicySnapshot:
+		if r.Spec.Storage.PersistentVolumeClaim == nil {
+			return errors.New("deletionPolicy Snapshot needs the persistent storage of the redis cluster")
+		}
+		if r.Spec.FinalBackup == nil {
+			return errors.New("deletionPolicy Snapshot needs a finalBackup")
+		}
+		// the same checks as the backup it creates
+		backup := &RedisBackup{Spec: RedisBackupSpec{
+			ClusterName: r.Name,
+			Storage:     r.Spec.FinalBackup.Storage,
+			Image:       r.Spec.FinalBackup.Image,
+		}}
+		if err := backup.Validate(); err != nil {
+			return fmt.Errorf("invalid finalBackup: %s", err)
+		}
+		r.Spec.FinalBackup.Image = backup.Spec.Image
+	default:
+		return fmt.Errorf("deletionPolicy %s is not supported", r.Spec.DeletionPolicy)
+	}
+	return nil
+}
 
+// Validate set the values by default if not defined and checks if the values given are valid
+func (b *RedisBackup) Validate() error {
+	if b.Spec.ClusterName == "" {
+		return errors.New("clusterName is required")
+	}
+	s3 := b.Spec.Storage.S3
+	if s3 == nil {
+		return errors.New("storage.s3 is required")
+	}
+	if s3.Endpoint == "" || s3.Bucket == "" || s3.CredentialsSecret == "" {
+		return errors.New("storage.s3 needs an endpoint, a bucket and a credentialsSecret")
+	}
+	if b.Spec.Image == "" {
+		b.Spec.Image = defaultBackupJobImage
+	}
+	return nil
+}
+
+// Validate set the values by default if not defined and checks if the values given are valid
+func (s *RedisBackupSchedule) Validate() error {
+	if s.Spec.ClusterName == "" {
+		return errors.New("clusterName is required")
+	}
+	if s.Spec.Schedule == "" {
+		return errors.New("schedule is required")
+	}
+	if s.Spec.Retention.MaxCount < 0 {
+		return errors.New("retention maxCount can't be negative")
+	}
+	if s.Spec.Retention.MaxAge != "" {
+		if _, err := time.ParseDuration(s.Spec.Retention.MaxAge); err != nil {
+			return fmt.Errorf("invalid retention maxAge: %s", err)
+		}
+	}
+	// the same checks as the backups it creates
+	backup := &RedisBackup{Spec: RedisBackupSpec{
+		ClusterName: s.Spec.ClusterName,
+		Storage:     s.Spec.Storage,
+		Image:       s.Spec.Image,
+	}}
+	if err := backup.Validate(); err != nil {
+		return err
+	}
+	s.Spec.Image = backup.Spec.Image
+	return nil
+}
+
+func (m *RedisMigration) Validate() error {
+	if m.Spec.ClusterName == "" {
+		return errors.New("clusterName is required")
+	}
+	if m.Spec.Source.Host == "" {
+		return errors.New("source host is required")
+	}
+	if m.Spec.Source.Port == 0 {
+		m.Spec.Source.Port = defaultReplicaOfPort
+	}
+	if m.Spec.RollbackWindow != "" {
+		if _, err := time.ParseDuration(m.Spec.RollbackWindow); err != nil {
+			return fmt.Errorf("invalid rollbackWindow: %s", err)
+		}
+	}
+	if m.Spec.AdvertiseAddress != "" {
+		if _, _, err := net.SplitHostPort(m.Spec.AdvertiseAddress); err != nil {
+			return fmt.Errorf("invalid advertiseAddress: %s", err)
+		}
+	}
+	return nil
+}
+
+func (r *RestoreSpec) validate() error {
+	u, err := url.Parse(r.From.URL)
+	if err != nil {
+		return fmt.Errorf("invalid restore url: %s", err)
+	}
+	image := defaultRestoreHTTPImage
+	if r.PointInTime != "" {
+		if _, err := time.Parse(time.RFC3339, r.PointInTime); err != nil {
+			return fmt.Errorf("invalid restore pointInTime: %s", err)
+		}
+		if u.Scheme != "s3" {
+			return errors.New("point-in-time restore needs an s3 url")
+		}
+	}
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return errors.New("restore url must be like s3://bucket/key")
+		}
+		if r.From.Endpoint == "" || r.From.CredentialsSecret == "" {
+			return errors.New("restore from s3 needs an endpoint and a credentialsSecret")
+		}
+		image = defaultBackupJobImage
+	case "http", "https":
+	default:
+		return fmt.Errorf("restore url %q must be s3, http or https", r.From.URL)
+	}
+	if r.Image == "" {
+		r.Image = image
+	}
+	return nil
+}
+
+func (r *RedisCluster) validatePITR() error {
+	if r.Spec.DisablePersistence {
+		return errors.New("pitr can't be used with disablePersistence")
+	}
+	pitr := r.Spec.PITR
+	s3 := pitr.Storage.S3
+	if s3 == nil || s3.Endpoint == "" || s3.Bucket == "" || s3.CredentialsSecret == "" {
+		return errors.New("pitr storage.s3 needs an endpoint, a bucket and a credentialsSecret")
+	}
+	if pitr.IntervalSeconds == 0 {
+		pitr.IntervalSeconds = defaultPITRIntervalSeconds
+	} else if pitr.IntervalSeconds < 0 {
+		return errors.New("pitr intervalSeconds can't be negative")
+	}
+	if pitr.Image == "" {
+		pitr.Image = defaultBackupJobImage
+	}
 	return nil
 }
 
@@ -11,9 +11,323 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
-		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisCluster":       schema_pkg_apis_redis_v1beta1_RedisCluster(ref),
-		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisClusterSpec":   schema_pkg_apis_redis_v1beta1_RedisClusterSpec(ref),
-		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisClusterStatus": schema_pkg_apis_redis_v1beta1_RedisClusterStatus(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackup":               schema_pkg_apis_redis_v1beta1_RedisBackup(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupSchedule":       schema_pkg_apis_redis_v1beta1_RedisBackupSchedule(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupScheduleSpec":   schema_pkg_apis_redis_v1beta1_RedisBackupScheduleSpec(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupScheduleStatus": schema_pkg_apis_redis_v1beta1_RedisBackupScheduleStatus(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupSpec":           schema_pkg_apis_redis_v1beta1_RedisBackupSpec(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupStatus":         schema_pkg_apis_redis_v1beta1_RedisBackupStatus(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisCluster":              schema_pkg_apis_redis_v1beta1_RedisCluster(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisClusterSpec":          schema_pkg_apis_redis_v1beta1_RedisClusterSpec(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisClusterStatus":        schema_pkg_apis_redis_v1beta1_RedisClusterStatus(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisMigration":            schema_pkg_apis_redis_v1beta1_RedisMigration(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisMigrationSpec":        schema_pkg_apis_redis_v1beta1_RedisMigrationSpec(ref),
+		"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisMigrationStatus":      schema_pkg_apis_redis_v1beta1_RedisMigrationStatus(ref),
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisBackup(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisBackup is the Schema for the redisbackups API",
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupSpec"),
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupStatus"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupStatus", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisBackupSchedule(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisBackupSchedule is the Schema for the redisbackupschedules API",
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupScheduleSpec"),
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupScheduleStatus"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupScheduleSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisBackupScheduleStatus", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisBackupScheduleSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisBackupScheduleSpec defines the desired state of RedisBackupSchedule",
+				Properties: map[string]spec.Schema{
+					"clusterName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClusterName is the RedisCluster to back up, in the same namespace",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"schedule": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Schedule is a cron expression in UTC, like \"0 3 * * *\" or \"@daily\"",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"storage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Storage is where the snapshots are uploaded",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.BackupStorage"),
+						},
+					},
+					"retention": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Retention defines when the backups are deleted, with their snapshot",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.BackupRetention"),
+						},
+					},
+					"suspend": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Suspend stops creating new backups, the retention is still applied",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image runs the upload job, it needs sh, sha256sum and the minio client mc",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"imagePullPolicy": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+				},
+				Required: []string{"clusterName", "schedule", "storage"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.BackupRetention", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.BackupStorage"},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisBackupScheduleStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisBackupScheduleStatus defines the observed state of RedisBackupSchedule",
+				Properties: map[string]spec.Schema{
+					"lastScheduleTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastScheduleTime is the last time a backup was due, created or skipped",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lastBackup": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastBackup is the name of the last backup created",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lastSuccessfulTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastSuccessfulTime is the completion time of the last completed backup",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message tells why the last run was skipped",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisBackupSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisBackupSpec defines the desired state of RedisBackup",
+				Properties: map[string]spec.Schema{
+					"clusterName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClusterName is the RedisCluster to back up, in the same namespace",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"storage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Storage is where the snapshot is uploaded",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.BackupStorage"),
+						},
+					},
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image runs the upload job, it needs sh, sha256sum and the minio client mc",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"imagePullPolicy": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+				},
+				Required: []string{"clusterName", "storage"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.BackupStorage"},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisBackupStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisBackupStatus defines the observed state of RedisBackup",
+				Properties: map[string]spec.Schema{
+					"phase": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"sourcePod": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SourcePod is the replica the snapshot is taken from",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"sourceOffset": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SourceOffset is the replication offset of the replica when the snapshot started",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"location": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Location is the url of the uploaded snapshot",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"size": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Size is the size of the snapshot in bytes",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"checksum": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Checksum is the sha256 of the snapshot",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"startTime": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"snapshotTime": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"completionTime": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -72,6 +386,12 @@ func schema_pkg_apis_redis_v1beta1_RedisClusterSpec(ref common.ReferenceCallback
 							Format: "int32",
 						},
 					},
+					"engine": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
 					"resources": {
 						SchemaProps: spec.SchemaProps{
 							Ref: ref("k8s.io/api/core/v1.ResourceRequirements"),
@@ -159,17 +479,86 @@ func schema_pkg_apis_redis_v1beta1_RedisClusterSpec(ref common.ReferenceCallback
 							Format: "",
 						},
 					},
+					"activeReplica": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ActiveReplica makes every node a master replicating all the others, sentinel is not deployed. Only supported by the keydb engine.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"sentinel": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Sentinel defines its cluster settings",
 							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.SentinelSettings"),
 						},
 					},
+					"readReplicas": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReadReplicas defines a group of hidden replicas that are never promoted to master",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.ReadReplicaSettings"),
+						},
+					},
+					"upgradeStrategy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeStrategy defines how an image change is rolled out",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.UpgradeStrategy"),
+						},
+					},
+					"restore": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Restore loads the data of a new cluster from an RDB, it is ignored once the cluster exists",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RestoreSpec"),
+						},
+					},
+					"pitr": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PITR ships the AOF files of the master to an S3 compatible storage, for a point-in-time restore",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.PITRSpec"),
+						},
+					},
+					"cloneFrom": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CloneFrom copies the data of another live cluster into a new cluster, it is ignored once the cluster exists",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.CloneSource"),
+						},
+					},
+					"replicaOf": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReplicaOf runs the cluster as a standby, its master replicates from an external master. Removing it promotes the cluster.",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.ReplicaOfSpec"),
+						},
+					},
+					"deletionPolicy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeletionPolicy is what happens to the data when the cluster is deleted, Delete, Retain or Snapshot. It defaults to Retain with storage.keepAfterDeletion, Delete otherwise.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"finalBackup": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FinalBackup is where the backup taken before the deletion is uploaded, with the Snapshot policy",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.FinalBackupSpec"),
+						},
+					},
+					"suspended": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Suspended saves the data and scales the cluster down to zero pod, the persistent volume claims are kept. Setting it back to false resumes the cluster with its data.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"memory": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Memory derives the maxmemory of the redis from their memory limit",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.MemorySettings"),
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisExporter", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisStorage", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.SentinelSettings", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements", "k8s.io/api/core/v1.Toleration"},
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.CloneSource", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.FinalBackupSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.MemorySettings", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.PITRSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.ReadReplicaSettings", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisExporter", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisStorage", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.ReplicaOfSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RestoreSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.SentinelSettings", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.UpgradeStrategy", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements", "k8s.io/api/core/v1.Toleration"},
 	}
 }
 
@@ -204,10 +593,244 @@ func schema_pkg_apis_redis_v1beta1_RedisClusterStatus(ref common.ReferenceCallba
 							Format: "",
 						},
 					},
+					"observedGeneration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ObservedGeneration is the generation of the last successfully applied spec",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"lastAppliedSpec": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastAppliedSpec is the last successfully applied spec, it is used to detect the changes made while the operator was not running. The password is left out.",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisClusterSpec"),
+						},
+					},
+					"passwordHash": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PasswordHash is the sha256 of the password of the last applied spec",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"upgrade": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Upgrade is the progress of a canary upgrade",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.UpgradeStatus"),
+						},
+					},
+					"restore": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Restore is the progress of the restore of the cluster from an RDB",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RestoreStatus"),
+						},
+					},
+					"clone": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Clone is the progress of the clone of the cluster from another cluster",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.CloneStatus"),
+						},
+					},
+					"replicaOf": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReplicaOf is the host:port of the external master of a standby cluster",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"volumeExpansion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "VolumeExpansion is the progress of the expansion of the persistent volumes of the redis",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.VolumeExpansionStatus"),
+						},
+					},
+					"suspension": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Suspension records the suspension of the cluster until it is resumed",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.SuspensionStatus"),
+						},
+					},
+					"drift": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Drift is what the operator would have healed, the last time the cluster was observed",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"memoryOvercommit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MemoryOvercommit is why the maxmemory of the redis does not fit in their memory limit",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.CloneStatus", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.Condition", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisClusterSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RestoreStatus", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.SuspensionStatus", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.UpgradeStatus", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.VolumeExpansionStatus"},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisMigration(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisMigration is the Schema for the redismigrations API",
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisMigrationSpec"),
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisMigrationStatus"),
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.Condition"},
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisMigrationSpec", "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.RedisMigrationStatus", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisMigrationSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisMigrationSpec defines the desired state of RedisMigration",
+				Properties: map[string]spec.Schema{
+					"clusterName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClusterName is the RedisCluster the data is migrated to, in the same namespace",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"source": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Source is the external redis the data is migrated from",
+							Ref:         ref("github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.ReplicaOfSpec"),
+						},
+					},
+					"cutover": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Cutover makes the source read-only, waits for zero lag and promotes the cluster",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"rollbackWindow": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RollbackWindow keeps the source in sync with the cluster after the cutover for this duration, like \"24h\"",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"rollback": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Rollback makes the source a writable master again during the rollback window",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"advertiseAddress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AdvertiseAddress is the host:port the source replicates from during the rollback window, like a LoadBalancer or NodePort service that selects the master of the cluster. Defaults to the pod ip of the master.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"clusterName", "source"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1.ReplicaOfSpec"},
+	}
+}
+
+func schema_pkg_apis_redis_v1beta1_RedisMigrationStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RedisMigrationStatus defines the observed state of RedisMigration",
+				Properties: map[string]spec.Schema{
+					"phase": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"lag": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Lag is the number of bytes of the replication stream not applied yet by the cluster, or by the source during the rollback window",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"sourceMinReplicasToWrite": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SourceMinReplicasToWrite is the min-replicas-to-write of the source before the cutover made it read-only",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"cutoverTime": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+					"rollbackDeadline": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RollbackDeadline is the end of the rollback window",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"completionTime": {
+						SchemaProps: spec.SchemaProps{
+							Type:   []string{"string"},
+							Format: "",
+						},
+					},
+				},
+			},
+		},
 	}
 }
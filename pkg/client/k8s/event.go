@@ -29,6 +29,16 @@ type Event interface {
 	FailedCluster(object runtime.Object, message string)
 	// HealthCluster event ClusterHealthy
 	HealthCluster(object runtime.Object)
+	// FailoverCluster event Failover
+	FailoverCluster(object runtime.Object, message string)
+	// MasterDown event MasterDown
+	MasterDown(object runtime.Object, message string)
+	// Drift event Drift
+	Drift(object runtime.Object, message string)
+	// MemoryOvercommit event MemoryOvercommit
+	MemoryOvercommit(object runtime.Object, message string)
+	// UnsupportedConfig event UnsupportedConfig
+	UnsupportedConfig(object runtime.Object, message string)
 }
 
 // EventOption is the Event client interface implementation that using API calls to kubernetes.
@@ -89,3 +99,28 @@ func (e *EventOption) FailedCluster(object runtime.Object, message string) {
 func (e *EventOption) HealthCluster(object runtime.Object) {
 	e.eventsCli.Event(object, v1.EventTypeNormal, string(redisv1beta1.ClusterConditionHealthy), "Redis cluster is healthy")
 }
+
+// FailoverCluster implement the Event.Interface
+func (e *EventOption) FailoverCluster(object runtime.Object, message string) {
+	e.eventsCli.Event(object, v1.EventTypeNormal, "Failover", message)
+}
+
+// MasterDown implement the Event.Interface
+func (e *EventOption) MasterDown(object runtime.Object, message string) {
+	e.eventsCli.Event(object, v1.EventTypeWarning, "MasterDown", message)
+}
+
+// Drift implement the Event.Interface
+func (e *EventOption) Drift(object runtime.Object, message string) {
+	e.eventsCli.Event(object, v1.EventTypeWarning, "Drift", message)
+}
+
+// MemoryOvercommit implement the Event.Interface
+func (e *EventOption) MemoryOvercommit(object runtime.Object, message string) {
+	e.eventsCli.Event(object, v1.EventTypeWarning, "MemoryOvercommit", message)
+}
+
+// UnsupportedConfig implement the Event.Interface
+func (e *EventOption) UnsupportedConfig(object runtime.Object, message string) {
+	e.eventsCli.Event(object, v1.EventTypeWarning, "UnsupportedConfig", message)
+}
@@ -16,6 +16,13 @@ type Services interface {
 	Deployment
 	StatefulSet
 	Cluster
+	Job
+	Backup
+	BackupSchedule
+	Secret
+	Migration
+	RedisFailover
+	PersistentVolumeClaim
 }
 
 type services struct {
@@ -27,18 +34,32 @@ type services struct {
 	Deployment
 	StatefulSet
 	Cluster
+	Job
+	Backup
+	BackupSchedule
+	Secret
+	Migration
+	RedisFailover
+	PersistentVolumeClaim
 }
 
 // New returns a new Kubernetes client set.
 func New(kubecli client.Client, logger logr.Logger) Services {
 	return &services{
-		ConfigMap:           NewConfigMap(kubecli, logger),
-		Pod:                 NewPod(kubecli, logger),
-		PodDisruptionBudget: NewPodDisruptionBudget(kubecli, logger),
-		Service:             NewService(kubecli, logger),
-		NameSpaces:          NewNameSpaces(logger),
-		Deployment:          NewDeployment(kubecli, logger),
-		StatefulSet:         NewStatefulSet(kubecli, logger),
-		Cluster:             NewCluster(kubecli, logger),
+		ConfigMap:             NewConfigMap(kubecli, logger),
+		Pod:                   NewPod(kubecli, logger),
+		PodDisruptionBudget:   NewPodDisruptionBudget(kubecli, logger),
+		Service:               NewService(kubecli, logger),
+		NameSpaces:            NewNameSpaces(logger),
+		Deployment:            NewDeployment(kubecli, logger),
+		StatefulSet:           NewStatefulSet(kubecli, logger),
+		Cluster:               NewCluster(kubecli, logger),
+		Job:                   NewJob(kubecli, logger),
+		Backup:                NewBackup(kubecli, logger),
+		BackupSchedule:        NewBackupSchedule(kubecli, logger),
+		Secret:                NewSecret(kubecli, logger),
+		Migration:             NewMigration(kubecli, logger),
+		RedisFailover:         NewRedisFailover(kubecli, logger),
+		PersistentVolumeClaim: NewPersistentVolumeClaim(kubecli, logger),
 	}
 }
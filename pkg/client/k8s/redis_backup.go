@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+)
+
+// Backup the client that knows how to interact with kubernetes to manage RedisBackup
+type Backup interface {
+	// ListBackups list the RedisBackups of the namespace matching the labels
+	ListBackups(namespace string, labelSet map[string]string) (*redisv1beta1.RedisBackupList, error)
+	// CreateBackup will create the given RedisBackup
+	CreateBackup(namespace string, backup *redisv1beta1.RedisBackup) error
+	// UpdateBackup update the RedisBackup, like its finalizers
+	UpdateBackup(namespace string, backup *redisv1beta1.RedisBackup) error
+	// DeleteBackup will delete the given RedisBackup
+	DeleteBackup(namespace string, name string) error
+	// UpdateBackupStatus update the status of the RedisBackup
+	UpdateBackupStatus(namespace string, backup *redisv1beta1.RedisBackup) error
+}
+
+// BackupOption is the RedisBackup client that using API calls to kubernetes.
+type BackupOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewBackup returns a new RedisBackup client.
+func NewBackup(kubeClient client.Client, logger logr.Logger) Backup {
+	logger = logger.WithValues("service", "crd.redisBackup")
+	return &BackupOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// UpdateBackupStatus implement the Backup.Interface
+func (b *BackupOption) UpdateBackupStatus(namespace string, backup *redisv1beta1.RedisBackup) error {
+	err := b.client.Status().Update(context.TODO(), backup)
+	if err != nil {
+		b.logger.WithValues("namespace", namespace, "backup", backup.Name, "phase", backup.Status.Phase).
+			Error(err, "redisBackupStatus")
+		return err
+	}
+	b.logger.WithValues("namespace", namespace, "backup", backup.Name, "phase", backup.Status.Phase).
+		V(3).Info("redisBackupStatus updated")
+	return nil
+}
+
+// ListBackups implement the Backup.Interface
+func (b *BackupOption) ListBackups(namespace string, labelSet map[string]string) (*redisv1beta1.RedisBackupList, error) {
+	backups := &redisv1beta1.RedisBackupList{}
+	listOps := &client.ListOptions{
+		Namespace:     namespace,
+		LabelSelector: labels.SelectorFromSet(labelSet),
+	}
+	err := b.client.List(context.TODO(), backups, listOps)
+	return backups, err
+}
+
+// CreateBackup implement the Backup.Interface
+func (b *BackupOption) CreateBackup(namespace string, backup *redisv1beta1.RedisBackup) error {
+	err := b.client.Create(context.TODO(), backup)
+	if err != nil {
+		return err
+	}
+	b.logger.WithValues("namespace", namespace, "backup", backup.Name).Info("redisBackup created")
+	return nil
+}
+
+// UpdateBackup implement the Backup.Interface
+func (b *BackupOption) UpdateBackup(namespace string, backup *redisv1beta1.RedisBackup) error {
+	err := b.client.Update(context.TODO(), backup)
+	if err != nil {
+		return err
+	}
+	b.logger.WithValues("namespace", namespace, "backup", backup.Name).V(3).Info("redisBackup updated")
+	return nil
+}
+
+// DeleteBackup implement the Backup.Interface
+func (b *BackupOption) DeleteBackup(namespace string, name string) error {
+	backup := &redisv1beta1.RedisBackup{}
+	if err := b.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, backup); err != nil {
+		return err
+	}
+	if err := b.client.Delete(context.TODO(), backup); err != nil {
+		return err
+	}
+	b.logger.WithValues("namespace", namespace, "backup", name).Info("redisBackup deleted")
+	return nil
+}
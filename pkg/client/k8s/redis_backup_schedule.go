@@ -0,0 +1,43 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+)
+
+// BackupSchedule the client that knows how to interact with kubernetes to manage RedisBackupSchedule
+type BackupSchedule interface {
+	// UpdateBackupScheduleStatus update the status of the RedisBackupSchedule
+	UpdateBackupScheduleStatus(namespace string, schedule *redisv1beta1.RedisBackupSchedule) error
+}
+
+// BackupScheduleOption is the RedisBackupSchedule client that using API calls to kubernetes.
+type BackupScheduleOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewBackupSchedule returns a new RedisBackupSchedule client.
+func NewBackupSchedule(kubeClient client.Client, logger logr.Logger) BackupSchedule {
+	logger = logger.WithValues("service", "crd.redisBackupSchedule")
+	return &BackupScheduleOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// UpdateBackupScheduleStatus implement the BackupSchedule.Interface
+func (b *BackupScheduleOption) UpdateBackupScheduleStatus(namespace string, schedule *redisv1beta1.RedisBackupSchedule) error {
+	err := b.client.Status().Update(context.TODO(), schedule)
+	if err != nil {
+		b.logger.WithValues("namespace", namespace, "schedule", schedule.Name).Error(err, "redisBackupScheduleStatus")
+		return err
+	}
+	b.logger.WithValues("namespace", namespace, "schedule", schedule.Name, "lastScheduleTime", schedule.Status.LastScheduleTime).
+		V(3).Info("redisBackupScheduleStatus updated")
+	return nil
+}
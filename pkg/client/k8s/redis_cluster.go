@@ -3,6 +3,7 @@ package k8s
 import (
 	"context"
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
@@ -10,8 +11,14 @@ import (
 
 // Cluster the client that knows how to interact with kubernetes to manage RedisCluster
 type Cluster interface {
+	// GetCluster get the RedisCluster from kubernetes with namespace and name
+	GetCluster(namespace string, name string) (*redisv1beta1.RedisCluster, error)
+	// CreateCluster will create the given RedisCluster
+	CreateCluster(namespace string, cluster *redisv1beta1.RedisCluster) error
 	// UpdateCluster update the RedisCluster
 	UpdateCluster(namespace string, cluster *redisv1beta1.RedisCluster) error
+	// UpdateClusterSpec update the spec of the RedisCluster
+	UpdateClusterSpec(namespace string, cluster *redisv1beta1.RedisCluster) error
 }
 
 // ClusterOption is the RedisCluster client that using API calls to kubernetes.
@@ -29,6 +36,19 @@ func NewCluster(kubeClient client.Client, logger logr.Logger) Cluster {
 	}
 }
 
+// GetCluster implement the Cluster.Interface
+func (c *ClusterOption) GetCluster(namespace string, name string) (*redisv1beta1.RedisCluster, error) {
+	cluster := &redisv1beta1.RedisCluster{}
+	err := c.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
 // UpdateCluster implement the  Cluster.Interface
 func (c *ClusterOption) UpdateCluster(namespace string, cluster *redisv1beta1.RedisCluster) error {
 	cluster.Status.DescConditionsByTime()
@@ -42,3 +62,24 @@ func (c *ClusterOption) UpdateCluster(namespace string, cluster *redisv1beta1.Re
 		V(3).Info("redisClusterStatus updated")
 	return nil
 }
+
+// UpdateClusterSpec implement the Cluster.Interface
+func (c *ClusterOption) UpdateClusterSpec(namespace string, cluster *redisv1beta1.RedisCluster) error {
+	err := c.client.Update(context.TODO(), cluster)
+	if err != nil {
+		c.logger.WithValues("namespace", namespace, "cluster", cluster.Name).Error(err, "redisClusterSpec")
+		return err
+	}
+	c.logger.WithValues("namespace", namespace, "cluster", cluster.Name).V(3).Info("redisClusterSpec updated")
+	return nil
+}
+
+// CreateCluster implement the Cluster.Interface
+func (c *ClusterOption) CreateCluster(namespace string, cluster *redisv1beta1.RedisCluster) error {
+	err := c.client.Create(context.TODO(), cluster)
+	if err != nil {
+		return err
+	}
+	c.logger.WithValues("namespace", namespace, "cluster", cluster.Name).Info("redisCluster created")
+	return nil
+}
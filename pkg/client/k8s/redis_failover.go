@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RedisFailoverGVK is the kind of the clusters of the spotahome redis-operator, read as unstructured
+// objects so their CRD is not needed to build or run this operator
+var RedisFailoverGVK = schema.GroupVersionKind{Group: "databases.spotahome.com", Version: "v1", Kind: "RedisFailover"}
+
+// RedisFailover the client that knows how to interact with kubernetes to manage the spotahome RedisFailover
+type RedisFailover interface {
+	// GetRedisFailover get the RedisFailover from kubernetes with namespace and name
+	GetRedisFailover(namespace string, name string) (*unstructured.Unstructured, error)
+	// OrphanRedisFailover deletes the RedisFailover and keeps the objects it owns
+	OrphanRedisFailover(namespace string, name string) error
+}
+
+// RedisFailoverOption is the RedisFailover client that using API calls to kubernetes.
+type RedisFailoverOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewRedisFailover returns a new RedisFailover client.
+func NewRedisFailover(kubeClient client.Client, logger logr.Logger) RedisFailover {
+	logger = logger.WithValues("service", "crd.redisFailover")
+	return &RedisFailoverOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// GetRedisFailover implement the RedisFailover.Interface
+func (r *RedisFailoverOption) GetRedisFailover(namespace string, name string) (*unstructured.Unstructured, error) {
+	rf := &unstructured.Unstructured{}
+	rf.SetGroupVersionKind(RedisFailoverGVK)
+	err := r.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, rf)
+	if err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// OrphanRedisFailover implement the RedisFailover.Interface
+func (r *RedisFailoverOption) OrphanRedisFailover(namespace string, name string) error {
+	rf := &unstructured.Unstructured{}
+	rf.SetGroupVersionKind(RedisFailoverGVK)
+	rf.SetNamespace(namespace)
+	rf.SetName(name)
+	orphan := metav1.DeletePropagationOrphan
+	if err := r.client.Delete(context.TODO(), rf, client.PropagationPolicy(orphan)); err != nil {
+		return err
+	}
+	r.logger.WithValues("namespace", namespace, "redisFailover", name).Info("redisFailover deleted, its objects are orphaned")
+	return nil
+}
@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+)
+
+// Migration the client that knows how to interact with kubernetes to manage RedisMigration
+type Migration interface {
+	// GetMigration get the RedisMigration from kubernetes with namespace and name
+	GetMigration(namespace string, name string) (*redisv1beta1.RedisMigration, error)
+	// CreateMigration will create the given RedisMigration
+	CreateMigration(namespace string, migration *redisv1beta1.RedisMigration) error
+	// UpdateMigration update the spec of the RedisMigration
+	UpdateMigration(namespace string, migration *redisv1beta1.RedisMigration) error
+	// UpdateMigrationStatus update the status of the RedisMigration
+	UpdateMigrationStatus(namespace string, migration *redisv1beta1.RedisMigration) error
+}
+
+// MigrationOption is the RedisMigration client that using API calls to kubernetes.
+type MigrationOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewMigration returns a new RedisMigration client.
+func NewMigration(kubeClient client.Client, logger logr.Logger) Migration {
+	logger = logger.WithValues("service", "crd.redisMigration")
+	return &MigrationOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// UpdateMigration implement the Migration.Interface
+func (m *MigrationOption) UpdateMigration(namespace string, migration *redisv1beta1.RedisMigration) error {
+	err := m.client.Update(context.TODO(), migration)
+	if err != nil {
+		m.logger.WithValues("namespace", namespace, "migration", migration.Name).Error(err, "redisMigrationSpec")
+		return err
+	}
+	m.logger.WithValues("namespace", namespace, "migration", migration.Name).V(3).Info("redisMigrationSpec updated")
+	return nil
+}
+
+// UpdateMigrationStatus implement the Migration.Interface
+func (m *MigrationOption) UpdateMigrationStatus(namespace string, migration *redisv1beta1.RedisMigration) error {
+	err := m.client.Status().Update(context.TODO(), migration)
+	if err != nil {
+		m.logger.WithValues("namespace", namespace, "migration", migration.Name).Error(err, "redisMigrationStatus")
+		return err
+	}
+	m.logger.WithValues("namespace", namespace, "migration", migration.Name, "phase", migration.Status.Phase).
+		V(3).Info("redisMigrationStatus updated")
+	return nil
+}
+
+// GetMigration implement the Migration.Interface
+func (m *MigrationOption) GetMigration(namespace string, name string) (*redisv1beta1.RedisMigration, error) {
+	migration := &redisv1beta1.RedisMigration{}
+	err := m.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, migration)
+	if err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+// CreateMigration implement the Migration.Interface
+func (m *MigrationOption) CreateMigration(namespace string, migration *redisv1beta1.RedisMigration) error {
+	err := m.client.Create(context.TODO(), migration)
+	if err != nil {
+		return err
+	}
+	m.logger.WithValues("namespace", namespace, "migration", migration.Name).Info("redisMigration created")
+	return nil
+}
@@ -0,0 +1,45 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Secret the client that knows how to interact with kubernetes to manage them
+type Secret interface {
+	// GetSecret get Secret from kubernetes with namespace and name
+	GetSecret(namespace string, name string) (*corev1.Secret, error)
+}
+
+// SecretOption is the secret client interface implementation that using API calls to kubernetes.
+type SecretOption struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// NewSecret returns a new Secret client.
+func NewSecret(kubeClient client.Client, logger logr.Logger) Secret {
+	logger = logger.WithValues("service", "k8s.secret")
+	return &SecretOption{
+		client: kubeClient,
+		logger: logger,
+	}
+}
+
+// GetSecret implement the Secret.Interface
+func (s *SecretOption) GetSecret(namespace string, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := s.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, secret)
+
+	if err != nil {
+		return nil, err
+	}
+	return secret, err
+}
@@ -8,6 +8,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -27,6 +28,8 @@ type StatefulSet interface {
 	CreateOrUpdateStatefulSet(namespace string, StatefulSet *appsv1.StatefulSet) error
 	// DeleteStatefulSet will delete the given StatefulSet
 	DeleteStatefulSet(namespace string, name string) error
+	// OrphanStatefulSet deletes the given StatefulSet and keeps its pods
+	OrphanStatefulSet(namespace string, name string) error
 	// ListStatefulSets get set of StatefulSet on a given namespace
 	ListStatefulSets(namespace string) (*appsv1.StatefulSetList, error)
 	CreateIfNotExistsStatefulSet(namespace string, statefulSet *appsv1.StatefulSet) error
@@ -146,6 +149,23 @@ func (s *StatefulSetOption) DeleteStatefulSet(namespace, name string) error {
 	return s.client.Delete(context.TODO(), statefulset)
 }
 
+// OrphanStatefulSet implement the StatefulSet.Interface
+func (s *StatefulSetOption) OrphanStatefulSet(namespace, name string) error {
+	statefulset := &appsv1.StatefulSet{}
+	if err := s.client.Get(context.TODO(), types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, statefulset); err != nil {
+		return err
+	}
+	orphan := metav1.DeletePropagationOrphan
+	if err := s.client.Delete(context.TODO(), statefulset, client.PropagationPolicy(orphan)); err != nil {
+		return err
+	}
+	s.logger.WithValues("namespace", namespace, "statefulSet", name).Info("statefulSet deleted, its pods are orphaned")
+	return nil
+}
+
 // ListStatefulSets implement the StatefulSet.Interface
 func (s *StatefulSetOption) ListStatefulSets(namespace string) (*appsv1.StatefulSetList, error) {
 	statelfulSets := &appsv1.StatefulSetList{}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -19,14 +20,54 @@ type Client interface {
 	GetNumberSentinelSlavesInMemory(ip string, auth *util.AuthConfig) (int32, error)
 	ResetSentinel(ip string, auth *util.AuthConfig) error
 	GetSlaveMasterIP(ip string, auth *util.AuthConfig) (string, error)
+	GetSlaveMasterAddr(ip string, auth *util.AuthConfig) (string, error)
+	GetReplicaMasterIPs(ip string, auth *util.AuthConfig) ([]string, error)
 	IsMaster(ip string, auth *util.AuthConfig) (bool, error)
 	MonitorRedis(ip string, monitor string, quorum string, auth *util.AuthConfig) error
 	MakeMaster(ip string, auth *util.AuthConfig) error
 	MakeSlaveOf(ip string, masterIP string, auth *util.AuthConfig) error
+	MakeSlaveOfAddr(ip string, host string, port string, auth *util.AuthConfig) error
 	GetSentinelMonitor(ip string, auth *util.AuthConfig) (string, error)
+	IsSentinelMasterDown(ip string, auth *util.AuthConfig) (bool, error)
 	SetCustomSentinelConfig(ip string, configs []string, auth *util.AuthConfig) error
 	SetCustomRedisConfig(ip string, configs map[string]string, auth *util.AuthConfig) error
 	GetAllRedisConfig(rClient *rediscli.Client) (map[string]string, error)
+	IsReplicaSynced(ip string, auth *util.AuthConfig) (bool, error)
+	SentinelFailover(ip string, auth *util.AuthConfig) error
+	GetErrorCount(ip string, auth *util.AuthConfig) (int64, error)
+	RunCommand(ip string, args []string, auth *util.AuthConfig) error
+	BgSave(ip string, auth *util.AuthConfig) error
+	GetSaveStatus(ip string, auth *util.AuthConfig) (*SaveStatus, error)
+	GetReplicationOffset(ip string, auth *util.AuthConfig) (int64, error)
+	GetLoadingStatus(ip string, auth *util.AuthConfig) (bool, float64, error)
+	GetKeyCount(ip string, auth *util.AuthConfig) (int64, error)
+	GetConfig(ip string, name string, auth *util.AuthConfig) (string, error)
+}
+
+// SaveStatus is the state of the background saves of a redis
+type SaveStatus struct {
+	// InProgress is true while a background save is running
+	InProgress bool
+	// LastSaveTime is the unix time of the last successful save
+	LastSaveTime int64
+	// LastSaveOK is false if the last background save failed
+	LastSaveOK bool
+}
+
+// UnsupportedConfigError lists the custom configs unknown to the version of a redis,
+// the other configs are applied
+type UnsupportedConfigError struct {
+	Configs []string
+	Addr    string
+	// Version is the version of the redis, empty if it wasn't read
+	Version string
+}
+
+func (e *UnsupportedConfigError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("configs %s are not supported by redis %s", strings.Join(e.Configs, ", "), e.Addr)
+	}
+	return fmt.Sprintf("configs %s are not supported by redis %s, version %s", strings.Join(e.Configs, ", "), e.Addr, e.Version)
 }
 
 type client struct {
@@ -41,8 +82,19 @@ const (
 	sentinelsNumberREString = "sentinels=([0-9]+)"
 	slaveNumberREString     = "slaves=([0-9]+)"
 	sentinelStatusREString  = "status=([a-z]+)"
-	redisMasterHostREString = "master_host:([0-9a-zA-Z:.]+)"
+	redisMasterHostREString = "master_host:([0-9a-zA-Z:._-]+)"
+	redisMasterPortREString = "master_port:([0-9]+)"
+	redisErrorStatREString  = "errorstat_[^:]+:count=([0-9]+)"
+	redisBgSaveREString     = "rdb_bgsave_in_progress:([0-9]+)"
+	redisLastSaveREString   = "rdb_last_save_time:([0-9]+)"
+	redisReplOffsetREString = "(?:slave_repl_offset|master_repl_offset):([0-9]+)"
+	redisLoadingREString    = "(?m)^loading:([0-9]+)"
+	redisLoadedPercREString = "loading_loaded_perc:([0-9.]+)"
+	redisKeysREString       = "db[0-9]+:keys=([0-9]+)"
+	redisLastBgSaveOK       = "rdb_last_bgsave_status:ok"
 	redisRoleMaster         = "role:master"
+	redisLinkStatusUp       = "master_link_status:up"
+	redisSyncNotInProgress  = "master_sync_in_progress:0"
 	redisPort               = "6379"
 	sentinelPort            = "26379"
 	masterName              = "mymaster"
@@ -57,6 +109,14 @@ var (
 	sentinelStatusRE  = regexp.MustCompile(sentinelStatusREString)
 	slaveNumberRE     = regexp.MustCompile(slaveNumberREString)
 	redisMasterHostRE = regexp.MustCompile(redisMasterHostREString)
+	redisMasterPortRE = regexp.MustCompile(redisMasterPortREString)
+	redisErrorStatRE  = regexp.MustCompile(redisErrorStatREString)
+	redisBgSaveRE     = regexp.MustCompile(redisBgSaveREString)
+	redisLastSaveRE   = regexp.MustCompile(redisLastSaveREString)
+	redisReplOffsetRE = regexp.MustCompile(redisReplOffsetREString)
+	redisLoadingRE    = regexp.MustCompile(redisLoadingREString)
+	redisLoadedPercRE = regexp.MustCompile(redisLoadedPercREString)
+	redisKeysRE       = regexp.MustCompile(redisKeysREString)
 )
 
 // GetNumberSentinelsInMemory return the number of sentinels that the requested sentinel has
@@ -95,8 +155,12 @@ func (c *client) GetNumberSentinelSlavesInMemory(ip string, auth *util.AuthConfi
 	if err = isSentinelReady(info); err != nil {
 		return 0, err
 	}
+	d, err := c.getDialect(rClient)
+	if err != nil {
+		return 0, err
+	}
 
-	cmd := rediscli.NewSliceCmd("sentinel", "slaves", masterName)
+	cmd := rediscli.NewSliceCmd("sentinel", d.sentinelReplicas(), masterName)
 	rClient.Process(cmd)
 	slaveInfoBlobs, err := cmd.Result()
 	if err != nil {
@@ -165,6 +229,47 @@ func (c *client) GetSlaveMasterIP(ip string, auth *util.AuthConfig) (string, err
 	return match[1], nil
 }
 
+// GetSlaveMasterAddr returns the host:port of the master of the given redis, or an empty string if it's master
+func (c *client) GetSlaveMasterAddr(ip string, auth *util.AuthConfig) (string, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("replication").Result()
+	if err != nil {
+		return "", err
+	}
+	return getMasterAddr(info), nil
+}
+
+func getMasterAddr(info string) string {
+	host := redisMasterHostRE.FindStringSubmatch(info)
+	port := redisMasterPortRE.FindStringSubmatch(info)
+	if len(host) == 0 || len(port) == 0 {
+		return ""
+	}
+	return net.JoinHostPort(host[1], port[1])
+}
+
+// GetReplicaMasterIPs returns all the masters of the given redis, a keydb multi master replicates from many
+func (c *client) GetReplicaMasterIPs(ip string, auth *util.AuthConfig) ([]string, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("replication").Result()
+	if err != nil {
+		return nil, err
+	}
+	return getMasterHosts(info), nil
+}
+
+func getMasterHosts(info string) []string {
+	masters := []string{}
+	for _, match := range redisMasterHostRE.FindAllStringSubmatch(info, -1) {
+		masters = append(masters, match[1])
+	}
+	return masters
+}
+
 func (c *client) IsMaster(ip string, auth *util.AuthConfig) (bool, error) {
 	options := c.setOptions(ip, redisPort, auth)
 	rClient := rediscli.NewClient(options)
@@ -176,6 +281,189 @@ func (c *client) IsMaster(ip string, auth *util.AuthConfig) (bool, error) {
 	return strings.Contains(info, redisRoleMaster), nil
 }
 
+// IsReplicaSynced returns true when the given redis is connected to its master and has finished the sync
+func (c *client) IsReplicaSynced(ip string, auth *util.AuthConfig) (bool, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("replication").Result()
+	if err != nil {
+		return false, err
+	}
+	return isReplicaSynced(info), nil
+}
+
+func isReplicaSynced(info string) bool {
+	return strings.Contains(info, redisLinkStatusUp) && strings.Contains(info, redisSyncNotInProgress)
+}
+
+// SentinelFailover forces the sentinel to fail over the master to one of its replicas
+func (c *client) SentinelFailover(ip string, auth *util.AuthConfig) error {
+	options := c.setOptions(ip, sentinelPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	cmd := rediscli.NewStatusCmd("SENTINEL", "FAILOVER", masterName)
+	rClient.Process(cmd)
+	return cmd.Err()
+}
+
+// GetErrorCount returns the number of error replies sent by the given redis, from INFO errorstats.
+// Versions before 6.2 have no errorstats and always return 0.
+func (c *client) GetErrorCount(ip string, auth *util.AuthConfig) (int64, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("errorstats").Result()
+	if err != nil {
+		return 0, err
+	}
+	return sumErrorStats(info)
+}
+
+func sumErrorStats(info string) (int64, error) {
+	var total int64
+	for _, match := range redisErrorStatRE.FindAllStringSubmatch(info, -1) {
+		count, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// RunCommand runs the given command on the redis and returns its error
+func (c *client) RunCommand(ip string, args []string, auth *util.AuthConfig) error {
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	cmdArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		cmdArgs[i] = arg
+	}
+	cmd := rediscli.NewCmd(cmdArgs...)
+	rClient.Process(cmd)
+	return cmd.Err()
+}
+
+// BgSave starts a background save, a save already in progress is not an error
+func (c *client) BgSave(ip string, auth *util.AuthConfig) error {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	if err := rClient.BgSave().Err(); err != nil && !strings.Contains(err.Error(), "already in progress") {
+		return err
+	}
+	return nil
+}
+
+// GetSaveStatus returns the state of the background saves from INFO persistence
+func (c *client) GetSaveStatus(ip string, auth *util.AuthConfig) (*SaveStatus, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("persistence").Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseSaveStatus(info)
+}
+
+func parseSaveStatus(info string) (*SaveStatus, error) {
+	inProgress := redisBgSaveRE.FindStringSubmatch(info)
+	lastSave := redisLastSaveRE.FindStringSubmatch(info)
+	if len(inProgress) == 0 || len(lastSave) == 0 {
+		return nil, errors.New("persistence info not found")
+	}
+	lastSaveTime, err := strconv.ParseInt(lastSave[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &SaveStatus{
+		InProgress:   inProgress[1] != "0",
+		LastSaveTime: lastSaveTime,
+		LastSaveOK:   strings.Contains(info, redisLastBgSaveOK),
+	}, nil
+}
+
+// GetReplicationOffset returns the replication offset of a replica, or of a master
+func (c *client) GetReplicationOffset(ip string, auth *util.AuthConfig) (int64, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("replication").Result()
+	if err != nil {
+		return 0, err
+	}
+	return parseReplicationOffset(info)
+}
+
+func parseReplicationOffset(info string) (int64, error) {
+	match := redisReplOffsetRE.FindStringSubmatch(info)
+	if len(match) == 0 {
+		return 0, errors.New("replication offset not found")
+	}
+	return strconv.ParseInt(match[1], 10, 64)
+}
+
+// GetLoadingStatus returns true and the loaded percentage while the redis loads its data from disk
+func (c *client) GetLoadingStatus(ip string, auth *util.AuthConfig) (bool, float64, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("persistence").Result()
+	if err != nil {
+		return false, 0, err
+	}
+	return parseLoadingStatus(info)
+}
+
+func parseLoadingStatus(info string) (bool, float64, error) {
+	match := redisLoadingRE.FindStringSubmatch(info)
+	if len(match) == 0 {
+		return false, 0, errors.New("loading status not found")
+	}
+	if match[1] == "0" {
+		return false, 0, nil
+	}
+	perc := redisLoadedPercRE.FindStringSubmatch(info)
+	if len(perc) == 0 {
+		return true, 0, nil
+	}
+	loaded, err := strconv.ParseFloat(perc[1], 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return true, loaded, nil
+}
+
+// GetKeyCount returns the number of keys of all the databases
+func (c *client) GetKeyCount(ip string, auth *util.AuthConfig) (int64, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	info, err := rClient.Info("keyspace").Result()
+	if err != nil {
+		return 0, err
+	}
+	return parseKeyCount(info)
+}
+
+func parseKeyCount(info string) (int64, error) {
+	var keys int64
+	for _, match := range redisKeysRE.FindAllStringSubmatch(info, -1) {
+		n, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		keys += n
+	}
+	return keys, nil
+}
+
 func (c *client) MonitorRedis(ip string, monitor string, quorum string, auth *util.AuthConfig) error {
 	options := c.setOptions(ip, sentinelPort, auth)
 	rClient := rediscli.NewClient(options)
@@ -220,20 +508,40 @@ func (c *client) MakeMaster(ip string, auth *util.AuthConfig) error {
 	options := c.setOptions(ip, redisPort, auth)
 	rClient := rediscli.NewClient(options)
 	defer rClient.Close()
-	if res := rClient.SlaveOf("NO", "ONE"); res.Err() != nil {
-		return res.Err()
+	d, err := c.getDialect(rClient)
+	if err != nil {
+		return err
 	}
-	return nil
+	cmd := rediscli.NewStatusCmd(d.replicaOf(), "NO", "ONE")
+	rClient.Process(cmd)
+	return cmd.Err()
 }
 
 func (c *client) MakeSlaveOf(ip string, masterIP string, auth *util.AuthConfig) error {
 	options := c.setOptions(ip, redisPort, auth)
 	rClient := rediscli.NewClient(options)
 	defer rClient.Close()
-	if res := rClient.SlaveOf(masterIP, redisPort); res.Err() != nil {
-		return res.Err()
+	d, err := c.getDialect(rClient)
+	if err != nil {
+		return err
 	}
-	return nil
+	cmd := rediscli.NewStatusCmd(d.replicaOf(), masterIP, redisPort)
+	rClient.Process(cmd)
+	return cmd.Err()
+}
+
+// MakeSlaveOfAddr makes the redis replicate from a master listening on another port, like a master outside of the cluster
+func (c *client) MakeSlaveOfAddr(ip string, host string, port string, auth *util.AuthConfig) error {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	d, err := c.getDialect(rClient)
+	if err != nil {
+		return err
+	}
+	cmd := rediscli.NewStatusCmd(d.replicaOf(), host, port)
+	rClient.Process(cmd)
+	return cmd.Err()
 }
 
 func (c *client) GetSentinelMonitor(ip string, auth *util.AuthConfig) (string, error) {
@@ -250,6 +558,31 @@ func (c *client) GetSentinelMonitor(ip string, auth *util.AuthConfig) (string, e
 	return masterIP, nil
 }
 
+// IsSentinelMasterDown is true when the sentinel flags the monitored master as subjectively
+// or objectively down
+func (c *client) IsSentinelMasterDown(ip string, auth *util.AuthConfig) (bool, error) {
+	options := c.setOptions(ip, sentinelPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	cmd := rediscli.NewSliceCmd("SENTINEL", "master", masterName)
+	rClient.Process(cmd)
+	res, err := cmd.Result()
+	if err != nil {
+		return false, err
+	}
+	return isMasterDown(slaveInfoFieldByName("flags", res)), nil
+}
+
+func isMasterDown(flags string) bool {
+	for _, flag := range strings.Split(flags, ",") {
+		if flag == "s_down" || flag == "o_down" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCustomSentinelConfig applies the configs accepted by SENTINEL SET, the others are in the config file
 func (c *client) SetCustomSentinelConfig(ip string, configs []string, auth *util.AuthConfig) error {
 	options := c.setOptions(ip, sentinelPort, auth)
 	rClient := rediscli.NewClient(options)
@@ -260,6 +593,9 @@ func (c *client) SetCustomSentinelConfig(ip string, configs []string, auth *util
 		if err != nil {
 			return err
 		}
+		if !IsSentinelRuntimeConfig(param) {
+			continue
+		}
 		if err := c.applySentinelConfig(param, value, rClient); err != nil {
 			return err
 		}
@@ -272,18 +608,47 @@ func (c *client) SetCustomRedisConfig(ip string, configs map[string]string, auth
 	rClient := rediscli.NewClient(options)
 	defer rClient.Close()
 
-	for param, value := range configs {
-		//param, value, err := c.getConfigParameters(config)
-		//if err != nil {
-		//	return err
-		//}
+	d, err := c.getDialect(rClient)
+	if err != nil {
+		return err
+	}
+	current, err := c.GetAllRedisConfig(rClient)
+	if err != nil {
+		return err
+	}
+	supported, unsupported := splitSupportedConfig(configs, current, d)
+	for param, value := range supported {
 		if err := c.applyRedisConfig(param, value, rClient); err != nil {
 			return err
 		}
 	}
+	if len(unsupported) > 0 {
+		return &UnsupportedConfigError{Configs: unsupported, Addr: ip, Version: d.version.String()}
+	}
 	return nil
 }
 
+// splitSupportedConfig returns the runtime configs known to the redis, named as in its version,
+// and the sorted names of the configs it doesn't know. The configs set in the config file are left out,
+// the redis are restarted to apply them.
+func splitSupportedConfig(configs map[string]string, current map[string]string, d *dialect) (map[string]string, []string) {
+	supported := make(map[string]string, len(configs))
+	var unsupported []string
+	for param, value := range configs {
+		param = d.configName(param)
+		if IsRestartConfig(param) {
+			continue
+		}
+		if _, ok := current[param]; !ok {
+			unsupported = append(unsupported, param)
+			continue
+		}
+		supported[param] = value
+	}
+	sort.Strings(unsupported)
+	return supported, unsupported
+}
+
 func (c *client) GetAllRedisConfig(rClient *rediscli.Client) (map[string]string, error) {
 	result := rClient.ConfigGet("*")
 	if result.Err() != nil {
@@ -299,6 +664,22 @@ func (c *client) GetAllRedisConfig(rClient *rediscli.Client) (map[string]string,
 	return valMap, nil
 }
 
+// GetConfig returns the value of a config of the redis
+func (c *client) GetConfig(ip string, name string, auth *util.AuthConfig) (string, error) {
+	options := c.setOptions(ip, redisPort, auth)
+	rClient := rediscli.NewClient(options)
+	defer rClient.Close()
+	val, err := rClient.ConfigGet(name).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(val) < 2 {
+		return "", fmt.Errorf("config %s not found", name)
+	}
+	value, _ := val[1].(string)
+	return value, nil
+}
+
 func (c *client) applyRedisConfig(parameter string, value string, rClient *rediscli.Client) error {
 	result := rClient.ConfigSet(parameter, value)
 	return result.Err()
@@ -318,13 +699,19 @@ func (c *client) getConfigParameters(config string) (parameter string, value str
 	return s[0], strings.Join(s[1:], " "), nil
 }
 
+// setOptions returns the options to connect to the port of the ip, the ip can also be the host:port
+// of a redis outside of the cluster
 func (c *client) setOptions(ip, port string, auth *util.AuthConfig) *rediscli.Options {
 	passwd := auth.Password
 	if port == sentinelPort {
 		passwd = ""
 	}
+	addr := net.JoinHostPort(ip, port)
+	if _, _, err := net.SplitHostPort(ip); err == nil {
+		addr = ip
+	}
 	return &rediscli.Options{
-		Addr:     net.JoinHostPort(ip, port),
+		Addr:     addr,
 		Password: passwd,
 		DB:       0,
 	}
@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -89,3 +90,353 @@ func Test_slaveInfoFieldByName(t *testing.T) {
 		})
 	}
 }
+
+func Test_isReplicaSynced(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want bool
+	}{
+		{
+			name: "synced",
+			info: "# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\nmaster_link_status:up\r\nmaster_last_io_seconds_ago:1\r\nmaster_sync_in_progress:0\r\n",
+			want: true,
+		},
+		{
+			name: "link down",
+			info: "# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\nmaster_link_status:down\r\nmaster_last_io_seconds_ago:-1\r\nmaster_sync_in_progress:0\r\n",
+			want: false,
+		},
+		{
+			name: "syncing",
+			info: "# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\nmaster_link_status:down\r\nmaster_last_io_seconds_ago:-1\r\nmaster_sync_in_progress:1\r\n",
+			want: false,
+		},
+		{
+			name: "master",
+			info: "# Replication\r\nrole:master\r\nconnected_slaves:2\r\n",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReplicaSynced(tt.info); got != tt.want {
+				t.Errorf("isReplicaSynced() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sumErrorStats(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want int64
+	}{
+		{
+			name: "errors",
+			info: "# Errorstats\r\nerrorstat_ERR:count=3\r\nerrorstat_WRONGTYPE:count=2\r\n",
+			want: 5,
+		},
+		{
+			name: "no errors",
+			info: "# Errorstats\r\n",
+			want: 0,
+		},
+		{
+			name: "no errorstats section",
+			info: "",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sumErrorStats(tt.info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("sumErrorStats() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getMasterHosts(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want []string
+	}{
+		{
+			name: "replica",
+			info: "# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\nmaster_link_status:up\r\n",
+			want: []string{"10.0.0.1"},
+		},
+		{
+			name: "multi master",
+			info: "# Replication\r\nrole:active-replica\r\nmaster_global_link_status:up\r\nMaster 0: \r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\nmaster_link_status:up\r\nMaster 1: \r\nmaster_host:10.0.0.2\r\nmaster_port:6379\r\nmaster_link_status:up\r\n",
+			want: []string{"10.0.0.1", "10.0.0.2"},
+		},
+		{
+			name: "master",
+			info: "# Replication\r\nrole:master\r\nconnected_slaves:2\r\n",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMasterHosts(tt.info); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMasterHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getMasterAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want string
+	}{
+		{
+			name: "replica",
+			info: "# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\nmaster_link_status:up\r\n",
+			want: "10.0.0.1:6379",
+		},
+		{
+			name: "replica of a hostname",
+			info: "# Replication\r\nrole:slave\r\nmaster_host:redis-0.redis-primary.example.com\r\nmaster_port:16379\r\nmaster_link_status:up\r\n",
+			want: "redis-0.redis-primary.example.com:16379",
+		},
+		{
+			name: "master",
+			info: "# Replication\r\nrole:master\r\nconnected_slaves:2\r\n",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMasterAddr(tt.info); got != tt.want {
+				t.Errorf("getMasterAddr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isMasterDown(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags string
+		want  bool
+	}{
+		{name: "ok", flags: "master", want: false},
+		{name: "subjectively down", flags: "s_down,master", want: true},
+		{name: "objectively down", flags: "s_down,o_down,master", want: true},
+		{name: "failover in progress", flags: "master,failover_in_progress", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isMasterDown(tt.flags); got != tt.want {
+				t.Errorf("isMasterDown(%q) = %v, want %v", tt.flags, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseSaveStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		want    *SaveStatus
+		wantErr bool
+	}{
+		{
+			name: "saving",
+			info: "# Persistence\r\nloading:0\r\nrdb_changes_since_last_save:10\r\nrdb_bgsave_in_progress:1\r\nrdb_last_save_time:1565000000\r\nrdb_last_bgsave_status:ok\r\n",
+			want: &SaveStatus{InProgress: true, LastSaveTime: 1565000000, LastSaveOK: true},
+		},
+		{
+			name: "last save failed",
+			info: "# Persistence\r\nloading:0\r\nrdb_bgsave_in_progress:0\r\nrdb_last_save_time:1565000000\r\nrdb_last_bgsave_status:err\r\n",
+			want: &SaveStatus{InProgress: false, LastSaveTime: 1565000000, LastSaveOK: false},
+		},
+		{
+			name:    "no persistence info",
+			info:    "# Replication\r\nrole:master\r\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSaveStatus(tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSaveStatus() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSaveStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseReplicationOffset(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		want    int64
+		wantErr bool
+	}{
+		{
+			name: "replica",
+			info: "# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nslave_repl_offset:1234\r\nslave_priority:1\r\nmaster_repl_offset:1234\r\n",
+			want: 1234,
+		},
+		{
+			name: "master",
+			info: "# Replication\r\nrole:master\r\nconnected_slaves:0\r\nmaster_repl_offset:42\r\n",
+			want: 42,
+		},
+		{
+			name:    "no offset",
+			info:    "# Replication\r\nrole:master\r\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReplicationOffset(tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReplicationOffset() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseReplicationOffset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseLoadingStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		info        string
+		wantLoading bool
+		wantLoaded  float64
+		wantErr     bool
+	}{
+		{
+			name:        "loading",
+			info:        "# Persistence\r\nloading:1\r\nloading_start_time:1564654800\r\nloading_loaded_perc:45.20\r\nloading_eta_seconds:12\r\n",
+			wantLoading: true,
+			wantLoaded:  45.2,
+		},
+		{
+			name: "loaded",
+			info: "# Persistence\r\nloading:0\r\nrdb_changes_since_last_save:0\r\n",
+		},
+		{
+			name:    "no persistence info",
+			info:    "# Server\r\nredis_version:5.0.4\r\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loading, loaded, err := parseLoadingStatus(tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseLoadingStatus() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if loading != tt.wantLoading || loaded != tt.wantLoaded {
+				t.Errorf("parseLoadingStatus() = %v, %v, want %v, %v", loading, loaded, tt.wantLoading, tt.wantLoaded)
+			}
+		})
+	}
+}
+
+func Test_parseKeyCount(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want int64
+	}{
+		{
+			name: "several databases",
+			info: "# Keyspace\r\ndb0:keys=1200,expires=3,avg_ttl=1000\r\ndb2:keys=34,expires=0,avg_ttl=0\r\n",
+			want: 1234,
+		},
+		{
+			name: "empty",
+			info: "# Keyspace\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKeyCount(tt.info)
+			if err != nil {
+				t.Errorf("parseKeyCount() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseKeyCount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitSupportedConfig(t *testing.T) {
+	configs := map[string]string{
+		"maxmemory":           "100mb",
+		"replica-priority":    "50",
+		"lfu-log-factor":      "10",
+		"activedefrag":        "yes",
+		"io-threads":          "4",
+		"min-slaves-to-write": "1",
+	}
+	tests := []struct {
+		name            string
+		version         version
+		current         map[string]string
+		wantSupported   map[string]string
+		wantUnsupported []string
+	}{
+		{
+			name:    "redis 3",
+			version: version{3, 2, 12},
+			current: map[string]string{"maxmemory": "0", "slave-priority": "100", "min-slaves-to-write": "0"},
+			wantSupported: map[string]string{
+				"maxmemory":           "100mb",
+				"slave-priority":      "50",
+				"min-slaves-to-write": "1",
+			},
+			wantUnsupported: []string{"activedefrag", "lfu-log-factor"},
+		},
+		{
+			name:    "redis 5",
+			version: version{5, 0, 5},
+			current: map[string]string{
+				"maxmemory": "0", "replica-priority": "100", "lfu-log-factor": "10", "activedefrag": "no", "min-replicas-to-write": "0",
+			},
+			wantSupported: map[string]string{
+				"maxmemory":             "100mb",
+				"replica-priority":      "50",
+				"lfu-log-factor":        "10",
+				"activedefrag":          "yes",
+				"min-replicas-to-write": "1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supported, unsupported := splitSupportedConfig(configs, tt.current, &dialect{version: tt.version})
+			if !reflect.DeepEqual(supported, tt.wantSupported) {
+				t.Errorf("splitSupportedConfig() supported = %v, want %v", supported, tt.wantSupported)
+			}
+			if !reflect.DeepEqual(unsupported, tt.wantUnsupported) {
+				t.Errorf("splitSupportedConfig() unsupported = %v, want %v", unsupported, tt.wantUnsupported)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	rediscli "github.com/go-redis/redis"
+)
+
+const redisVersionREString = "redis_version:([0-9]+)\\.([0-9]+)\\.([0-9]+)"
+
+var redisVersionRE = regexp.MustCompile(redisVersionREString)
+
+// configAliases are the config names renamed from slave to replica in redis 5.0
+var configAliases = map[string]string{
+	"slaveof":                "replicaof",
+	"slave-priority":         "replica-priority",
+	"slave-read-only":        "replica-read-only",
+	"slave-serve-stale-data": "replica-serve-stale-data",
+	"slave-lazy-flush":       "replica-lazy-flush",
+	"slave-ignore-maxmemory": "replica-ignore-maxmemory",
+	"slave-announce-ip":      "replica-announce-ip",
+	"slave-announce-port":    "replica-announce-port",
+	"min-slaves-to-write":    "min-replicas-to-write",
+	"min-slaves-max-lag":     "min-replicas-max-lag",
+}
+
+// restartConfigs are the configs refused by CONFIG SET, redis only reads them from its config file when
+// it starts. The configs set by the operator, like port and bind, are refused by the validation of the cluster.
+var restartConfigs = map[string]bool{
+	"databases":                true,
+	"io-threads":               true,
+	"io-threads-do-reads":      true,
+	"cluster-enabled":          true,
+	"cluster-config-file":      true,
+	"cluster-port":             true,
+	"tcp-backlog":              true,
+	"unixsocket":               true,
+	"unixsocketperm":           true,
+	"supervised":               true,
+	"pidfile":                  true,
+	"logfile":                  true,
+	"syslog-enabled":           true,
+	"syslog-ident":             true,
+	"syslog-facility":          true,
+	"always-show-logo":         true,
+	"set-proc-title":           true,
+	"aclfile":                  true,
+	"appendfilename":           true,
+	"appenddirname":            true,
+	"disable-thp":              true,
+	"enable-debug-command":     true,
+	"enable-module-command":    true,
+	"enable-protected-configs": true,
+}
+
+// sentinelRuntimeConfigs are the configs of a monitored master accepted by SENTINEL SET, the other
+// sentinel configs are only read from the config file when the sentinel starts
+var sentinelRuntimeConfigs = map[string]bool{
+	"down-after-milliseconds":         true,
+	"failover-timeout":                true,
+	"parallel-syncs":                  true,
+	"quorum":                          true,
+	"notification-script":             true,
+	"client-reconfig-script":          true,
+	"auth-pass":                       true,
+	"auth-user":                       true,
+	"rename-command":                  true,
+	"master-reboot-down-after-period": true,
+}
+
+// version is the version of a redis or sentinel server
+type version struct {
+	major, minor, patch int
+}
+
+func (v version) atLeast(major, minor int) bool {
+	return v.major > major || (v.major == major && v.minor >= minor)
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+// dialect chooses the commands and config names understood by a given redis version.
+// The INFO fields and the fields of SENTINEL REPLICAS kept their slave names when the commands and
+// configs were renamed, redis 7 still reports role:slave, slave_repl_offset, slaves= and slave-priority,
+// so the INFO parsers are the same for every version.
+type dialect struct {
+	version version
+}
+
+// getDialect reads the version of the server from INFO server
+func (c *client) getDialect(rClient *rediscli.Client) (*dialect, error) {
+	info, err := rClient.Info("server").Result()
+	if err != nil {
+		return nil, err
+	}
+	return newDialect(info)
+}
+
+func newDialect(info string) (*dialect, error) {
+	match := redisVersionRE.FindStringSubmatch(info)
+	if len(match) == 0 {
+		return nil, fmt.Errorf("redis version not found")
+	}
+	v := version{}
+	for i, p := range []*int{&v.major, &v.minor, &v.patch} {
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return nil, err
+		}
+		*p = n
+	}
+	return &dialect{version: v}, nil
+}
+
+// hasReplicaNames is true for the versions that renamed slave to replica
+func (d *dialect) hasReplicaNames() bool {
+	return d.version.atLeast(5, 0)
+}
+
+// replicaOf returns the command that changes the master of a redis
+func (d *dialect) replicaOf() string {
+	if d.hasReplicaNames() {
+		return "REPLICAOF"
+	}
+	return "SLAVEOF"
+}
+
+// sentinelReplicas returns the sentinel subcommand that lists the replicas of a master
+func (d *dialect) sentinelReplicas() string {
+	if d.hasReplicaNames() {
+		return "REPLICAS"
+	}
+	return "SLAVES"
+}
+
+// configName returns the name of the config in this version, whatever name is used in the spec
+func (d *dialect) configName(name string) string {
+	if d.hasReplicaNames() {
+		if replicaName, ok := configAliases[name]; ok {
+			return replicaName
+		}
+		return name
+	}
+	for slaveName, replicaName := range configAliases {
+		if replicaName == name {
+			return slaveName
+		}
+	}
+	return name
+}
+
+// IsRestartConfig is true when a change of the config needs a restart of redis,
+// it is written to the config file of redis instead of being set at runtime
+func IsRestartConfig(name string) bool {
+	return restartConfigs[name]
+}
+
+// IsSentinelRuntimeConfig is true when the sentinel config can be changed with SENTINEL SET,
+// the other sentinel configs are written to the config file and restart the sentinels
+func IsSentinelRuntimeConfig(name string) bool {
+	return sentinelRuntimeConfigs[name]
+}
+
+// ConfigAlias returns the other name of a config renamed from slave to replica, or the name itself
+func ConfigAlias(name string) string {
+	if replicaName, ok := configAliases[name]; ok {
+		return replicaName
+	}
+	for slaveName, replicaName := range configAliases {
+		if replicaName == name {
+			return slaveName
+		}
+	}
+	return name
+}
@@ -0,0 +1,204 @@
+package redis
+
+import (
+	"testing"
+)
+
+func Test_newDialect(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		want    version
+		wantErr bool
+	}{
+		{
+			name: "redis 4",
+			info: "# Server\r\nredis_version:4.0.14\r\nredis_git_sha1:00000000\r\n",
+			want: version{4, 0, 14},
+		},
+		{
+			name: "redis 7",
+			info: "# Server\r\nredis_version:7.2.4\r\nredis_git_sha1:00000000\r\n",
+			want: version{7, 2, 4},
+		},
+		{
+			name:    "no version",
+			info:    "# Server\r\nredis_git_sha1:00000000\r\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newDialect(tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newDialect() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.version != tt.want {
+				t.Errorf("newDialect() version = %v, want %v", got.version, tt.want)
+			}
+		})
+	}
+}
+
+func Test_dialect(t *testing.T) {
+	tests := []struct {
+		name             string
+		version          version
+		replicaOf        string
+		sentinelReplicas string
+		configs          map[string]string
+	}{
+		{
+			name:             "redis 4",
+			version:          version{4, 0, 14},
+			replicaOf:        "SLAVEOF",
+			sentinelReplicas: "SLAVES",
+			configs: map[string]string{
+				"slave-priority":        "slave-priority",
+				"replica-priority":      "slave-priority",
+				"min-replicas-max-lag":  "min-slaves-max-lag",
+				"maxmemory":             "maxmemory",
+				"replica-announce-port": "slave-announce-port",
+			},
+		},
+		{
+			name:             "redis 5",
+			version:          version{5, 0, 4},
+			replicaOf:        "REPLICAOF",
+			sentinelReplicas: "REPLICAS",
+			configs: map[string]string{
+				"slave-priority":   "replica-priority",
+				"replica-priority": "replica-priority",
+				"slave-read-only":  "replica-read-only",
+				"maxmemory":        "maxmemory",
+			},
+		},
+		{
+			name:             "redis 7",
+			version:          version{7, 2, 4},
+			replicaOf:        "REPLICAOF",
+			sentinelReplicas: "REPLICAS",
+			configs: map[string]string{
+				"slave-priority":      "replica-priority",
+				"min-slaves-to-write": "min-replicas-to-write",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &dialect{version: tt.version}
+			if got := d.replicaOf(); got != tt.replicaOf {
+				t.Errorf("replicaOf() = %v, want %v", got, tt.replicaOf)
+			}
+			if got := d.sentinelReplicas(); got != tt.sentinelReplicas {
+				t.Errorf("sentinelReplicas() = %v, want %v", got, tt.sentinelReplicas)
+			}
+			for name, want := range tt.configs {
+				if got := d.configName(name); got != want {
+					t.Errorf("configName(%s) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigAlias(t *testing.T) {
+	tests := map[string]string{
+		"slave-priority":   "replica-priority",
+		"replica-priority": "slave-priority",
+		"maxmemory":        "maxmemory",
+	}
+	for name, want := range tests {
+		if got := ConfigAlias(name); got != want {
+			t.Errorf("ConfigAlias(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestIsRestartConfig(t *testing.T) {
+	configs := map[string]bool{
+		"maxmemory":       false,
+		"databases":       true,
+		"io-threads":      true,
+		"cluster-enabled": true,
+		// set by the operator, refused by the validation
+		"port": false,
+		"bind": false,
+	}
+	for name, want := range configs {
+		if got := IsRestartConfig(name); got != want {
+			t.Errorf("IsRestartConfig(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestIsSentinelRuntimeConfig(t *testing.T) {
+	configs := map[string]bool{
+		"down-after-milliseconds": true,
+		"failover-timeout":        true,
+		"auth-pass":               true,
+		"announce-ip":             false,
+		"resolve-hostnames":       false,
+	}
+	for name, want := range configs {
+		if got := IsSentinelRuntimeConfig(name); got != want {
+			t.Errorf("IsSentinelRuntimeConfig(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// redis7Info is the INFO of a redis 7.2 replica, trimmed to the sections read by the operator
+const redis7Info = "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n\r\n" +
+	"# Persistence\r\nloading:0\r\nasync_loading:0\r\nrdb_changes_since_last_save:0\r\nrdb_bgsave_in_progress:0\r\n" +
+	"rdb_last_save_time:1717236000\r\nrdb_last_bgsave_status:ok\r\naof_enabled:1\r\n\r\n" +
+	"# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6379\r\nmaster_link_status:up\r\n" +
+	"master_last_io_seconds_ago:1\r\nmaster_sync_in_progress:0\r\nslave_read_repl_offset:4242\r\nslave_repl_offset:4242\r\n" +
+	"slave_priority:100\r\nslave_read_only:1\r\nreplica_announced:1\r\nconnected_slaves:0\r\n" +
+	"master_failover_state:no-failover\r\nmaster_repl_offset:4242\r\nsecond_repl_offset:-1\r\n\r\n" +
+	"# Errorstats\r\nerrorstat_ERR:count=2\r\nerrorstat_WRONGTYPE:count=1\r\n\r\n" +
+	"# Keyspace\r\ndb0:keys=10,expires=0,avg_ttl=0\r\ndb1:keys=5,expires=1,avg_ttl=100\r\n"
+
+// redis7SentinelInfo is the INFO sentinel of a redis 7.2 sentinel
+const redis7SentinelInfo = "# Sentinel\r\nsentinel_masters:1\r\nsentinel_tilt:0\r\nsentinel_tilt_since_seconds:-1\r\n" +
+	"sentinel_running_scripts:0\r\nsentinel_scripts_queue_length:0\r\nsentinel_simulate_failure_flags:0\r\n" +
+	"master0:name=mymaster,status=ok,address=10.0.0.1:6379,slaves=2,sentinels=3\r\n"
+
+func Test_redis7Info(t *testing.T) {
+	d, err := newDialect(redis7Info)
+	if err != nil || !d.hasReplicaNames() {
+		t.Fatalf("newDialect() = %v, %v, want a dialect with the replica names", d, err)
+	}
+	if got := getMasterAddr(redis7Info); got != "10.0.0.1:6379" {
+		t.Errorf("getMasterAddr() = %v, want 10.0.0.1:6379", got)
+	}
+	if !isReplicaSynced(redis7Info) {
+		t.Errorf("isReplicaSynced() = false, want true")
+	}
+	if got, err := parseReplicationOffset(redis7Info); err != nil || got != 4242 {
+		t.Errorf("parseReplicationOffset() = %v, %v, want 4242", got, err)
+	}
+	if got, err := parseSaveStatus(redis7Info); err != nil || got.InProgress || got.LastSaveTime != 1717236000 || !got.LastSaveOK {
+		t.Errorf("parseSaveStatus() = %+v, %v, want a finished save at 1717236000", got, err)
+	}
+	if loading, _, err := parseLoadingStatus(redis7Info); err != nil || loading {
+		t.Errorf("parseLoadingStatus() = %v, %v, want not loading", loading, err)
+	}
+	if got, err := sumErrorStats(redis7Info); err != nil || got != 3 {
+		t.Errorf("sumErrorStats() = %v, %v, want 3", got, err)
+	}
+	if got, err := parseKeyCount(redis7Info); err != nil || got != 15 {
+		t.Errorf("parseKeyCount() = %v, %v, want 15", got, err)
+	}
+
+	if err := isSentinelReady(redis7SentinelInfo); err != nil {
+		t.Errorf("isSentinelReady() = %v, want nil", err)
+	}
+	if match := sentinelNumberRE.FindStringSubmatch(redis7SentinelInfo); len(match) == 0 || match[1] != "3" {
+		t.Errorf("sentinels = %v, want 3", match)
+	}
+	replica := []interface{}{"name", "10.0.0.2:6379", "flags", "slave", "slave-priority", "0", "replica-announced", "1"}
+	if got := slaveInfoFieldByName("slave-priority", replica); got != "0" {
+		t.Errorf("slaveInfoFieldByName(slave-priority) = %v, want 0", got)
+	}
+}
@@ -48,6 +48,24 @@ func newCluster(rc *redisv1beta1.RedisCluster) *Meta {
 	}
 }
 
+// restoreCluster rebuilds the meta of a RedisCluster from the spec recorded in its status,
+// when the cluster was applied before the operator (re)started
+func restoreCluster(rc *redisv1beta1.RedisCluster) *Meta {
+	old := rc.DeepCopy()
+	old.Spec = *rc.Status.LastAppliedSpec.DeepCopy()
+	// only the hash of the password is recorded, the validation checked the password of the spec against it.
+	// The status written by the older versions still has the password.
+	if old.Spec.Password == "" {
+		old.Spec.Password = rc.Spec.Password
+	}
+	old.Generation = rc.Status.ObservedGeneration
+	meta := newCluster(old)
+	meta.State = Check
+	meta.Status = redisv1beta1.ClusterConditionHealthy
+	meta.Message = "Restored from last applied spec"
+	return meta
+}
+
 // MetaMap cache last RedisCluster and meta data
 type MetaMap struct {
 	sync.Map
@@ -55,7 +73,11 @@ type MetaMap struct {
 
 func (c *MetaMap) Cache(obj *redisv1beta1.RedisCluster) *Meta {
 	meta, ok := c.Load(getNamespacedName(obj.GetNamespace(), obj.GetName()))
-	if !ok {
+	if !ok && obj.Status.LastAppliedSpec != nil {
+		restored := restoreCluster(obj)
+		c.Store(getNamespacedName(obj.GetNamespace(), obj.GetName()), restored)
+		c.Update(restored, obj)
+	} else if !ok {
 		c.Add(obj)
 	} else {
 		c.Update(meta.(*Meta), obj)
@@ -79,6 +101,9 @@ func (c *MetaMap) Del(obj *redisv1beta1.RedisCluster) {
 func (c *MetaMap) Update(meta *Meta, new *redisv1beta1.RedisCluster) {
 	if meta.Obj.GetGeneration() == new.GetGeneration() {
 		meta.State = Check
+		// keep the latest object, for its metadata and status
+		new.Spec.Password = meta.Obj.Spec.Password
+		meta.Obj = new
 		return
 	}
 
@@ -106,11 +131,15 @@ func (c *MetaMap) Update(meta *Meta, new *redisv1beta1.RedisCluster) {
 	}
 	if isResourcesChange(old, new) {
 		meta.Message = "Updating compute resources"
+		// the maxmemory derived from the memory limit follows it
+		if maxMemory := new.Spec.Config["maxmemory"]; maxMemory != old.Spec.Config["maxmemory"] {
+			meta.Message = fmt.Sprintf("Updating compute resources, maxmemory %s", maxMemory)
+		}
 	}
 }
 
 func isImagesChanged(old, new *redisv1beta1.RedisCluster) bool {
-	return old.Spec.Image == new.Spec.Image
+	return old.Spec.Image != new.Spec.Image
 }
 
 func isScalingDown(old, new *redisv1beta1.RedisCluster) bool {
@@ -122,8 +151,8 @@ func isScalingUp(old, new *redisv1beta1.RedisCluster) bool {
 }
 
 func isResourcesChange(old, new *redisv1beta1.RedisCluster) bool {
-	return old.Spec.Resources.Limits.Memory().Size() != new.Spec.Resources.Limits.Memory().Size() ||
-		old.Spec.Resources.Limits.Cpu().Size() != new.Spec.Resources.Limits.Cpu().Size()
+	return old.Spec.Resources.Limits.Memory().Cmp(*new.Spec.Resources.Limits.Memory()) != 0 ||
+		old.Spec.Resources.Limits.Cpu().Cmp(*new.Spec.Resources.Limits.Cpu()) != 0
 }
 
 func getNamespacedName(nameSpace, name string) string {
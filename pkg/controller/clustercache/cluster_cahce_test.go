@@ -4,6 +4,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
@@ -51,7 +53,7 @@ func TestCache(t *testing.T) {
 		},
 	}
 
-	meta := make(MetaMap)
+	meta := new(MetaMap)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			rcMeta1 := meta.Cache(test.rc1)
@@ -112,7 +114,7 @@ func TestCachePasswd(t *testing.T) {
 		},
 	}
 
-	meta := make(MetaMap)
+	meta := new(MetaMap)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			rcMeta1 := meta.Cache(test.rc1)
@@ -130,3 +132,166 @@ func TestCachePasswd(t *testing.T) {
 		})
 	}
 }
+
+func TestCacheRestore(t *testing.T) {
+	tests := []struct {
+		name        string
+		rc          *redisv1beta1.RedisCluster
+		wantState   StateType
+		wantStatus  redisv1beta1.ConditionType
+		wantMessage string
+	}{
+		{
+			name: "unchanged",
+			rc: &redisv1beta1.RedisCluster{
+				ObjectMeta: v1.ObjectMeta{
+					Name:       "test1",
+					Namespace:  "prj-restore",
+					Generation: 2,
+				},
+				Spec: redisv1beta1.RedisClusterSpec{
+					Size:  3,
+					Image: "redis:5.0.4-alpine",
+				},
+				Status: redisv1beta1.RedisClusterStatus{
+					ObservedGeneration: 2,
+					LastAppliedSpec: &redisv1beta1.RedisClusterSpec{
+						Size:  3,
+						Image: "redis:5.0.4-alpine",
+					},
+				},
+			},
+			wantState:  Check,
+			wantStatus: redisv1beta1.ClusterConditionHealthy,
+		},
+		{
+			name: "scaled while operator was down",
+			rc: &redisv1beta1.RedisCluster{
+				ObjectMeta: v1.ObjectMeta{
+					Name:       "test2",
+					Namespace:  "prj-restore",
+					Generation: 3,
+				},
+				Spec: redisv1beta1.RedisClusterSpec{
+					Size:  5,
+					Image: "redis:5.0.4-alpine",
+				},
+				Status: redisv1beta1.RedisClusterStatus{
+					ObservedGeneration: 2,
+					LastAppliedSpec: &redisv1beta1.RedisClusterSpec{
+						Size:  3,
+						Image: "redis:5.0.4-alpine",
+					},
+				},
+			},
+			wantState:   Update,
+			wantStatus:  redisv1beta1.ClusterConditionScaling,
+			wantMessage: "Scaling up form: 3 to: 5",
+		},
+		{
+			name: "upgraded while operator was down",
+			rc: &redisv1beta1.RedisCluster{
+				ObjectMeta: v1.ObjectMeta{
+					Name:       "test3",
+					Namespace:  "prj-restore",
+					Generation: 3,
+				},
+				Spec: redisv1beta1.RedisClusterSpec{
+					Size:  3,
+					Image: "redis:5.0.5-alpine",
+				},
+				Status: redisv1beta1.RedisClusterStatus{
+					ObservedGeneration: 2,
+					LastAppliedSpec: &redisv1beta1.RedisClusterSpec{
+						Size:  3,
+						Image: "redis:5.0.4-alpine",
+					},
+				},
+			},
+			wantState:   Update,
+			wantStatus:  redisv1beta1.ClusterConditionUpgrading,
+			wantMessage: "Upgrading to redis:5.0.5-alpine",
+		},
+		{
+			name: "never applied",
+			rc: &redisv1beta1.RedisCluster{
+				ObjectMeta: v1.ObjectMeta{
+					Name:       "test4",
+					Namespace:  "prj-restore",
+					Generation: 1,
+				},
+				Spec: redisv1beta1.RedisClusterSpec{
+					Size: 3,
+				},
+			},
+			wantState:   Create,
+			wantStatus:  redisv1beta1.ClusterConditionCreating,
+			wantMessage: "Bootstrap redis cluster",
+		},
+	}
+
+	meta := new(MetaMap)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rcMeta := meta.Cache(test.rc)
+			assert.EqualValues(t, test.wantState, rcMeta.State)
+			assert.EqualValues(t, test.wantStatus, rcMeta.Status)
+			if test.wantMessage != "" {
+				assert.EqualValues(t, test.wantMessage, rcMeta.Message)
+			}
+		})
+	}
+}
+
+func TestCacheRestorePassword(t *testing.T) {
+	rc := &redisv1beta1.RedisCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "test1",
+			Namespace:  "prj-password",
+			Generation: 2,
+		},
+		Spec: redisv1beta1.RedisClusterSpec{
+			Size:     3,
+			Password: "secret",
+		},
+		Status: redisv1beta1.RedisClusterStatus{ObservedGeneration: 2},
+	}
+	rc.Status.SetLastAppliedSpec(&rc.Spec)
+	assert.Empty(t, rc.Status.LastAppliedSpec.Password)
+	assert.False(t, rc.IsPasswordChanged())
+
+	rcMeta := new(MetaMap).Cache(rc)
+	assert.EqualValues(t, Check, rcMeta.State)
+	assert.EqualValues(t, "secret", rcMeta.Auth.Password)
+	assert.EqualValues(t, "secret", rcMeta.Obj.Spec.Password)
+
+	changed := rc.DeepCopy()
+	changed.Spec.Password = "other"
+	assert.True(t, changed.IsPasswordChanged())
+}
+
+func TestCacheResources(t *testing.T) {
+	rc := func(generation int64, limit, maxMemory string) *redisv1beta1.RedisCluster {
+		return &redisv1beta1.RedisCluster{
+			ObjectMeta: v1.ObjectMeta{
+				Name:       "test1",
+				Namespace:  "prj-mem",
+				Generation: generation,
+			},
+			Spec: redisv1beta1.RedisClusterSpec{
+				Size: 3,
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(limit)},
+				},
+				Config: map[string]string{"maxmemory": maxMemory},
+			},
+		}
+	}
+
+	meta := new(MetaMap)
+	meta.Cache(rc(1, "1Gi", "536870912"))
+	rcMeta := meta.Cache(rc(2, "1024Mi", "536870912"))
+	assert.EqualValues(t, "Updating redis config", rcMeta.Message)
+	rcMeta = meta.Cache(rc(3, "2Gi", "1073741824"))
+	assert.EqualValues(t, "Updating compute resources, maxmemory 1073741824", rcMeta.Message)
+}
@@ -0,0 +1,298 @@
+package redisbackup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/controller/service"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+var needRequeueErr = errors.New("need requeue")
+
+// RedisBackupHandler takes a snapshot of a RedisCluster replica and uploads it.
+type RedisBackupHandler struct {
+	k8sServices k8s.Services
+	redisClient redis.Client
+	rcChecker   service.RedisClusterCheck
+	logger      logr.Logger
+}
+
+// uploadResult is written by the upload job in its termination message
+type uploadResult struct {
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// Do moves the backup to its next phase:
+// Pending: a synced replica is chosen and BGSAVE is started on it
+// Snapshotting: wait for the background save to finish, then start the upload job
+// Uploading: wait for the job and record the size and the checksum of the snapshot
+func (r *RedisBackupHandler) Do(b *redisv1beta1.RedisBackup) error {
+	if err := b.Validate(); err != nil {
+		return r.fail(b, err.Error())
+	}
+	rc, err := r.k8sServices.GetCluster(b.Namespace, b.Spec.ClusterName)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return r.fail(b, fmt.Sprintf("redis cluster %s not found", b.Spec.ClusterName))
+		}
+		return err
+	}
+	if err := rc.Validate(); err != nil {
+		return r.fail(b, err.Error())
+	}
+
+	switch b.Status.Phase {
+	case "", redisv1beta1.BackupPhasePending:
+		return r.snapshot(b, rc)
+	case redisv1beta1.BackupPhaseSnapshot:
+		return r.waitSnapshot(b, rc)
+	case redisv1beta1.BackupPhaseUploading:
+		return r.waitUpload(b)
+	}
+	return nil
+}
+
+// snapshot starts a background save on a replica that is synced with its master,
+// the master is never used so the clients do not pay for the fork
+func (r *RedisBackupHandler) snapshot(b *redisv1beta1.RedisBackup, rc *redisv1beta1.RedisCluster) error {
+	if rc.Spec.Storage.PersistentVolumeClaim == nil {
+		return r.fail(b, "backup needs the persistent storage of the redis cluster")
+	}
+	auth := &util.AuthConfig{Password: rc.Spec.Password}
+	pod, err := r.getSourcePod(rc, auth)
+	if err != nil {
+		return err
+	}
+	if pod == nil {
+		return r.setPending(b, "no replica synced with its master")
+	}
+
+	offset, err := r.redisClient.GetReplicationOffset(pod.Status.PodIP, auth)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := r.redisClient.BgSave(pod.Status.PodIP, auth); err != nil {
+		return err
+	}
+	r.logger.WithValues("namespace", b.Namespace, "name", b.Name).Info(fmt.Sprintf("snapshot started on %s", pod.Name))
+
+	b.Status.Phase = redisv1beta1.BackupPhaseSnapshot
+	b.Status.Message = ""
+	b.Status.SourcePod = pod.Name
+	b.Status.SourceOffset = offset
+	b.Status.StartTime = start.Format(time.RFC3339)
+	if err := r.k8sServices.UpdateBackupStatus(b.Namespace, b); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// getSourcePod returns a ready replica synced with its master, or nil if there is none
+func (r *RedisBackupHandler) getSourcePod(rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) (*corev1.Pod, error) {
+	pods, err := r.k8sServices.GetStatefulSetPods(rc.Namespace, util.GetRedisName(rc))
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP == "" || !util.IsPodReady(&pod) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		// active replicas are all labeled as master and replicate from each other
+		if pod.Labels[redisv1beta1.LabelRoleKey] != redisv1beta1.RoleReplica && !rc.IsActiveReplica() {
+			continue
+		}
+		if err := r.rcChecker.CheckReplicaSynced(pod.Status.PodIP, auth); err != nil {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("replica %s: %s", pod.Name, err))
+			continue
+		}
+		pod := pod
+		return &pod, nil
+	}
+	return nil, nil
+}
+
+// waitSnapshot waits for the background save started after the backup, then uploads it
+func (r *RedisBackupHandler) waitSnapshot(b *redisv1beta1.RedisBackup, rc *redisv1beta1.RedisCluster) error {
+	pod, err := r.k8sServices.GetPod(b.Namespace, b.Status.SourcePod)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return r.fail(b, fmt.Sprintf("source pod %s deleted during the snapshot", b.Status.SourcePod))
+		}
+		return err
+	}
+	if !util.IsPodReady(pod) {
+		return r.fail(b, fmt.Sprintf("source pod %s is not ready", pod.Name))
+	}
+	start, err := time.Parse(time.RFC3339, b.Status.StartTime)
+	if err != nil {
+		return r.fail(b, fmt.Sprintf("invalid start time: %s", err))
+	}
+
+	auth := &util.AuthConfig{Password: rc.Spec.Password}
+	status, err := r.redisClient.GetSaveStatus(pod.Status.PodIP, auth)
+	if err != nil {
+		return err
+	}
+	if status.InProgress {
+		return needRequeueErr
+	}
+	if !status.LastSaveOK {
+		return r.fail(b, fmt.Sprintf("background save failed on %s", pod.Name))
+	}
+	if status.LastSaveTime < start.Unix() {
+		return r.fail(b, fmt.Sprintf("background save did not run on %s", pod.Name))
+	}
+
+	key := getObjectKey(b)
+	if err := r.k8sServices.CreateJob(b.Namespace, generateUploadJob(b, rc, pod, key)); err != nil && !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	b.Status.Phase = redisv1beta1.BackupPhaseUploading
+	b.Status.SnapshotTime = time.Unix(status.LastSaveTime, 0).Format(time.RFC3339)
+	b.Status.Location = fmt.Sprintf("s3://%s/%s", b.Spec.Storage.S3.Bucket, key)
+	if err := r.k8sServices.UpdateBackupStatus(b.Namespace, b); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// waitUpload waits for the upload job and reads its result from the termination message
+func (r *RedisBackupHandler) waitUpload(b *redisv1beta1.RedisBackup) error {
+	job, err := r.k8sServices.GetJob(b.Namespace, util.GetBackupJobName(b))
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return r.fail(b, "upload job deleted")
+		}
+		return err
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return r.fail(b, fmt.Sprintf("upload failed: %s", c.Message))
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		return needRequeueErr
+	}
+
+	pods, err := r.k8sServices.GetJobPods(b.Namespace, job.Name)
+	if err != nil {
+		return err
+	}
+	result, err := getUploadResult(pods.Items)
+	if err != nil {
+		return r.fail(b, err.Error())
+	}
+	r.logger.WithValues("namespace", b.Namespace, "name", b.Name).Info(fmt.Sprintf("backup uploaded to %s", b.Status.Location))
+	b.Status.Phase = redisv1beta1.BackupPhaseCompleted
+	b.Status.Message = ""
+	b.Status.Size = result.Size
+	b.Status.Checksum = result.Checksum
+	b.Status.CompletionTime = time.Now().Format(time.RFC3339)
+	return r.k8sServices.UpdateBackupStatus(b.Namespace, b)
+}
+
+// Cleanup deletes the snapshot from the bucket, then removes the finalizer so the backup is deleted
+func (r *RedisBackupHandler) Cleanup(b *redisv1beta1.RedisBackup) error {
+	if !util.ContainsString(b.Finalizers, redisv1beta1.BackupCleanupFinalizer) {
+		return nil
+	}
+	// nothing was uploaded, or the backup is invalid and nothing can be deleted
+	if b.Status.Location == "" || b.Validate() != nil {
+		return r.removeFinalizer(b)
+	}
+	// the upload must not race with the deletion of the object
+	if b.Status.Phase == redisv1beta1.BackupPhaseUploading {
+		if err := r.k8sServices.DeleteJob(b.Namespace, util.GetBackupJobName(b)); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	job, err := r.k8sServices.GetJob(b.Namespace, util.GetBackupCleanupJobName(b))
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		if err := r.k8sServices.CreateJob(b.Namespace, generateCleanupJob(b, getObjectKey(b))); err != nil && !kerrors.IsAlreadyExists(err) {
+			return err
+		}
+		return needRequeueErr
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			// the finalizer is kept, it can be removed by hand once the snapshot is deleted
+			message := fmt.Sprintf("failed to delete %s: %s", b.Status.Location, c.Message)
+			if b.Status.Message == message {
+				return nil
+			}
+			r.logger.WithValues("namespace", b.Namespace, "name", b.Name).Info(message)
+			b.Status.Message = message
+			return r.k8sServices.UpdateBackupStatus(b.Namespace, b)
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		return needRequeueErr
+	}
+	r.logger.WithValues("namespace", b.Namespace, "name", b.Name).Info(fmt.Sprintf("snapshot %s deleted", b.Status.Location))
+	return r.removeFinalizer(b)
+}
+
+func (r *RedisBackupHandler) removeFinalizer(b *redisv1beta1.RedisBackup) error {
+	b.Finalizers = util.RemoveString(b.Finalizers, redisv1beta1.BackupCleanupFinalizer)
+	return r.k8sServices.UpdateBackup(b.Namespace, b)
+}
+
+func getUploadResult(pods []corev1.Pod) (*uploadResult, error) {
+	for _, pod := range pods {
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != uploadContainerName || cs.State.Terminated == nil || cs.State.Terminated.ExitCode != 0 {
+				continue
+			}
+			result := &uploadResult{}
+			if err := json.Unmarshal([]byte(cs.State.Terminated.Message), result); err != nil {
+				return nil, fmt.Errorf("invalid upload result %q: %s", cs.State.Terminated.Message, err)
+			}
+			return result, nil
+		}
+	}
+	return nil, errors.New("upload result not found")
+}
+
+// getObjectKey returns the name of the snapshot in the bucket
+func getObjectKey(b *redisv1beta1.RedisBackup) string {
+	return path.Join(b.Spec.Storage.S3.Prefix, b.Namespace, b.Name, "dump.rdb")
+}
+
+func (r *RedisBackupHandler) setPending(b *redisv1beta1.RedisBackup, message string) error {
+	r.logger.WithValues("namespace", b.Namespace, "name", b.Name).Info(message)
+	if b.Status.Phase != redisv1beta1.BackupPhasePending || b.Status.Message != message {
+		b.Status.Phase = redisv1beta1.BackupPhasePending
+		b.Status.Message = message
+		if err := r.k8sServices.UpdateBackupStatus(b.Namespace, b); err != nil {
+			return err
+		}
+	}
+	return needRequeueErr
+}
+
+// fail records the failure, a failed backup is not retried
+func (r *RedisBackupHandler) fail(b *redisv1beta1.RedisBackup, message string) error {
+	r.logger.WithValues("namespace", b.Namespace, "name", b.Name).Info(fmt.Sprintf("backup failed: %s", message))
+	b.Status.Phase = redisv1beta1.BackupPhaseFailed
+	b.Status.Message = message
+	b.Status.CompletionTime = time.Now().Format(time.RFC3339)
+	return r.k8sServices.UpdateBackupStatus(b.Namespace, b)
+}
@@ -0,0 +1,97 @@
+package redisbackup
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+)
+
+func terminatedPod(container string, exitCode int32, message string) corev1.Pod {
+	return corev1.Pod{
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: container,
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Message: message},
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_getUploadResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		want    *uploadResult
+		wantErr bool
+	}{
+		{
+			name: "succeeded after a retry",
+			pods: []corev1.Pod{
+				terminatedPod(uploadContainerName, 1, ""),
+				terminatedPod(uploadContainerName, 0, `{"size":1024,"checksum":"abc"}`),
+			},
+			want: &uploadResult{Size: 1024, Checksum: "abc"},
+		},
+		{
+			name:    "invalid message",
+			pods:    []corev1.Pod{terminatedPod(uploadContainerName, 0, "done")},
+			wantErr: true,
+		},
+		{
+			name:    "no pod succeeded",
+			pods:    []corev1.Pod{terminatedPod(uploadContainerName, 1, "")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getUploadResult(tt.pods)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getUploadResult() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getUploadResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getObjectKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{
+			name: "no prefix",
+			want: "default/nightly/dump.rdb",
+		},
+		{
+			name:   "prefix",
+			prefix: "redis/",
+			want:   "redis/default/nightly/dump.rdb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &redisv1beta1.RedisBackup{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly"},
+				Spec: redisv1beta1.RedisBackupSpec{
+					Storage: redisv1beta1.BackupStorage{S3: &redisv1beta1.S3Storage{Prefix: tt.prefix}},
+				},
+			}
+			if got := getObjectKey(b); got != tt.want {
+				t.Errorf("getObjectKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
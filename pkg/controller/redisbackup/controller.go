@@ -0,0 +1,131 @@
+package redisbackup
+
+import (
+	"context"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/controller/service"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const requeueTime = 10 * time.Second
+
+var log = logf.Log.WithName("controller_redisbackup")
+
+// Add creates a new RedisBackup Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	k8sService := k8s.New(mgr.GetClient(), log)
+	redisClient := redis.New()
+	handler := &RedisBackupHandler{
+		k8sServices: k8sService,
+		redisClient: redisClient,
+		rcChecker:   service.NewRedisClusterChecker(k8sService, redisClient, log),
+		logger:      log,
+	}
+	return &ReconcileRedisBackup{client: mgr.GetClient(), scheme: mgr.GetScheme(), handler: handler}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("redisbackup-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	pred := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// Ignore updates to CR status in which case metadata.Generation does not change,
+			// unless the backup is being deleted and its snapshot must be cleaned up
+			if !util.ShouldManage(e.MetaNew) {
+				return false
+			}
+			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() || e.MetaNew.GetDeletionTimestamp() != nil
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return util.ShouldManage(e.Meta)
+		},
+	}
+
+	// Watch for changes to primary resource RedisBackup
+	err = c.Watch(&source.Kind{Type: &redisv1beta1.RedisBackup{}}, &handler.EnqueueRequestForObject{}, pred)
+	if err != nil {
+		return err
+	}
+
+	// Watch the upload and cleanup jobs, so the backup moves on as soon as its job finishes
+	return c.Watch(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &redisv1beta1.RedisBackup{},
+	})
+}
+
+var _ reconcile.Reconciler = &ReconcileRedisBackup{}
+
+// ReconcileRedisBackup reconciles a RedisBackup object
+type ReconcileRedisBackup struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client  client.Client
+	scheme  *runtime.Scheme
+	handler *RedisBackupHandler
+}
+
+// Reconcile moves the RedisBackup to its next phase, a finished backup is only reconciled again
+// when it is deleted, to delete its snapshot if it has the cleanup finalizer.
+func (r *ReconcileRedisBackup) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.V(3).Info("Reconciling RedisBackup")
+
+	instance := &redisv1beta1.RedisBackup{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Owned objects are automatically garbage collected.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !util.ShouldManage(instance) {
+		return reconcile.Result{}, nil
+	}
+
+	if instance.DeletionTimestamp != nil {
+		err = r.handler.Cleanup(instance)
+	} else if !instance.IsFinished() {
+		err = r.handler.Do(instance)
+	}
+	if err != nil {
+		if err == needRequeueErr {
+			return reconcile.Result{RequeueAfter: requeueTime}, nil
+		}
+		reqLogger.Error(err, "Reconcile handler")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,169 @@
+package redisbackup
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const (
+	uploadContainerName = "upload"
+	defaultRdbFileName  = "dump.rdb"
+	uploadBackoffLimit  = int32(2)
+
+	// the snapshot is copied first, so the checksum and the upload see the same file
+	// even if redis replaces it with a newer one
+	uploadScript = `set -e
+cp "/data/$RDB_FILE" /backup/dump.rdb
+size=$(stat -c %s /backup/dump.rdb)
+checksum=$(sha256sum /backup/dump.rdb | cut -d' ' -f1)
+mc alias set backup "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY"
+mc cp /backup/dump.rdb "backup/$S3_BUCKET/$S3_KEY"
+printf '{"size":%s,"checksum":"%s"}' "$size" "$checksum" > /dev/termination-log
+`
+
+	cleanupContainerName = "cleanup"
+	// a snapshot already deleted from the bucket is not an error
+	cleanupScript = `set -e
+mc alias set backup "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY"
+if mc stat "backup/$S3_BUCKET/$S3_KEY" > /dev/null 2>&1; then
+  mc rm "backup/$S3_BUCKET/$S3_KEY"
+fi
+`
+)
+
+// generateUploadJob returns the job copying the snapshot of the source pod to the bucket. It runs on the
+// node of the source pod, the data volume of a statefulset can only be mounted there.
+func generateUploadJob(b *redisv1beta1.RedisBackup, rc *redisv1beta1.RedisCluster, pod *corev1.Pod, key string) *batchv1.Job {
+	s3 := b.Spec.Storage.S3
+	labels := getJobLabels(b)
+	rdbFile := rc.Spec.Config["dbfilename"]
+	if rdbFile == "" {
+		rdbFile = defaultRdbFileName
+	}
+	backoffLimit := uploadBackoffLimit
+	claimName := fmt.Sprintf("%s-%s", rc.Spec.Storage.PersistentVolumeClaim.Name, pod.Name)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.GetBackupJobName(b),
+			Namespace: b.Namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(b, redisv1beta1.VersionKind(redisv1beta1.BackupKind)),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					NodeName:         pod.Spec.NodeName,
+					Tolerations:      rc.Spec.ToleRations,
+					SecurityContext:  rc.Spec.SecurityContext,
+					ImagePullSecrets: rc.Spec.ImagePullSecrets,
+					Containers: []corev1.Container{
+						{
+							Name:            uploadContainerName,
+							Image:           b.Spec.Image,
+							ImagePullPolicy: b.Spec.ImagePullPolicy,
+							Command:         []string{"sh", "-c", uploadScript},
+							Env: append([]corev1.EnvVar{
+								{Name: "RDB_FILE", Value: rdbFile},
+							}, s3Env(s3, key)...),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "data",
+									MountPath: "/data",
+									ReadOnly:  true,
+								},
+								{
+									Name:      "backup",
+									MountPath: "/backup",
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "data",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: claimName,
+									ReadOnly:  true,
+								},
+							},
+						},
+						{
+							Name: "backup",
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// generateCleanupJob returns the job deleting the snapshot of the backup from the bucket
+func generateCleanupJob(b *redisv1beta1.RedisBackup, key string) *batchv1.Job {
+	labels := getJobLabels(b)
+	backoffLimit := uploadBackoffLimit
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.GetBackupCleanupJobName(b),
+			Namespace: b.Namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(b, redisv1beta1.VersionKind(redisv1beta1.BackupKind)),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:            cleanupContainerName,
+							Image:           b.Spec.Image,
+							ImagePullPolicy: b.Spec.ImagePullPolicy,
+							Command:         []string{"sh", "-c", cleanupScript},
+							Env:             s3Env(b.Spec.Storage.S3, key),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getJobLabels(b *redisv1beta1.RedisBackup) map[string]string {
+	return map[string]string{
+		redisv1beta1.LabelManagedByKey: redisv1beta1.OperatorName,
+		redisv1beta1.LabelNameKey:      fmt.Sprintf("%s%c%s", b.Namespace, '_', b.Name),
+	}
+}
+
+// s3Env returns the location of the object and the credentials for mc
+func s3Env(s3 *redisv1beta1.S3Storage, key string) []corev1.EnvVar {
+	return append([]corev1.EnvVar{
+		{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+		{Name: "S3_BUCKET", Value: s3.Bucket},
+		{Name: "S3_KEY", Value: key},
+	}, util.S3CredentialsEnv(s3.CredentialsSecret)...)
+}
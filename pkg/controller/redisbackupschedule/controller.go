@@ -0,0 +1,127 @@
+package redisbackupschedule
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/metrics"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+var log = logf.Log.WithName("controller_redisbackupschedule")
+
+// Add creates a new RedisBackupSchedule Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	handler := &RedisBackupScheduleHandler{
+		k8sServices: k8s.New(mgr.GetClient(), log),
+		metrics:     metrics.ClusterMetrics,
+		logger:      log,
+	}
+	return &ReconcileRedisBackupSchedule{client: mgr.GetClient(), scheme: mgr.GetScheme(), handler: handler}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("redisbackupschedule-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	pred := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// Ignore updates to CR status in which case metadata.Generation does not change
+			return util.ShouldManage(e.MetaNew) && e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration()
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			if util.ShouldManage(e.Meta) {
+				metrics.ClusterMetrics.DeleteBackupSchedule(e.Meta.GetNamespace(), e.Meta.GetName())
+			}
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return util.ShouldManage(e.Meta)
+		},
+	}
+
+	// Watch for changes to primary resource RedisBackupSchedule
+	err = c.Watch(&source.Kind{Type: &redisv1beta1.RedisBackupSchedule{}}, &handler.EnqueueRequestForObject{}, pred)
+	if err != nil {
+		return err
+	}
+
+	// Watch the backups created by the schedules, they are not owned by the schedule so they
+	// survive its deletion, the label links them instead
+	return c.Watch(&source.Kind{Type: &redisv1beta1.RedisBackup{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			name, ok := o.Meta.GetLabels()[redisv1beta1.LabelBackupScheduleKey]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: o.Meta.GetNamespace(), Name: name}}}
+		}),
+	})
+}
+
+var _ reconcile.Reconciler = &ReconcileRedisBackupSchedule{}
+
+// ReconcileRedisBackupSchedule reconciles a RedisBackupSchedule object
+type ReconcileRedisBackupSchedule struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client  client.Client
+	scheme  *runtime.Scheme
+	handler *RedisBackupScheduleHandler
+}
+
+// Reconcile applies the retention of the RedisBackupSchedule and creates its backups when they are due,
+// it is requeued for the next run.
+func (r *ReconcileRedisBackupSchedule) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.V(3).Info("Reconciling RedisBackupSchedule")
+
+	instance := &redisv1beta1.RedisBackupSchedule{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The backups are kept, the retention is not applied anymore.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !util.ShouldManage(instance) {
+		return reconcile.Result{}, nil
+	}
+
+	next, err := r.handler.Do(instance)
+	if err != nil {
+		reqLogger.Error(err, "Reconcile handler")
+		return reconcile.Result{}, err
+	}
+	if next.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	// wake up a bit after the due time, the cron has a minute precision
+	return reconcile.Result{RequeueAfter: time.Until(next) + time.Second}, nil
+}
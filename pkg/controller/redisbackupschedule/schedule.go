@@ -0,0 +1,244 @@
+package redisbackupschedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/metrics"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// RedisBackupScheduleHandler creates the RedisBackups of a schedule and deletes the expired ones.
+type RedisBackupScheduleHandler struct {
+	k8sServices k8s.Services
+	metrics     metrics.Instrumenter
+	logger      logr.Logger
+}
+
+// Do applies the retention, then creates the backup if one is due. It returns the time of the next run,
+// or the zero time if the schedule is invalid.
+func (r *RedisBackupScheduleHandler) Do(s *redisv1beta1.RedisBackupSchedule) (time.Time, error) {
+	if err := s.Validate(); err != nil {
+		return time.Time{}, r.setMessage(s, err.Error())
+	}
+	cron, err := util.ParseCron(s.Spec.Schedule)
+	if err != nil {
+		return time.Time{}, r.setMessage(s, err.Error())
+	}
+
+	backups, err := r.k8sServices.ListBackups(s.Namespace, map[string]string{redisv1beta1.LabelBackupScheduleKey: s.Name})
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := time.Now().UTC()
+	if err := r.prune(s, backups.Items, now); err != nil {
+		return time.Time{}, err
+	}
+
+	status := s.Status.DeepCopy()
+	if last := getLastSuccessfulTime(backups.Items); !last.IsZero() {
+		r.metrics.SetBackupLastSuccess(s.Namespace, s.Name, last)
+		status.LastSuccessfulTime = last.Format(time.RFC3339)
+	}
+
+	from := s.CreationTimestamp.Time.UTC()
+	if s.Status.LastScheduleTime != "" {
+		if from, err = time.Parse(time.RFC3339, s.Status.LastScheduleTime); err != nil {
+			return time.Time{}, r.setMessage(s, fmt.Sprintf("invalid lastScheduleTime: %s", err))
+		}
+		from = from.UTC()
+	}
+	due := getLastDueTime(cron, from, now)
+	if !due.IsZero() {
+		// a missed run is not caught up, only the last one is considered
+		status.LastScheduleTime = due.Format(time.RFC3339)
+		status.Message, err = r.run(s, backups.Items, due)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if status.Message == "" {
+			status.LastBackup = getBackupName(s, due)
+		}
+		from = due
+	}
+
+	if *status != s.Status {
+		s.Status = *status
+		if err := r.k8sServices.UpdateBackupScheduleStatus(s.Namespace, s); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	next := cron.Next(from)
+	if next.IsZero() {
+		return time.Time{}, r.setMessage(s, fmt.Sprintf("schedule %q never runs", s.Spec.Schedule))
+	}
+	return next, nil
+}
+
+// run creates the backup due at the given time, it returns why the run is skipped
+func (r *RedisBackupScheduleHandler) run(s *redisv1beta1.RedisBackupSchedule, backups []redisv1beta1.RedisBackup, due time.Time) (string, error) {
+	logger := r.logger.WithValues("namespace", s.Namespace, "name", s.Name)
+	if s.Spec.Suspend {
+		logger.V(2).Info("schedule suspended, run skipped")
+		return "schedule suspended", nil
+	}
+	for _, b := range backups {
+		if !b.IsFinished() && b.DeletionTimestamp == nil {
+			message := fmt.Sprintf("run skipped, backup %s is still running", b.Name)
+			logger.Info(message)
+			return message, nil
+		}
+	}
+	rc, err := r.k8sServices.GetCluster(s.Namespace, s.Spec.ClusterName)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			message := fmt.Sprintf("run skipped, redis cluster %s not found", s.Spec.ClusterName)
+			logger.Info(message)
+			return message, nil
+		}
+		return "", err
+	}
+	if !rc.Status.IsHealthy() {
+		message := fmt.Sprintf("run skipped, redis cluster %s is not healthy", rc.Name)
+		logger.Info(message)
+		return message, nil
+	}
+
+	backup := generateBackup(s, due)
+	if err := r.k8sServices.CreateBackup(s.Namespace, backup); err != nil && !kerrors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return "", nil
+}
+
+// prune deletes the expired backups, their snapshot is deleted by the backup controller
+func (r *RedisBackupScheduleHandler) prune(s *redisv1beta1.RedisBackupSchedule, backups []redisv1beta1.RedisBackup, now time.Time) error {
+	var maxAge time.Duration
+	if s.Spec.Retention.MaxAge != "" {
+		// checked by Validate
+		maxAge, _ = time.ParseDuration(s.Spec.Retention.MaxAge)
+	}
+	for _, name := range getExpiredBackups(backups, s.Spec.Retention.MaxCount, maxAge, now) {
+		r.logger.WithValues("namespace", s.Namespace, "name", s.Name).Info(fmt.Sprintf("backup %s expired", name))
+		if err := r.k8sServices.DeleteBackup(s.Namespace, name); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RedisBackupScheduleHandler) setMessage(s *redisv1beta1.RedisBackupSchedule, message string) error {
+	r.logger.WithValues("namespace", s.Namespace, "name", s.Name).Info(message)
+	if s.Status.Message == message {
+		return nil
+	}
+	s.Status.Message = message
+	return r.k8sServices.UpdateBackupScheduleStatus(s.Namespace, s)
+}
+
+// getExpiredBackups returns the names of the completed backups beyond maxCount or older than maxAge, and of
+// the failed backups older than the last completed one. The running backups and the backups being deleted are kept.
+func getExpiredBackups(backups []redisv1beta1.RedisBackup, maxCount int32, maxAge time.Duration, now time.Time) []string {
+	var completed, failed []redisv1beta1.RedisBackup
+	for _, b := range backups {
+		if b.DeletionTimestamp != nil {
+			continue
+		}
+		switch b.Status.Phase {
+		case redisv1beta1.BackupPhaseCompleted:
+			completed = append(completed, b)
+		case redisv1beta1.BackupPhaseFailed:
+			failed = append(failed, b)
+		}
+	}
+	sort.SliceStable(completed, func(i, j int) bool {
+		return getCompletionTime(&completed[i]).After(getCompletionTime(&completed[j]))
+	})
+
+	var expired []string
+	for i, b := range completed {
+		if (maxCount > 0 && int32(i) >= maxCount) || (maxAge > 0 && now.Sub(getCompletionTime(&b)) > maxAge) {
+			expired = append(expired, b.Name)
+		}
+	}
+	if len(completed) > 0 {
+		lastSuccess := getCompletionTime(&completed[0])
+		for _, b := range failed {
+			if getCompletionTime(&b).Before(lastSuccess) {
+				expired = append(expired, b.Name)
+			}
+		}
+	}
+	return expired
+}
+
+// getLastSuccessfulTime returns the completion time of the last completed backup
+func getLastSuccessfulTime(backups []redisv1beta1.RedisBackup) time.Time {
+	var last time.Time
+	for _, b := range backups {
+		if b.Status.Phase != redisv1beta1.BackupPhaseCompleted {
+			continue
+		}
+		if t := getCompletionTime(&b); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+func getCompletionTime(b *redisv1beta1.RedisBackup) time.Time {
+	t, err := time.Parse(time.RFC3339, b.Status.CompletionTime)
+	if err != nil {
+		return b.CreationTimestamp.Time
+	}
+	return t
+}
+
+// getLastDueTime returns the last run of the schedule after from and not after now,
+// or the zero time if there is none
+func getLastDueTime(cron *util.CronSchedule, from, now time.Time) time.Time {
+	var due time.Time
+	for t := cron.Next(from); !t.IsZero() && !t.After(now); t = cron.Next(t) {
+		due = t
+	}
+	return due
+}
+
+func getBackupName(s *redisv1beta1.RedisBackupSchedule, due time.Time) string {
+	return fmt.Sprintf("%s-%d", s.Name, due.Unix())
+}
+
+// generateBackup returns the backup of the run. It is not owned by the schedule, so the backups are kept
+// when the schedule is deleted, and deleting it deletes its snapshot through the finalizer.
+func generateBackup(s *redisv1beta1.RedisBackupSchedule, due time.Time) *redisv1beta1.RedisBackup {
+	annotations := map[string]string{}
+	if v, ok := s.Annotations[util.AnnotationScope]; ok {
+		annotations[util.AnnotationScope] = v
+	}
+	return &redisv1beta1.RedisBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getBackupName(s, due),
+			Namespace: s.Namespace,
+			Labels: map[string]string{
+				redisv1beta1.LabelManagedByKey:      redisv1beta1.OperatorName,
+				redisv1beta1.LabelBackupScheduleKey: s.Name,
+			},
+			Annotations: annotations,
+			Finalizers:  []string{redisv1beta1.BackupCleanupFinalizer},
+		},
+		Spec: redisv1beta1.RedisBackupSpec{
+			ClusterName:     s.Spec.ClusterName,
+			Storage:         s.Spec.Storage,
+			Image:           s.Spec.Image,
+			ImagePullPolicy: s.Spec.ImagePullPolicy,
+		},
+	}
+}
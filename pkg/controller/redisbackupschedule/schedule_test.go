@@ -0,0 +1,127 @@
+package redisbackupschedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+var now = time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func backup(name string, phase redisv1beta1.BackupPhase, age time.Duration) redisv1beta1.RedisBackup {
+	return redisv1beta1.RedisBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: redisv1beta1.RedisBackupStatus{
+			Phase:          phase,
+			CompletionTime: now.Add(-age).Format(time.RFC3339),
+		},
+	}
+}
+
+func Test_getExpiredBackups(t *testing.T) {
+	deleting := backup("deleting", redisv1beta1.BackupPhaseCompleted, 96*time.Hour)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now}
+	backups := []redisv1beta1.RedisBackup{
+		backup("b3", redisv1beta1.BackupPhaseCompleted, 72*time.Hour),
+		backup("b1", redisv1beta1.BackupPhaseCompleted, 24*time.Hour),
+		backup("f2", redisv1beta1.BackupPhaseFailed, 48*time.Hour),
+		backup("b2", redisv1beta1.BackupPhaseCompleted, 48*time.Hour),
+		backup("f0", redisv1beta1.BackupPhaseFailed, time.Hour),
+		backup("running", redisv1beta1.BackupPhaseUploading, 0),
+		deleting,
+	}
+	tests := []struct {
+		name     string
+		backups  []redisv1beta1.RedisBackup
+		maxCount int32
+		maxAge   time.Duration
+		want     []string
+	}{
+		{
+			name:    "keep all",
+			backups: backups,
+			want:    []string{"f2"},
+		},
+		{
+			name:     "max count",
+			backups:  backups,
+			maxCount: 2,
+			want:     []string{"b3", "f2"},
+		},
+		{
+			name:    "max age",
+			backups: backups,
+			maxAge:  36 * time.Hour,
+			want:    []string{"b2", "b3", "f2"},
+		},
+		{
+			name:     "max count and max age",
+			backups:  backups,
+			maxCount: 1,
+			maxAge:   60 * time.Hour,
+			want:     []string{"b2", "b3", "f2"},
+		},
+		{
+			name:     "failed backups are kept without a completed one",
+			backups:  []redisv1beta1.RedisBackup{backup("f1", redisv1beta1.BackupPhaseFailed, 24*time.Hour)},
+			maxCount: 1,
+			maxAge:   time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getExpiredBackups(tt.backups, tt.maxCount, tt.maxAge, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getExpiredBackups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getLastDueTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		from     time.Time
+		want     time.Time
+	}{
+		{
+			name:     "not due",
+			schedule: "0 13 * * *",
+			from:     now.Add(-time.Hour),
+		},
+		{
+			name:     "due now",
+			schedule: "@hourly",
+			from:     now.Add(-time.Hour),
+			want:     now,
+		},
+		{
+			name:     "missed runs",
+			schedule: "*/15 * * * *",
+			from:     now.Add(-24 * time.Hour),
+			want:     now,
+		},
+		{
+			name:     "last run before now",
+			schedule: "0 3 * * *",
+			from:     now.Add(-72 * time.Hour),
+			want:     time.Date(2020, 3, 10, 3, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := util.ParseCron(tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := getLastDueTime(cron, tt.from, now); !got.Equal(tt.want) {
+				t.Errorf("getLastDueTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
 	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
 	"github.com/ucloud/redis-operator/pkg/util"
 )
@@ -21,59 +22,68 @@ var (
 )
 
 // CheckAndHeal Check the health of the cluster and heal,
+// Record the progress of the restore
 // Waiting Number of ready redis is equal as the set on the RedisCluster spec
 // Waiting Number of ready sentinel is equal as the set on the RedisCluster spec
+// Active replicas replicate from all the others, without sentinel
+// A restored cluster uses the redis loaded from the RDB as its first master
+// A cloned cluster uses the redis synced from the source as its first master
+// A resumed cluster uses the redis that was the master when it was suspended
+// The master of a standby replicates from the external master, removing replicaOf promotes it
 // Check only one master
 // Number of redis master is 1
 // All redis slaves have the same master
+// Redis pods are labeled with their role
 // Set Custom Redis config
+// All read replicas have the same master and never be promoted
 // All sentinels points to the same redis master
 // Sentinel has not death nodes
 // Sentinel knows the correct slave number
 func (r *RedisClusterHandler) CheckAndHeal(meta *clustercache.Meta) error {
+	if meta.Obj.IsRestoring() {
+		r.checkRestore(meta)
+	}
 	if err := r.rcChecker.CheckRedisNumber(meta.Obj); err != nil {
 		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).V(2).Info("number of redis mismatch, this could be for a change on the statefulset")
 		r.eventsCli.UpdateCluster(meta.Obj, "wait for all redis server start")
 		return needRequeueErr
 	}
+	if meta.Obj.IsActiveReplica() {
+		return r.checkAndHealActiveReplicas(meta)
+	}
 	if err := r.rcChecker.CheckSentinelNumber(meta.Obj); err != nil {
 		r.eventsCli.FailedCluster(meta.Obj, err.Error())
 		return nil
 	}
-
-	nMasters, err := r.rcChecker.GetNumberMasters(meta.Obj, meta.Auth)
-	if err != nil {
-		return err
-	}
-	switch nMasters {
-	case 0:
-		r.eventsCli.UpdateCluster(meta.Obj, "set master")
-		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).V(2).Info("no master find, fixing...")
-		redisesIP, err := r.rcChecker.GetRedisesIPs(meta.Obj, meta.Auth)
-		if err != nil {
+	if meta.Obj.IsRestoring() {
+		if err := r.setRestoredMaster(meta); err != nil {
 			return err
 		}
-		if len(redisesIP) == 1 {
-			if err := r.rcHealer.MakeMaster(redisesIP[0], meta.Auth); err != nil {
-				return err
-			}
-			break
+	}
+	if meta.Obj.IsCloning() {
+		if err := r.cloneFromSource(meta); err != nil {
+			return err
 		}
-		minTime, err := r.rcChecker.GetMinimumRedisPodTime(meta.Obj)
-		if err != nil {
+	}
+	if meta.Obj.IsResuming() {
+		if err := r.setResumedMaster(meta); err != nil {
 			return err
 		}
-		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(fmt.Sprintf("time %.f more than expected. Not even one master, fixing...", minTime.Round(time.Second).Seconds()))
-		if err := r.rcHealer.SetOldestAsMaster(meta.Obj, meta.Auth); err != nil {
+	}
+
+	if meta.Obj.Status.ReplicaOf != "" && !meta.Obj.IsStandby() {
+		if err := r.promoteStandby(meta); err != nil {
 			return err
 		}
-	case 1:
-		break
-	default:
-		return errors.New("more than one master, fix manually")
 	}
 
-	master, err := r.rcChecker.GetMasterIP(meta.Obj, meta.Auth)
+	var master string
+	var err error
+	if meta.Obj.IsStandby() {
+		master, err = r.setStandbyMaster(meta)
+	} else {
+		master, err = r.electMaster(meta)
+	}
 	if err != nil {
 		return err
 	}
@@ -84,10 +94,22 @@ func (r *RedisClusterHandler) CheckAndHeal(meta *clustercache.Meta) error {
 		}
 	}
 
+	if err := r.rcChecker.CheckRoleLabels(master, meta.Obj); err != nil {
+		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(err.Error())
+		if err := r.rcHealer.SetRoleLabels(master, meta.Obj); err != nil {
+			return err
+		}
+	}
+	meta.Obj.Status.MasterIP = master
+
 	if err = r.setRedisConfig(meta); err != nil {
 		return err
 	}
 
+	if err = r.setReadReplicas(meta, master); err != nil {
+		return err
+	}
+
 	sentinels, err := r.rcChecker.GetSentinelsIPs(meta.Obj)
 	if err != nil {
 		return err
@@ -98,6 +120,10 @@ func (r *RedisClusterHandler) CheckAndHeal(meta *clustercache.Meta) error {
 			if err := r.rcHealer.NewSentinelMonitor(sip, master, meta.Obj, meta.Auth); err != nil {
 				return err
 			}
+			// a new monitor has the default settings
+			if err := r.rcHealer.SetSentinelCustomConfig(sip, meta.Obj, meta.Auth); err != nil {
+				return err
+			}
 		}
 	}
 	for _, sip := range sentinels {
@@ -107,6 +133,9 @@ func (r *RedisClusterHandler) CheckAndHeal(meta *clustercache.Meta) error {
 			if err := r.rcHealer.RestoreSentinel(sip, meta.Auth); err != nil {
 				return err
 			}
+			if r.observing {
+				continue
+			}
 			if err := r.waitRestoreSentinelSlavesOK(sip, meta.Obj, meta.Auth); err != nil {
 				r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(err.Error())
 				return err
@@ -130,6 +159,76 @@ func (r *RedisClusterHandler) CheckAndHeal(meta *clustercache.Meta) error {
 	return nil
 }
 
+// electMaster makes sure there is exactly one master and returns it
+func (r *RedisClusterHandler) electMaster(meta *clustercache.Meta) (string, error) {
+	nMasters, err := r.rcChecker.GetNumberMasters(meta.Obj, meta.Auth)
+	if err != nil {
+		return "", err
+	}
+	switch nMasters {
+	case 0:
+		r.eventsCli.UpdateCluster(meta.Obj, "set master")
+		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).V(2).Info("no master find, fixing...")
+		redisesIP, err := r.rcChecker.GetRedisesIPs(meta.Obj, meta.Auth)
+		if err != nil {
+			return "", err
+		}
+		if len(redisesIP) == 1 {
+			if err := r.rcHealer.MakeMaster(redisesIP[0], meta.Auth); err != nil {
+				return "", err
+			}
+			break
+		}
+		minTime, err := r.rcChecker.GetMinimumRedisPodTime(meta.Obj)
+		if err != nil {
+			return "", err
+		}
+		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(fmt.Sprintf("time %.f more than expected. Not even one master, fixing...", minTime.Round(time.Second).Seconds()))
+		if err := r.rcHealer.SetOldestAsMaster(meta.Obj, meta.Auth); err != nil {
+			return "", err
+		}
+	case 1:
+		break
+	default:
+		return "", errors.New("more than one master, fix manually")
+	}
+
+	return r.rcChecker.GetMasterIP(meta.Obj, meta.Auth)
+}
+
+// checkAndHealActiveReplicas makes every redis replicate from all the others,
+// there is no master to elect and no sentinel to configure
+func (r *RedisClusterHandler) checkAndHealActiveReplicas(meta *clustercache.Meta) error {
+	redises, err := r.rcChecker.GetRedisesIPs(meta.Obj, meta.Auth)
+	if err != nil {
+		return err
+	}
+	for _, rip := range redises {
+		peers := []string{}
+		for _, peer := range redises {
+			if peer != rip {
+				peers = append(peers, peer)
+			}
+		}
+		if err := r.rcChecker.CheckActiveReplicaPeers(rip, peers, meta.Auth); err != nil {
+			r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(err.Error())
+			if err := r.rcHealer.SetActiveReplicaPeers(rip, peers, meta.Auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := r.rcChecker.CheckRoleLabels("", meta.Obj); err != nil {
+		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(err.Error())
+		if err := r.rcHealer.SetRoleLabels("", meta.Obj); err != nil {
+			return err
+		}
+	}
+	meta.Obj.Status.MasterIP = ""
+
+	return r.setRedisConfig(meta)
+}
+
 func (r *RedisClusterHandler) setRedisConfig(meta *clustercache.Meta) error {
 	redises, err := r.rcChecker.GetRedisesIPs(meta.Obj, meta.Auth)
 	if err != nil {
@@ -137,9 +236,49 @@ func (r *RedisClusterHandler) setRedisConfig(meta *clustercache.Meta) error {
 	}
 	for _, rip := range redises {
 		if err := r.rcChecker.CheckRedisConfig(meta.Obj, rip, meta.Auth); err != nil {
+			if r.warnUnsupportedConfig(meta, err) {
+				continue
+			}
 			r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(err.Error())
 			r.eventsCli.UpdateCluster(meta.Obj, "set custom config for redis server")
-			if err := r.rcHealer.SetRedisCustomConfig(rip, meta.Obj, meta.Auth); err != nil {
+			if err := r.rcHealer.SetRedisCustomConfig(rip, meta.Obj, meta.Auth); err != nil && !r.warnUnsupportedConfig(meta, err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// warnUnsupportedConfig sends a warning event for the custom configs unknown to the version of a redis,
+// they are skipped and the other configs are applied. It is false for the other errors.
+func (r *RedisClusterHandler) warnUnsupportedConfig(meta *clustercache.Meta, err error) bool {
+	unsupported, ok := err.(*redis.UnsupportedConfigError)
+	if !ok {
+		return false
+	}
+	r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(unsupported.Error())
+	r.eventsCli.UnsupportedConfig(meta.Obj, unsupported.Error())
+	return true
+}
+
+func (r *RedisClusterHandler) setReadReplicas(meta *clustercache.Meta, master string) error {
+	if err := r.rcChecker.CheckReadReplicasFromMaster(master, meta.Obj, meta.Auth); err != nil {
+		r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(err.Error())
+		return r.rcHealer.SetMasterOnReadReplicas(master, meta.Obj, meta.Auth)
+	}
+
+	replicas, err := r.rcChecker.GetReadReplicasIPs(meta.Obj)
+	if err != nil {
+		return err
+	}
+	for _, rip := range replicas {
+		if err := r.rcChecker.CheckReadReplicaConfig(meta.Obj, rip, meta.Auth); err != nil {
+			if r.warnUnsupportedConfig(meta, err) {
+				continue
+			}
+			r.logger.WithValues("namespace", meta.Obj.Namespace, "name", meta.Obj.Name).Info(err.Error())
+			r.eventsCli.UpdateCluster(meta.Obj, "set custom config for read replica")
+			if err := r.rcHealer.SetReadReplicaCustomConfig(rip, meta.Obj, meta.Auth); err != nil && !r.warnUnsupportedConfig(meta, err) {
 				return err
 			}
 		}
@@ -0,0 +1,59 @@
+package rediscluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func Test_setRedisConfig(t *testing.T) {
+	unsupported := &redis.UnsupportedConfigError{Configs: []string{"lfu-log-factor"}, Addr: "10.0.0.2"}
+	tests := []struct {
+		name      string
+		errs      map[string]error
+		wantDrift []string
+		wantEvent string
+	}{
+		{
+			name: "in sync",
+		},
+		{
+			name:      "config conflict",
+			errs:      map[string]error{"CheckRedisConfig 10.0.0.1": errors.New("maxmemory configs conflict")},
+			wantDrift: []string{"set the custom config of redis 10.0.0.1"},
+			wantEvent: "Normal " + string(redisv1beta1.ClusterConditionUpdating) + " set custom config for redis server",
+		},
+		{
+			name:      "unsupported config is only warned about",
+			errs:      map[string]error{"CheckRedisConfig 10.0.0.2": unsupported},
+			wantEvent: "Warning UnsupportedConfig " + unsupported.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &fakeChecker{redises: []string{"10.0.0.1", "10.0.0.2"}, errs: tt.errs}
+			r, healer, recorder := newTestHandler(&fakeServices{}, checker)
+			meta := &clustercache.Meta{Obj: &redisv1beta1.RedisCluster{}, Auth: &util.AuthConfig{}}
+
+			if err := r.setRedisConfig(meta); err != nil {
+				t.Fatalf("setRedisConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(healer.drift, tt.wantDrift) {
+				t.Errorf("setRedisConfig() heals = %v, want %v", healer.drift, tt.wantDrift)
+			}
+			var event string
+			select {
+			case event = <-recorder.Events:
+			default:
+			}
+			if event != tt.wantEvent {
+				t.Errorf("setRedisConfig() event = %q, want %q", event, tt.wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package rediscluster
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const sourceRedisPort = "6379"
+
+// cloneFromSource makes the first redis replicate from the current master of the source cluster, found
+// through the sentinel service of the source. Once the initial sync is done, the first redis is detached
+// and made the master of the cluster, the other redis do a full sync from it.
+func (r *RedisClusterHandler) cloneFromSource(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	logger := r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name)
+	from := rc.Spec.CloneFrom
+	now := time.Now().Format(time.RFC3339)
+
+	if rc.Status.Clone == nil {
+		rc.Status.Clone = &redisv1beta1.CloneStatus{
+			Source:    fmt.Sprintf("%s/%s", from.Namespace, from.Name),
+			Phase:     redisv1beta1.ClonePhaseSyncing,
+			StartTime: now,
+		}
+		if rc.Status.LastAppliedSpec != nil {
+			// the clone was added to a running cluster, its data is kept
+			rc.Status.Clone.Phase = redisv1beta1.ClonePhaseSkipped
+			rc.Status.Clone.Message = "clone ignored, the cluster already exists"
+			rc.Status.Clone.CompletionTime = now
+			logger.Info(rc.Status.Clone.Message)
+			r.k8sServices.UpdateCluster(rc.Namespace, rc)
+			return nil
+		}
+	}
+	status := rc.Status.Clone.DeepCopy()
+	defer func() {
+		if !reflect.DeepEqual(status, rc.Status.Clone) {
+			logger.Info(fmt.Sprintf("clone %s: %s", status.Phase, status.Message))
+			rc.Status.Clone = status
+			r.k8sServices.UpdateCluster(rc.Namespace, rc)
+		}
+	}()
+
+	if from.Namespace != rc.Namespace && !util.IsClusterScoped() {
+		return fmt.Errorf("cloning from namespace %s needs a cluster scoped operator", from.Namespace)
+	}
+	source, err := r.k8sServices.GetCluster(from.Namespace, from.Name)
+	if err != nil {
+		status.Message = fmt.Sprintf("source cluster not found: %s", err)
+		return needRequeueErr
+	}
+	svc, err := r.k8sServices.GetService(source.Namespace, util.GetSentinelName(source))
+	if err != nil {
+		status.Message = fmt.Sprintf("sentinel service of the source not found: %s", err)
+		return needRequeueErr
+	}
+	sourceMaster, err := r.rcChecker.GetSentinelMaster(svc.Spec.ClusterIP, meta.Auth)
+	if err != nil {
+		status.Message = fmt.Sprintf("master of the source not found: %s", err)
+		return needRequeueErr
+	}
+
+	pod, err := r.k8sServices.GetPod(rc.Namespace, util.GetFirstRedisPodName(rc))
+	if err != nil {
+		return err
+	}
+	if pod.Status.PodIP == "" {
+		return needRequeueErr
+	}
+	master, err := r.rcChecker.GetReplicaMaster(pod.Status.PodIP, meta.Auth)
+	if err != nil {
+		return err
+	}
+	if master != sourceMaster {
+		// the source master changes on a failover of the source, the sync starts again
+		logger.Info(fmt.Sprintf("pod %s replicates from master %s of the source", pod.Name, sourceMaster))
+		r.eventsCli.UpdateCluster(rc, "replicate from the source cluster")
+		sourceAuth := &util.AuthConfig{Password: source.Spec.Password}
+		if err := r.rcHealer.SetExternalMaster(pod.Status.PodIP, sourceMaster, sourceRedisPort, sourceAuth, meta.Auth); err != nil {
+			return err
+		}
+		status.SourceMaster = sourceMaster
+		status.Message = fmt.Sprintf("syncing from master %s of %s", sourceMaster, status.Source)
+		return needRequeueErr
+	}
+	if err := r.rcChecker.CheckReplicaSynced(pod.Status.PodIP, meta.Auth); err != nil {
+		status.Message = err.Error()
+		return needRequeueErr
+	}
+
+	logger.Info(fmt.Sprintf("pod %s synced from the source, it is the master", pod.Name))
+	r.eventsCli.UpdateCluster(rc, "detach from the source cluster")
+	if err := r.rcHealer.DetachExternalMaster(pod.Status.PodIP, meta.Auth); err != nil {
+		return err
+	}
+	if err := r.rcHealer.SetMasterOnAll(pod.Status.PodIP, rc, meta.Auth); err != nil {
+		return err
+	}
+	status.Phase = redisv1beta1.ClonePhaseCompleted
+	status.Message = fmt.Sprintf("cloned from master %s of %s", sourceMaster, status.Source)
+	status.CompletionTime = time.Now().Format(time.RFC3339)
+	return nil
+}
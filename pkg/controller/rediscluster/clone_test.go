@@ -0,0 +1,119 @@
+package rediscluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func Test_cloneFromSource(t *testing.T) {
+	syncing := func(sourceMaster string) *redisv1beta1.CloneStatus {
+		return &redisv1beta1.CloneStatus{Source: "default/source", Phase: redisv1beta1.ClonePhaseSyncing, SourceMaster: sourceMaster}
+	}
+	tests := []struct {
+		name             string
+		applied          bool
+		clone            *redisv1beta1.CloneStatus
+		replicaMaster    string
+		errs             map[string]error
+		wantErr          error
+		wantDrift        []string
+		wantPhase        redisv1beta1.ClonePhase
+		wantSourceMaster string
+	}{
+		{
+			name:      "existing cluster is skipped",
+			applied:   true,
+			wantPhase: redisv1beta1.ClonePhaseSkipped,
+		},
+		{
+			name:             "new clone replicates from the source master",
+			wantErr:          needRequeueErr,
+			wantDrift:        []string{"make 10.0.0.10 replicate from 10.0.1.10:6379"},
+			wantPhase:        redisv1beta1.ClonePhaseSyncing,
+			wantSourceMaster: "10.0.1.10",
+		},
+		{
+			name:             "resync after a failover of the source",
+			clone:            syncing("10.0.1.11"),
+			replicaMaster:    "10.0.1.11",
+			wantErr:          needRequeueErr,
+			wantDrift:        []string{"make 10.0.0.10 replicate from 10.0.1.10:6379"},
+			wantPhase:        redisv1beta1.ClonePhaseSyncing,
+			wantSourceMaster: "10.0.1.10",
+		},
+		{
+			name:             "wait for the sync",
+			clone:            syncing("10.0.1.10"),
+			replicaMaster:    "10.0.1.10",
+			errs:             map[string]error{"CheckReplicaSynced 10.0.0.10": errors.New("sync in progress")},
+			wantErr:          needRequeueErr,
+			wantPhase:        redisv1beta1.ClonePhaseSyncing,
+			wantSourceMaster: "10.0.1.10",
+		},
+		{
+			name:          "detach once synced",
+			clone:         syncing("10.0.1.10"),
+			replicaMaster: "10.0.1.10",
+			wantDrift: []string{
+				"detach 10.0.0.10 from its external master",
+				"make all the redis replicate from 10.0.0.10",
+			},
+			wantPhase:        redisv1beta1.ClonePhaseCompleted,
+			wantSourceMaster: "10.0.1.10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &redisv1beta1.RedisCluster{ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "default"}}
+			rc := &redisv1beta1.RedisCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: redisv1beta1.RedisClusterSpec{
+					Size:      3,
+					CloneFrom: &redisv1beta1.CloneSource{Namespace: "default", Name: "source"},
+				},
+			}
+			if tt.applied {
+				rc.Status.LastAppliedSpec = rc.Spec.DeepCopy()
+			}
+			rc.Status.Clone = tt.clone
+			services := &fakeServices{
+				clusters: map[string]*redisv1beta1.RedisCluster{source.Name: source},
+				services: map[string]*corev1.Service{
+					util.GetSentinelName(source): {Spec: corev1.ServiceSpec{ClusterIP: "10.0.1.1"}},
+				},
+				pods: map[string][]corev1.Pod{
+					util.GetRedisName(rc): {newTestPod(util.GetFirstRedisPodName(rc), "10.0.0.10", "v1", true)},
+				},
+			}
+			checker := &fakeChecker{
+				master:         "10.0.1.10",
+				replicaMasters: map[string]string{"10.0.0.10": tt.replicaMaster},
+				errs:           tt.errs,
+			}
+			r, healer, _ := newTestHandler(services, checker)
+			meta := &clustercache.Meta{Obj: rc, Auth: &util.AuthConfig{}}
+
+			if err := r.cloneFromSource(meta); err != tt.wantErr {
+				t.Fatalf("cloneFromSource() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(healer.drift, tt.wantDrift) {
+				t.Errorf("cloneFromSource() heals = %v, want %v", healer.drift, tt.wantDrift)
+			}
+			status := rc.Status.Clone
+			if status.Phase != tt.wantPhase || status.SourceMaster != tt.wantSourceMaster {
+				t.Errorf("cloneFromSource() status = %+v, want phase %s, source master %q", status, tt.wantPhase, tt.wantSourceMaster)
+			}
+			if services.clusterUpdates != 1 {
+				t.Errorf("cloneFromSource() updated the cluster %d times, want 1", services.clusterUpdates)
+			}
+		})
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"time"
 
 	"github.com/spf13/pflag"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -23,6 +26,7 @@ import (
 	"github.com/ucloud/redis-operator/pkg/client/k8s"
 	"github.com/ucloud/redis-operator/pkg/client/redis"
 	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/controller/sentinelwatcher"
 	"github.com/ucloud/redis-operator/pkg/controller/service"
 	"github.com/ucloud/redis-operator/pkg/metrics"
 	"github.com/ucloud/redis-operator/pkg/util"
@@ -58,11 +62,12 @@ func FlagSet() *pflag.FlagSet {
 // Add creates a new RedisCluster Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	watcher := sentinelwatcher.New(k8s.NewEvent(mgr.GetEventRecorderFor("redis-operator"), log), log)
+	return add(mgr, newReconciler(mgr, watcher), watcher)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, watcher *sentinelwatcher.Watcher) reconcile.Reconciler {
 	// Create kubernetes service.
 	k8sService := k8s.New(mgr.GetClient(), log)
 
@@ -81,6 +86,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 		rcHealer:    rcHealer,
 		metaCache:   new(clustercache.MetaMap),
 		eventsCli:   k8s.NewEvent(mgr.GetEventRecorderFor("redis-operator"), log),
+		watcher:     watcher,
 		logger:      log,
 	}
 
@@ -88,7 +94,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r reconcile.Reconciler, watcher *sentinelwatcher.Watcher) error {
 	// Create a new controller
 	c, err := controller.New("rediscluster-controller", mgr, controller.Options{Reconciler: r,
 		MaxConcurrentReconciles: maxConcurrentReconciles})
@@ -99,7 +105,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	Pred := predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			// returns false if redisCluster is ignored (not managed) by this operator.
-			if !shoudManage(e.MetaNew) {
+			if !util.ShouldManage(e.MetaNew) {
 				return false
 			}
 			log.WithValues("namespace", e.MetaNew.GetNamespace(), "name", e.MetaNew.GetName()).V(5).Info("Call UpdateFunc")
@@ -109,11 +115,16 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 					Info("Generation change return true", "old", e.ObjectOld, "new", e.ObjectNew)
 				return true
 			}
-			return false
+			// pausing, observing and resuming the reconciliation
+			if e.MetaOld.GetAnnotations()[redisv1beta1.AnnotationReconcile] != e.MetaNew.GetAnnotations()[redisv1beta1.AnnotationReconcile] {
+				return true
+			}
+			// a deleted cluster waits for its finalizer, like the removal of the deletion protection
+			return e.MetaNew.GetDeletionTimestamp() != nil
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
 			// returns false if redisCluster is ignored (not managed) by this operator.
-			if !shoudManage(e.Meta) {
+			if !util.ShouldManage(e.Meta) {
 				return false
 			}
 			log.WithValues("namespace", e.Meta.GetNamespace(), "name", e.Meta.GetName()).Info("Call DeleteFunc")
@@ -123,7 +134,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		},
 		CreateFunc: func(e event.CreateEvent) bool {
 			// returns false if redisCluster is ignored (not managed) by this operator.
-			if !shoudManage(e.Meta) {
+			if !util.ShouldManage(e.Meta) {
 				return false
 			}
 			log.WithValues("namespace", e.Meta.GetNamespace(), "name", e.Meta.GetName()).Info("Call CreateFunc")
@@ -137,42 +148,89 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	//ownerPred := predicate.Funcs{
-	//	UpdateFunc: func(e event.UpdateEvent) bool {
-	//		return false
-	//	},
-	//	DeleteFunc: func(e event.DeleteEvent) bool {
-	//		log.WithValues("namespace", e.Meta.GetNamespace(), "kind", e.Object.GetObjectKind().GroupVersionKind().Kind, "name", e.Meta.GetName()).
-	//			V(3).Info("dependent resource delete")
-	//		// Evaluates to false if the object has been confirmed deleted.
-	//		return !e.DeleteStateUnknown
-	//	},
-	//	CreateFunc: func(e event.CreateEvent) bool {
-	//		return false
-	//	},
-	//}
-	//
-	//// Watch for changes to redisCluster StatefulSet secondary resources
-	//err = c.Watch(&source.Kind{Type: &appsv1.StatefulSet{}}, &handler.EnqueueRequestForOwner{
-	//	IsController: true,
-	//	OwnerType:    &redisv1beta1.RedisCluster{},
-	//}, ownerPred)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//// Watch for changes to redisCluster Deployment secondary resources
-	//err = c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
-	//	IsController: true,
-	//	OwnerType:    &redisv1beta1.RedisCluster{},
-	//}, ownerPred)
-	//if err != nil {
-	//	return err
-	//}
+	// Watch for the failovers and down instances notified by the sentinels
+	err = c.Watch(&source.Channel{Source: watcher.Events()}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to redisCluster secondary resources
+	for _, obj := range []runtime.Object{
+		&appsv1.StatefulSet{},
+		&corev1.Service{},
+		&corev1.ConfigMap{},
+		&policyv1beta1.PodDisruptionBudget{},
+	} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &redisv1beta1.RedisCluster{},
+		}, ownerPred)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for changes to the pods of redisCluster, they are owned by the statefulsets
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(podToRequests),
+	}, podPred)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// ownerPred fires on the changes of the objects owned by a RedisCluster that need a reconcile
+var ownerPred = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		// a changed spec or a changed number of ready pods of the statefulsets
+		if e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() {
+			return true
+		}
+		oldSts, ok := e.ObjectOld.(*appsv1.StatefulSet)
+		if !ok {
+			return false
+		}
+		newSts := e.ObjectNew.(*appsv1.StatefulSet)
+		return oldSts.Status.ReadyReplicas != newSts.Status.ReadyReplicas
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		log.WithValues("namespace", e.Meta.GetNamespace(), "kind", e.Object.GetObjectKind().GroupVersionKind().Kind, "name", e.Meta.GetName()).
+			V(3).Info("dependent resource delete")
+		return true
+	},
+	CreateFunc: func(e event.CreateEvent) bool {
+		return false
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
+
+// podPred fires on the changes of the redis pods that change the topology of the cluster
+var podPred = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		newPod := e.ObjectNew.(*corev1.Pod)
+		// role label changes are made by the operator itself and are ignored
+		return oldPod.Status.PodIP != newPod.Status.PodIP || util.IsPodReady(oldPod) != util.IsPodReady(newPod)
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		log.WithValues("namespace", e.Meta.GetNamespace(), "name", e.Meta.GetName()).V(3).Info("redis pod delete")
+		return true
+	},
+	CreateFunc: func(e event.CreateEvent) bool {
+		return false
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
+
 var _ reconcile.Reconciler = &ReconcileRedisCluster{}
 
 // ReconcileRedisCluster reconciles a RedisCluster object
@@ -206,14 +264,53 @@ func (r *ReconcileRedisCluster) Reconcile(request reconcile.Request) (reconcile.
 			instance.Namespace = request.NamespacedName.Namespace
 			instance.Name = request.NamespacedName.Name
 			r.handler.metaCache.Del(instance)
+			r.handler.watcher.Stop(instance.Namespace, instance.Name)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
+	// requests mapped from the dependent resources are not filtered by the RedisCluster predicate
+	if !util.ShouldManage(instance) {
+		return reconcile.Result{}, nil
+	}
+
 	reqLogger.V(5).Info(fmt.Sprintf("RedisCluster Spec:\n %+v", instance))
 
+	// the deletion of a paused or observed cluster waits, its finalizer is not run
+	switch instance.Annotations[redisv1beta1.AnnotationReconcile] {
+	case redisv1beta1.ReconcilePaused:
+		if err = r.handler.Pause(instance); err != nil {
+			reqLogger.Error(err, "Reconcile pause")
+		}
+		return reconcile.Result{RequeueAfter: time.Duration(reconcileTime) * time.Second}, nil
+	case redisv1beta1.ReconcileObserve:
+		if err = r.handler.Observe(instance); err != nil {
+			reqLogger.Error(err, "Reconcile observe")
+		}
+		return reconcile.Result{RequeueAfter: time.Duration(reconcileTime) * time.Second}, nil
+	}
+
+	// a protected cluster is still healed while its deletion waits for the removal of the annotation
+	if instance.DeletionTimestamp != nil && !instance.IsDeletionProtected() {
+		if err = r.handler.Finalize(instance); err != nil {
+			if err.Error() == needRequeueMsg {
+				return reconcile.Result{RequeueAfter: 20 * time.Second}, nil
+			}
+			reqLogger.Error(err, "Reconcile finalize")
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// no finalizer can be added to a deleted cluster
+	if instance.DeletionTimestamp == nil {
+		if err = r.handler.ensureFinalizer(instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	if err = r.handler.Do(instance); err != nil {
 		if err.Error() == needRequeueMsg {
 			return reconcile.Result{RequeueAfter: 20 * time.Second}, nil
@@ -222,6 +319,9 @@ func (r *ReconcileRedisCluster) Reconcile(request reconcile.Request) (reconcile.
 		return reconcile.Result{}, err
 	}
 
+	if instance.IsActiveReplica() {
+		return reconcile.Result{RequeueAfter: time.Duration(reconcileTime) * time.Second}, nil
+	}
 	if err = r.handler.rcChecker.CheckSentinelReadyReplicas(instance); err != nil {
 		reqLogger.Info(err.Error())
 		return reconcile.Result{RequeueAfter: 20 * time.Second}, nil
@@ -230,15 +330,14 @@ func (r *ReconcileRedisCluster) Reconcile(request reconcile.Request) (reconcile.
 	return reconcile.Result{RequeueAfter: time.Duration(reconcileTime) * time.Second}, nil
 }
 
-func shoudManage(meta v1.Object) bool {
-	if v, ok := meta.GetAnnotations()[util.AnnotationScope]; ok {
-		if util.IsClusterScoped() {
-			return v == util.AnnotationClusterScoped
-		}
-	} else {
-		if !util.IsClusterScoped() {
-			return true
-		}
+// podToRequests maps the pods created by the operator to the RedisCluster they belong to
+func podToRequests(o handler.MapObject) []reconcile.Request {
+	labels := o.Meta.GetLabels()
+	if labels[redisv1beta1.LabelManagedByKey] != redisv1beta1.OperatorName ||
+		labels["app.kubernetes.io/part-of"] != util.AppLabel || labels["app.kubernetes.io/name"] == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: o.Meta.GetNamespace(), Name: labels["app.kubernetes.io/name"]}},
 	}
-	return false
 }
@@ -0,0 +1,153 @@
+package rediscluster
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func Test_podToRequests(t *testing.T) {
+	managed := map[string]string{
+		redisv1beta1.LabelManagedByKey: redisv1beta1.OperatorName,
+		"app.kubernetes.io/part-of":    util.AppLabel,
+		"app.kubernetes.io/name":       "test",
+	}
+	without := func(key string) map[string]string {
+		labels := map[string]string{}
+		for k, v := range managed {
+			if k != key {
+				labels[k] = v
+			}
+		}
+		return labels
+	}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []reconcile.Request
+	}{
+		{
+			name:   "redis pod",
+			labels: managed,
+			want:   []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test"}}},
+		},
+		{
+			name:   "not managed by the operator",
+			labels: without(redisv1beta1.LabelManagedByKey),
+		},
+		{
+			name:   "not part of a redis cluster",
+			labels: without("app.kubernetes.io/part-of"),
+		},
+		{
+			name:   "no cluster name",
+			labels: without("app.kubernetes.io/name"),
+		},
+		{
+			name: "no labels",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "redis-0", Namespace: "default", Labels: tt.labels}}
+			if got := podToRequests(handler.MapObject{Meta: pod, Object: pod}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("podToRequests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newUpdateEvent(oldObj, newObj runtime.Object) event.UpdateEvent {
+	return event.UpdateEvent{
+		MetaOld:   oldObj.(metav1.Object),
+		ObjectOld: oldObj,
+		MetaNew:   newObj.(metav1.Object),
+		ObjectNew: newObj,
+	}
+}
+
+func Test_ownerPred(t *testing.T) {
+	sts := func(generation int64, ready int32) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis", Generation: generation},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: ready},
+		}
+	}
+	configMap := func(generation int64, data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis", Generation: generation},
+			Data:       map[string]string{"redis.conf": data},
+		}
+	}
+	tests := []struct {
+		name string
+		e    event.UpdateEvent
+		want bool
+	}{
+		{name: "statefulset generation", e: newUpdateEvent(sts(1, 3), sts(2, 3)), want: true},
+		{name: "statefulset ready replicas", e: newUpdateEvent(sts(1, 3), sts(1, 2)), want: true},
+		{name: "statefulset status only", e: newUpdateEvent(sts(1, 3), sts(1, 3))},
+		{name: "other object generation", e: newUpdateEvent(configMap(1, "a"), configMap(2, "a")), want: true},
+		{name: "other object same generation", e: newUpdateEvent(configMap(1, "a"), configMap(1, "b"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownerPred.Update(tt.e); got != tt.want {
+				t.Errorf("ownerPred.Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	obj := sts(1, 3)
+	if !ownerPred.Delete(event.DeleteEvent{Meta: obj, Object: obj}) {
+		t.Errorf("ownerPred.Delete() = false, want true")
+	}
+	if ownerPred.Create(event.CreateEvent{Meta: obj, Object: obj}) {
+		t.Errorf("ownerPred.Create() = true, want false")
+	}
+}
+
+func Test_podPred(t *testing.T) {
+	pod := func(ip string, ready bool, role string) *corev1.Pod {
+		p := newTestPod("redis-0", ip, "v1", ready)
+		p.Labels[redisv1beta1.LabelRoleKey] = role
+		return &p
+	}
+	tests := []struct {
+		name string
+		e    event.UpdateEvent
+		want bool
+	}{
+		{name: "pod ip", e: newUpdateEvent(pod("", false, ""), pod("10.0.0.1", false, "")), want: true},
+		{name: "ready", e: newUpdateEvent(pod("10.0.0.1", false, ""), pod("10.0.0.1", true, "")), want: true},
+		{name: "not ready", e: newUpdateEvent(pod("10.0.0.1", true, ""), pod("10.0.0.1", false, "")), want: true},
+		{name: "role label", e: newUpdateEvent(pod("10.0.0.1", true, redisv1beta1.RoleReplica), pod("10.0.0.1", true, redisv1beta1.RoleMaster))},
+		{name: "not a pod", e: newUpdateEvent(&corev1.ConfigMap{}, &corev1.ConfigMap{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podPred.Update(tt.e); got != tt.want {
+				t.Errorf("podPred.Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	obj := pod("10.0.0.1", true, "")
+	if !podPred.Delete(event.DeleteEvent{Meta: obj, Object: obj}) {
+		t.Errorf("podPred.Delete() = false, want true")
+	}
+	if podPred.Create(event.CreateEvent{Meta: obj, Object: obj}) {
+		t.Errorf("podPred.Create() = true, want false")
+	}
+}
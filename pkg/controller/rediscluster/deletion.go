@@ -0,0 +1,236 @@
+package rediscluster
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// ensureFinalizer adds the finalizer that applies the deletion policy of the cluster
+func (r *RedisClusterHandler) ensureFinalizer(rc *redisv1beta1.RedisCluster) error {
+	if util.ContainsString(rc.Finalizers, redisv1beta1.ClusterDeletionFinalizer) {
+		return nil
+	}
+	rc.Finalizers = append(rc.Finalizers, redisv1beta1.ClusterDeletionFinalizer)
+	return r.k8sServices.UpdateClusterSpec(rc.Namespace, rc)
+}
+
+// Finalize applies the deletion policy of a deleted cluster, then removes its finalizer:
+// Delete: the persistent volume claims are deleted with the cluster
+// Retain: the persistent volume claims are kept, a new cluster with the same name adopts them
+// Snapshot: a final backup is taken, then the persistent volume claims are deleted
+// The final backup needs the redis pods, with a foreground deletion they are deleted first and the backup fails.
+// A protected cluster is not finalized, it keeps being reconciled by Do.
+func (r *RedisClusterHandler) Finalize(rc *redisv1beta1.RedisCluster) error {
+	if !util.ContainsString(rc.Finalizers, redisv1beta1.ClusterDeletionFinalizer) {
+		return nil
+	}
+
+	// the defaults are applied to a copy, the finalizer is removed from the stored cluster
+	validated := rc.DeepCopy()
+	if err := validated.Validate(); err != nil {
+		return r.setDeletingCondition(rc, fmt.Sprintf("deletion blocked by an invalid spec: %s", err))
+	}
+
+	switch validated.Spec.DeletionPolicy {
+	case redisv1beta1.DeletionPolicySnapshot:
+		if err := r.finalBackup(validated); err != nil {
+			return err
+		}
+		if err := r.deletePersistentVolumeClaims(rc); err != nil {
+			return err
+		}
+	case redisv1beta1.DeletionPolicyRetain:
+		if err := r.retainPersistentVolumeClaims(rc); err != nil {
+			return err
+		}
+	default:
+		if err := r.deletePersistentVolumeClaims(rc); err != nil {
+			return err
+		}
+	}
+
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("deletion policy %s applied", validated.Spec.DeletionPolicy))
+	rc.Finalizers = util.RemoveString(rc.Finalizers, redisv1beta1.ClusterDeletionFinalizer)
+	return r.k8sServices.UpdateClusterSpec(rc.Namespace, rc)
+}
+
+// finalBackup creates the final backup of the cluster and waits for its completion.
+// It is not owned by the cluster, so the snapshot is kept until the backup is deleted.
+func (r *RedisClusterHandler) finalBackup(rc *redisv1beta1.RedisCluster) error {
+	name := getFinalBackupName(rc)
+	backups, err := r.k8sServices.ListBackups(rc.Namespace, map[string]string{redisv1beta1.LabelFinalBackupKey: rc.Name})
+	if err != nil {
+		return err
+	}
+	var backup *redisv1beta1.RedisBackup
+	for i := range backups.Items {
+		if backups.Items[i].Name == name {
+			backup = &backups.Items[i]
+		}
+	}
+
+	if backup == nil {
+		r.eventsCli.UpdateCluster(rc, "final backup")
+		if err := r.k8sServices.CreateBackup(rc.Namespace, generateFinalBackup(rc)); err != nil && !kerrors.IsAlreadyExists(err) {
+			return err
+		}
+		if err := r.setDeletingCondition(rc, fmt.Sprintf("taking the final backup %s", name)); err != nil {
+			return err
+		}
+		return needRequeueErr
+	}
+
+	switch backup.Status.Phase {
+	case redisv1beta1.BackupPhaseCompleted:
+		return nil
+	case redisv1beta1.BackupPhaseFailed:
+		// the finalizer is kept, deleting the backup takes a new one
+		message := fmt.Sprintf("final backup %s failed: %s", name, backup.Status.Message)
+		if err := r.setDeletingCondition(rc, message); err != nil {
+			return err
+		}
+		return needRequeueErr
+	}
+	return needRequeueErr
+}
+
+// deletePersistentVolumeClaims deletes the persistent volume claims of the cluster,
+// they are kept by the kubernetes until the pods using them are deleted
+func (r *RedisClusterHandler) deletePersistentVolumeClaims(rc *redisv1beta1.RedisCluster) error {
+	pvcs, err := r.k8sServices.ListPersistentVolumeClaims(rc.Namespace, getPersistentVolumeClaimLabels(rc))
+	if err != nil {
+		return err
+	}
+	for _, pvc := range pvcs.Items {
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		if err := r.k8sServices.DeletePersistentVolumeClaim(rc.Namespace, pvc.Name); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// retainPersistentVolumeClaims releases the persistent volume claims of the cluster,
+// so the garbage collector keeps them
+func (r *RedisClusterHandler) retainPersistentVolumeClaims(rc *redisv1beta1.RedisCluster) error {
+	pvcs, err := r.k8sServices.ListPersistentVolumeClaims(rc.Namespace, getPersistentVolumeClaimLabels(rc))
+	if err != nil {
+		return err
+	}
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if !releasePersistentVolumeClaim(pvc, rc) {
+			continue
+		}
+		if err := r.k8sServices.UpdatePersistentVolumeClaim(rc.Namespace, pvc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adoptRetainedPersistentVolumeClaims takes back the persistent volume claims retained by a deleted cluster
+// with the same name, the statefulsets reuse them as their names do not change
+func (r *RedisClusterHandler) adoptRetainedPersistentVolumeClaims(rc *redisv1beta1.RedisCluster, ownerRefs []metav1.OwnerReference) error {
+	pvcs, err := r.k8sServices.ListPersistentVolumeClaims(rc.Namespace, map[string]string{redisv1beta1.LabelRetainedKey: rc.Name})
+	if err != nil {
+		return err
+	}
+	// the claims are owned like the ones created from the statefulset template
+	owned := !rc.Spec.Storage.KeepAfterDeletion && rc.Spec.DeletionPolicy != redisv1beta1.DeletionPolicyRetain
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		adoptPersistentVolumeClaim(pvc, ownerRefs, owned)
+		r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("adopt the retained persistent volume claim %s", pvc.Name))
+		if err := r.k8sServices.UpdatePersistentVolumeClaim(rc.Namespace, pvc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RedisClusterHandler) setDeletingCondition(rc *redisv1beta1.RedisCluster, message string) error {
+	conditions := rc.Status.Conditions
+	if len(conditions) > 0 && conditions[0].Type == redisv1beta1.ClusterConditionDeleting && conditions[0].Message == message {
+		return nil
+	}
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(message)
+	rc.Status.SetDeletingCondition(message)
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// releasePersistentVolumeClaim removes the owner reference to the cluster and marks the claim as retained,
+// it returns false if the claim is already released
+func releasePersistentVolumeClaim(pvc *corev1.PersistentVolumeClaim, rc *redisv1beta1.RedisCluster) bool {
+	if pvc.Labels[redisv1beta1.LabelRetainedKey] == rc.Name {
+		return false
+	}
+	var ownerRefs []metav1.OwnerReference
+	for _, ref := range pvc.OwnerReferences {
+		if ref.UID != rc.UID {
+			ownerRefs = append(ownerRefs, ref)
+		}
+	}
+	pvc.OwnerReferences = ownerRefs
+	if pvc.Labels == nil {
+		pvc.Labels = map[string]string{}
+	}
+	pvc.Labels[redisv1beta1.LabelRetainedKey] = rc.Name
+	return true
+}
+
+// adoptPersistentVolumeClaim removes the retained mark of the claim, and sets the owner references
+// when the claim is deleted with the cluster
+func adoptPersistentVolumeClaim(pvc *corev1.PersistentVolumeClaim, ownerRefs []metav1.OwnerReference, owned bool) {
+	delete(pvc.Labels, redisv1beta1.LabelRetainedKey)
+	if owned {
+		pvc.OwnerReferences = append(pvc.OwnerReferences, ownerRefs...)
+	}
+}
+
+// getPersistentVolumeClaimLabels returns the labels the statefulsets set on the claims created from their template
+func getPersistentVolumeClaimLabels(rc *redisv1beta1.RedisCluster) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/part-of": util.AppLabel,
+		"app.kubernetes.io/name":    rc.Name,
+	}
+}
+
+func getFinalBackupName(rc *redisv1beta1.RedisCluster) string {
+	return fmt.Sprintf("%s-final-%d", rc.Name, rc.DeletionTimestamp.Unix())
+}
+
+// generateFinalBackup returns the backup taken before the deletion of the cluster.
+// Deleting it deletes its snapshot through the finalizer.
+func generateFinalBackup(rc *redisv1beta1.RedisCluster) *redisv1beta1.RedisBackup {
+	annotations := map[string]string{}
+	if v, ok := rc.Annotations[util.AnnotationScope]; ok {
+		annotations[util.AnnotationScope] = v
+	}
+	return &redisv1beta1.RedisBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getFinalBackupName(rc),
+			Namespace: rc.Namespace,
+			Labels: map[string]string{
+				redisv1beta1.LabelManagedByKey:   redisv1beta1.OperatorName,
+				redisv1beta1.LabelFinalBackupKey: rc.Name,
+			},
+			Annotations: annotations,
+			Finalizers:  []string{redisv1beta1.BackupCleanupFinalizer},
+		},
+		Spec: redisv1beta1.RedisBackupSpec{
+			ClusterName:     rc.Name,
+			Storage:         rc.Spec.FinalBackup.Storage,
+			Image:           rc.Spec.FinalBackup.Image,
+			ImagePullPolicy: rc.Spec.FinalBackup.ImagePullPolicy,
+		},
+	}
+}
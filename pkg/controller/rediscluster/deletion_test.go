@@ -0,0 +1,89 @@
+package rediscluster
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+)
+
+func Test_releasePersistentVolumeClaim(t *testing.T) {
+	rc := &redisv1beta1.RedisCluster{ObjectMeta: metav1.ObjectMeta{Name: "test", UID: "rc-uid"}}
+	other := metav1.OwnerReference{Name: "other", UID: "other-uid"}
+	tests := []struct {
+		name       string
+		pvc        *corev1.PersistentVolumeClaim
+		want       bool
+		wantOwners []metav1.OwnerReference
+	}{
+		{
+			name: "owned by the cluster",
+			pvc: &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{Name: "test", UID: "rc-uid"}, other},
+			}},
+			want:       true,
+			wantOwners: []metav1.OwnerReference{other},
+		},
+		{
+			name: "kept after deletion",
+			pvc:  &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "redis"}}},
+			want: true,
+		},
+		{
+			name: "already released",
+			pvc: &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{redisv1beta1.LabelRetainedKey: "test"},
+			}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := releasePersistentVolumeClaim(tt.pvc, rc); got != tt.want {
+				t.Errorf("releasePersistentVolumeClaim() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.pvc.OwnerReferences, tt.wantOwners) {
+				t.Errorf("releasePersistentVolumeClaim() owners = %v, want %v", tt.pvc.OwnerReferences, tt.wantOwners)
+			}
+			if tt.pvc.Labels[redisv1beta1.LabelRetainedKey] != "test" {
+				t.Errorf("releasePersistentVolumeClaim() labels = %v, want the retained label", tt.pvc.Labels)
+			}
+		})
+	}
+}
+
+func Test_adoptPersistentVolumeClaim(t *testing.T) {
+	ownerRefs := []metav1.OwnerReference{{Name: "test", UID: "new-uid"}}
+	tests := []struct {
+		name       string
+		owned      bool
+		wantOwners []metav1.OwnerReference
+	}{
+		{
+			name:       "deleted with the cluster",
+			owned:      true,
+			wantOwners: ownerRefs,
+		},
+		{
+			name:  "kept after deletion",
+			owned: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{redisv1beta1.LabelRetainedKey: "test", "app": "redis"},
+			}}
+			adoptPersistentVolumeClaim(pvc, ownerRefs, tt.owned)
+			if !reflect.DeepEqual(pvc.OwnerReferences, tt.wantOwners) {
+				t.Errorf("adoptPersistentVolumeClaim() owners = %v, want %v", pvc.OwnerReferences, tt.wantOwners)
+			}
+			if !reflect.DeepEqual(pvc.Labels, map[string]string{"app": "redis"}) {
+				t.Errorf("adoptPersistentVolumeClaim() labels = %v", pvc.Labels)
+			}
+		})
+	}
+}
@@ -11,16 +11,25 @@ func (r *RedisClusterHandler) Ensure(rc *redisv1beta1.RedisCluster, labels map[s
 	if err := r.rcService.EnsureRedisService(rc, labels, or); err != nil {
 		return err
 	}
-	if err := r.rcService.EnsureSentinelService(rc, labels, or); err != nil {
+	if err := r.rcService.EnsureRedisRoleServices(rc, labels, or); err != nil {
 		return err
 	}
-	if err := r.rcService.EnsureSentinelHeadlessService(rc, labels, or); err != nil {
-		return err
-	}
-	if err := r.rcService.EnsureSentinelConfigMap(rc, labels, or); err != nil {
-		return err
+	// active replicas replicate each other, they are not monitored by sentinel
+	if !rc.IsActiveReplica() {
+		if err := r.rcService.EnsureSentinelService(rc, labels, or); err != nil {
+			return err
+		}
+		if err := r.rcService.EnsureSentinelHeadlessService(rc, labels, or); err != nil {
+			return err
+		}
+		if err := r.rcService.EnsureSentinelConfigMap(rc, labels, or); err != nil {
+			return err
+		}
+		if err := r.rcService.EnsureSentinelProbeConfigMap(rc, labels, or); err != nil {
+			return err
+		}
 	}
-	if err := r.rcService.EnsureSentinelProbeConfigMap(rc, labels, or); err != nil {
+	if err := r.rcService.EnsureRedisConfigMap(rc, labels, or); err != nil {
 		return err
 	}
 	if err := r.rcService.EnsureRedisShutdownConfigMap(rc, labels, or); err != nil {
@@ -29,7 +38,15 @@ func (r *RedisClusterHandler) Ensure(rc *redisv1beta1.RedisCluster, labels map[s
 	if err := r.rcService.EnsureRedisStatefulset(rc, labels, or); err != nil {
 		return err
 	}
-	if err := r.rcService.EnsureSentinelStatefulset(rc, labels, or); err != nil {
+	if !rc.IsActiveReplica() {
+		if err := r.rcService.EnsureSentinelStatefulset(rc, labels, or); err != nil {
+			return err
+		}
+	}
+	if err := r.rcService.EnsureReadReplicaService(rc, labels, or); err != nil {
+		return err
+	}
+	if err := r.rcService.EnsureReadReplicaStatefulset(rc, labels, or); err != nil {
 		return err
 	}
 
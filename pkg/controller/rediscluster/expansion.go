@@ -0,0 +1,151 @@
+package rediscluster
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// expandVolumes expands the persistent volumes of the redis when their requested size is increased.
+// The volume claim templates of a statefulset can not be updated: the claims are expanded first, kubernetes
+// refuses it when their storage class does not allow the expansion. Once the volumes are resized the
+// statefulset is deleted without its pods, and recreated with the new template by Ensure.
+func (r *RedisClusterHandler) expandVolumes(rc *redisv1beta1.RedisCluster) error {
+	if rc.Spec.Storage.PersistentVolumeClaim == nil {
+		return nil
+	}
+	size, ok := rc.Spec.Storage.PersistentVolumeClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		return nil
+	}
+	ss, err := r.k8sServices.GetStatefulSet(rc.Namespace, util.GetRedisName(rc))
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	// the orphaned pods are adopted by the new statefulset, it is created once the old one is gone
+	if ss.DeletionTimestamp != nil {
+		return needRequeueErr
+	}
+	if len(ss.Spec.VolumeClaimTemplates) == 0 {
+		return nil
+	}
+	template := ss.Spec.VolumeClaimTemplates[0]
+	current := template.Spec.Resources.Requests[corev1.ResourceStorage]
+
+	now := time.Now().Format(time.RFC3339)
+	status := &redisv1beta1.VolumeExpansionStatus{
+		Size:      size.String(),
+		Phase:     redisv1beta1.VolumeExpansionPhaseResizing,
+		StartTime: now,
+	}
+	if rc.Status.VolumeExpansion != nil && rc.Status.VolumeExpansion.Size == size.String() {
+		status = rc.Status.VolumeExpansion.DeepCopy()
+	}
+
+	switch size.Cmp(current) {
+	case 0:
+		if rc.Status.VolumeExpansion != nil && rc.Status.VolumeExpansion.Phase == redisv1beta1.VolumeExpansionPhaseRecreating {
+			status.Phase = redisv1beta1.VolumeExpansionPhaseCompleted
+			status.Message = ""
+			status.CompletionTime = now
+			return r.setVolumeExpansion(rc, status)
+		}
+		return nil
+	case -1:
+		status.Phase = redisv1beta1.VolumeExpansionPhaseFailed
+		status.Message = fmt.Sprintf("the volumes can not be shrunk from %s", current.String())
+		return r.setVolumeExpansion(rc, status)
+	}
+
+	pvcs, err := r.k8sServices.ListPersistentVolumeClaims(rc.Namespace, ss.Spec.Selector.MatchLabels)
+	if err != nil {
+		return err
+	}
+	// the claims of a statefulset are named <template>-<statefulset>-<ordinal>
+	prefix := fmt.Sprintf("%s-%s-", template.Name, ss.Name)
+	var pending []string
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if !strings.HasPrefix(pvc.Name, prefix) {
+			continue
+		}
+		if needExpansion(pvc, size) {
+			if pvc.Spec.Resources.Requests == nil {
+				pvc.Spec.Resources.Requests = corev1.ResourceList{}
+			}
+			pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+			if err := r.k8sServices.UpdatePersistentVolumeClaim(rc.Namespace, pvc); err != nil {
+				if kerrors.IsForbidden(err) || kerrors.IsInvalid(err) {
+					status.Phase = redisv1beta1.VolumeExpansionPhaseFailed
+					status.Message = fmt.Sprintf("failed to expand %s: %s", pvc.Name, err)
+					return r.setVolumeExpansion(rc, status)
+				}
+				return err
+			}
+		}
+		if !isVolumeExpanded(pvc, size) {
+			pending = append(pending, pvc.Name)
+		}
+	}
+
+	if len(pending) > 0 {
+		status.Phase = redisv1beta1.VolumeExpansionPhaseResizing
+		status.Message = fmt.Sprintf("waiting for the resize of %s", strings.Join(pending, ", "))
+		return r.setVolumeExpansion(rc, status)
+	}
+
+	status.Phase = redisv1beta1.VolumeExpansionPhaseRecreating
+	status.Message = "recreating the statefulset with the new volume claim template"
+	if err := r.setVolumeExpansion(rc, status); err != nil {
+		return err
+	}
+	if err := r.k8sServices.OrphanStatefulSet(ss.Namespace, ss.Name); err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return needRequeueErr
+}
+
+func (r *RedisClusterHandler) setVolumeExpansion(rc *redisv1beta1.RedisCluster, status *redisv1beta1.VolumeExpansionStatus) error {
+	if reflect.DeepEqual(status, rc.Status.VolumeExpansion) {
+		return nil
+	}
+	message := fmt.Sprintf("volume expansion to %s %s: %s", status.Size, status.Phase, status.Message)
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(message)
+	switch status.Phase {
+	case redisv1beta1.VolumeExpansionPhaseFailed:
+		r.eventsCli.FailedCluster(rc, message)
+	case redisv1beta1.VolumeExpansionPhaseResizing, redisv1beta1.VolumeExpansionPhaseRecreating:
+		r.eventsCli.UpdateCluster(rc, message)
+		rc.Status.SetResizingCondition(message)
+	}
+	rc.Status.VolumeExpansion = status
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// needExpansion is true when the claim requests less than the size
+func needExpansion(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) bool {
+	request, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	return !ok || request.Cmp(size) < 0
+}
+
+// isVolumeExpanded is true when the volume of the claim and its file system are resized
+func isVolumeExpanded(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) bool {
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == corev1.PersistentVolumeClaimFileSystemResizePending && c.Status == corev1.ConditionTrue {
+			return false
+		}
+	}
+	capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+	return ok && capacity.Cmp(size) >= 0
+}
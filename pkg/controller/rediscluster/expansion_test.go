@@ -0,0 +1,94 @@
+package rediscluster
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_needExpansion(t *testing.T) {
+	size := resource.MustParse("2Gi")
+	tests := []struct {
+		name     string
+		requests corev1.ResourceList
+		want     bool
+	}{
+		{
+			name:     "smaller request",
+			requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			want:     true,
+		},
+		{
+			name:     "already requested",
+			requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2048Mi")},
+			want:     false,
+		},
+		{
+			name:     "bigger request",
+			requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("3Gi")},
+			want:     false,
+		},
+		{
+			name: "no request",
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{Requests: tt.requests},
+			}}
+			if got := needExpansion(pvc, size); got != tt.want {
+				t.Errorf("needExpansion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isVolumeExpanded(t *testing.T) {
+	size := resource.MustParse("2Gi")
+	tests := []struct {
+		name       string
+		capacity   corev1.ResourceList
+		conditions []corev1.PersistentVolumeClaimCondition
+		want       bool
+	}{
+		{
+			name:     "resized",
+			capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+			want:     true,
+		},
+		{
+			name:     "volume resizing",
+			capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			conditions: []corev1.PersistentVolumeClaimCondition{
+				{Type: corev1.PersistentVolumeClaimResizing, Status: corev1.ConditionTrue},
+			},
+			want: false,
+		},
+		{
+			name:     "file system resize pending",
+			capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+			conditions: []corev1.PersistentVolumeClaimCondition{
+				{Type: corev1.PersistentVolumeClaimFileSystemResizePending, Status: corev1.ConditionTrue},
+			},
+			want: false,
+		},
+		{
+			name: "not bound",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := &corev1.PersistentVolumeClaim{Status: corev1.PersistentVolumeClaimStatus{
+				Capacity:   tt.capacity,
+				Conditions: tt.conditions,
+			}}
+			if got := isVolumeExpanded(pvc, size); got != tt.want {
+				t.Errorf("isVolumeExpanded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
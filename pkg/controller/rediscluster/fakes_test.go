@@ -0,0 +1,211 @@
+package rediscluster
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/controller/service"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// fakeServices serves the statefulsets and pods of a test and records the changes made to them.
+// The methods a test does not expect panic on the nil embedded interface.
+type fakeServices struct {
+	k8s.Services
+	clusters     map[string]*redisv1beta1.RedisCluster
+	services     map[string]*corev1.Service
+	statefulSets map[string]*appsv1.StatefulSet
+	pods         map[string][]corev1.Pod
+	deleted      []string
+	updatedPods  []string
+	// clusterUpdates is the number of status updates of the cluster
+	clusterUpdates int
+}
+
+func (f *fakeServices) GetCluster(namespace, name string) (*redisv1beta1.RedisCluster, error) {
+	rc, ok := f.clusters[name]
+	if !ok {
+		return nil, errors.NewNotFound(redisv1beta1.SchemeGroupVersion.WithResource("redisclusters").GroupResource(), name)
+	}
+	return rc, nil
+}
+
+func (f *fakeServices) GetService(namespace, name string) (*corev1.Service, error) {
+	svc, ok := f.services[name]
+	if !ok {
+		return nil, errors.NewNotFound(corev1.Resource("services"), name)
+	}
+	return svc, nil
+}
+
+func (f *fakeServices) GetStatefulSet(namespace, name string) (*appsv1.StatefulSet, error) {
+	ss, ok := f.statefulSets[name]
+	if !ok {
+		return nil, errors.NewNotFound(appsv1.Resource("statefulsets"), name)
+	}
+	return ss, nil
+}
+
+func (f *fakeServices) GetStatefulSetPods(namespace, name string) (*corev1.PodList, error) {
+	return &corev1.PodList{Items: append([]corev1.Pod{}, f.pods[name]...)}, nil
+}
+
+func (f *fakeServices) GetPod(namespace, name string) (*corev1.Pod, error) {
+	for _, pods := range f.pods {
+		for i := range pods {
+			if pods[i].Name == name {
+				return pods[i].DeepCopy(), nil
+			}
+		}
+	}
+	return nil, errors.NewNotFound(corev1.Resource("pods"), name)
+}
+
+func (f *fakeServices) UpdatePod(namespace string, pod *corev1.Pod) error {
+	f.updatedPods = append(f.updatedPods, pod.Name)
+	return nil
+}
+
+func (f *fakeServices) DeletePod(namespace, name string) error {
+	f.deleted = append(f.deleted, name)
+	return nil
+}
+
+func (f *fakeServices) UpdateCluster(namespace string, cluster *redisv1beta1.RedisCluster) error {
+	f.clusterUpdates++
+	return nil
+}
+
+// fakeChecker answers the checks of a test. The checks fail with the error set in errs
+// for "<method> <address>", the methods a test does not expect panic.
+type fakeChecker struct {
+	service.RedisClusterCheck
+	master string
+	// replicaMasters is the master each redis replicates from
+	replicaMasters map[string]string
+	redises        []string
+	sentinels      []string
+	errs           map[string]error
+	errReplies     map[string]int64
+	saves          map[string]*redis.SaveStatus
+	// auth is the auth the masters were counted with
+	auth *util.AuthConfig
+}
+
+func (f *fakeChecker) err(method, addr string) error {
+	return f.errs[fmt.Sprintf("%s %s", method, addr)]
+}
+
+func (f *fakeChecker) CheckRedisNumber(rc *redisv1beta1.RedisCluster) error {
+	return f.err("CheckRedisNumber", "")
+}
+
+func (f *fakeChecker) CheckSentinelNumber(rc *redisv1beta1.RedisCluster) error {
+	return f.err("CheckSentinelNumber", "")
+}
+
+func (f *fakeChecker) GetNumberMasters(rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) (int, error) {
+	f.auth = auth
+	return 1, f.err("GetNumberMasters", "")
+}
+
+func (f *fakeChecker) GetMasterIP(rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) (string, error) {
+	return f.master, f.err("GetMasterIP", "")
+}
+
+func (f *fakeChecker) GetSentinelMaster(sentinel string, auth *util.AuthConfig) (string, error) {
+	return f.master, f.err("GetSentinelMaster", sentinel)
+}
+
+func (f *fakeChecker) GetReplicaMaster(addr string, auth *util.AuthConfig) (string, error) {
+	return f.replicaMasters[addr], f.err("GetReplicaMaster", addr)
+}
+
+func (f *fakeChecker) GetRedisesIPs(rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) ([]string, error) {
+	return f.redises, nil
+}
+
+func (f *fakeChecker) CheckRedisConfig(rc *redisv1beta1.RedisCluster, addr string, auth *util.AuthConfig) error {
+	return f.err("CheckRedisConfig", addr)
+}
+
+func (f *fakeChecker) GetSentinelsIPs(rc *redisv1beta1.RedisCluster) ([]string, error) {
+	return f.sentinels, nil
+}
+
+func (f *fakeChecker) CheckReplicaSynced(addr string, auth *util.AuthConfig) error {
+	return f.err("CheckReplicaSynced", addr)
+}
+
+func (f *fakeChecker) CheckSentinelMonitor(sentinel string, monitor string, auth *util.AuthConfig) error {
+	return f.err("CheckSentinelMonitor", sentinel)
+}
+
+func (f *fakeChecker) CheckSentinelMasterStatus(sentinel string, auth *util.AuthConfig) error {
+	return f.err("CheckSentinelMasterStatus", sentinel)
+}
+
+func (f *fakeChecker) CheckSentinelNumberInMemory(sentinel string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return f.err("CheckSentinelNumberInMemory", sentinel)
+}
+
+func (f *fakeChecker) CheckSmokeCommand(addr string, command []string, auth *util.AuthConfig) error {
+	return f.err("CheckSmokeCommand", addr)
+}
+
+func (f *fakeChecker) GetSaveStatus(addr string, auth *util.AuthConfig) (*redis.SaveStatus, error) {
+	return f.saves[addr], f.err("GetSaveStatus", addr)
+}
+
+func (f *fakeChecker) GetErrorReplies(addr string, auth *util.AuthConfig) (int64, error) {
+	return f.errReplies[addr], f.err("GetErrorReplies", addr)
+}
+
+// newTestHandler returns a handler on the fakes, its healer records the heals as drift
+// and its events are kept by a fake recorder
+func newTestHandler(services *fakeServices, checker *fakeChecker) (*RedisClusterHandler, *driftRecorder, *record.FakeRecorder) {
+	healer := &driftRecorder{}
+	recorder := record.NewFakeRecorder(100)
+	return &RedisClusterHandler{
+		k8sServices: services,
+		rcChecker:   checker,
+		rcHealer:    healer,
+		eventsCli:   k8s.NewEvent(recorder, logf.NullLogger{}),
+		logger:      logf.NullLogger{},
+	}, healer, recorder
+}
+
+// newTestStatefulSet returns a statefulset whose last spec was seen by the statefulset controller
+func newTestStatefulSet(name, revision string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+		Status:     appsv1.StatefulSetStatus{ObservedGeneration: 1, UpdateRevision: revision},
+	}
+}
+
+// newTestPod returns a pod of the given statefulset revision
+func newTestPod(name, ip, revision string, ready bool) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{appsv1.StatefulSetRevisionLabel: revision},
+		},
+		Status: corev1.PodStatus{
+			PodIP:      ip,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
@@ -9,6 +9,7 @@ import (
 	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
 	"github.com/ucloud/redis-operator/pkg/client/k8s"
 	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/controller/sentinelwatcher"
 	"github.com/ucloud/redis-operator/pkg/controller/service"
 	"github.com/ucloud/redis-operator/pkg/metrics"
 	"github.com/ucloud/redis-operator/pkg/util"
@@ -29,7 +30,10 @@ type RedisClusterHandler struct {
 	rcHealer    service.RedisClusterHeal
 	metaCache   *clustercache.MetaMap
 	eventsCli   k8s.Event
+	watcher     *sentinelwatcher.Watcher
 	logger      logr.Logger
+	// observing handlers record the heals instead of making them, so they do not wait for their results
+	observing bool
 }
 
 // Do will ensure the RedisCluster is in the expected state and update the RedisCluster status.
@@ -39,6 +43,10 @@ func (r *RedisClusterHandler) Do(rc *redisv1beta1.RedisCluster) error {
 		metrics.ClusterMetrics.SetClusterError(rc.Namespace, rc.Name)
 		return err
 	}
+	if err := r.setMaxMemory(rc); err != nil {
+		metrics.ClusterMetrics.SetClusterError(rc.Namespace, rc.Name)
+		return err
+	}
 
 	// diff new and new RedisCluster, then update status
 	meta := r.metaCache.Cache(rc)
@@ -50,12 +58,31 @@ func (r *RedisClusterHandler) Do(rc *redisv1beta1.RedisCluster) error {
 	// received rc.
 	oRefs := r.createOwnerReferences(rc)
 
+	if err := r.adoptRetainedPersistentVolumeClaims(rc, oRefs); err != nil {
+		return err
+	}
+
 	// Create the labels every object derived from this need to have.
 	labels := r.getLabels(rc)
 
+	r.prepareUpgrade(rc)
+
+	if rc.Spec.Suspended {
+		return r.suspend(meta, labels, oRefs)
+	}
+	if rc.Status.Suspension != nil && rc.Status.Suspension.Phase == redisv1beta1.SuspensionPhaseSuspended {
+		if err := r.resume(rc); err != nil {
+			return err
+		}
+	}
+
+	if err := r.expandVolumes(rc); err != nil {
+		return err
+	}
+
 	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info("Ensure...")
 	r.eventsCli.EnsureCluster(rc)
-	if err := r.Ensure(meta.Obj, labels, oRefs); err != nil {
+	if err := r.Ensure(withRollbackImage(meta.Obj), labels, oRefs); err != nil {
 		r.eventsCli.FailedCluster(rc, err.Error())
 		rc.Status.SetFailedCondition(err.Error())
 		r.k8sServices.UpdateCluster(rc.Namespace, rc)
@@ -73,6 +100,15 @@ func (r *RedisClusterHandler) Do(rc *redisv1beta1.RedisCluster) error {
 			r.k8sServices.UpdateCluster(rc.Namespace, rc)
 			return err
 		}
+		// a canary that never becomes ready blocks the checks, its health gates and rollback must still run
+		if rc.Status.Upgrade != nil {
+			if err := r.upgradeRedis(meta); err != nil && err.Error() != needRequeueMsg {
+				r.eventsCli.FailedCluster(rc, err.Error())
+				rc.Status.SetFailedCondition(err.Error())
+				r.k8sServices.UpdateCluster(rc.Namespace, rc)
+				return err
+			}
+		}
 		// if user delete statefulset or deployment, set status
 		status := rc.Status.Conditions
 		if len(status) > 0 && status[0].Type == redisv1beta1.ClusterConditionHealthy {
@@ -83,11 +119,43 @@ func (r *RedisClusterHandler) Do(rc *redisv1beta1.RedisCluster) error {
 		return err
 	}
 
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info("Upgrade...")
+	if err := r.upgrade(meta); err != nil {
+		if err.Error() != needRequeueMsg {
+			metrics.ClusterMetrics.SetClusterError(rc.Namespace, rc.Name)
+			r.eventsCli.FailedCluster(rc, err.Error())
+			rc.Status.SetFailedCondition(err.Error())
+			r.k8sServices.UpdateCluster(rc.Namespace, rc)
+		}
+		return err
+	}
+
 	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info("SetReadyCondition...")
 	r.eventsCli.HealthCluster(rc)
-	rc.Status.SetReadyCondition("Cluster ok")
+	message := "Cluster ok"
+	if up := rc.Status.Upgrade; up != nil && up.Phase == redisv1beta1.UpgradePhaseFailed {
+		message = fmt.Sprintf("Cluster ok, upgrade to %s failed, rolled back to %s", up.TargetImage, up.PreviousImage)
+	}
+	if rc.DeletionTimestamp != nil {
+		message = fmt.Sprintf("Cluster ok, deletion blocked by the annotation %s", redisv1beta1.AnnotationDeletionProtection)
+	}
+	rc.Status.SetReadyCondition(message)
+	rc.Status.ObservedGeneration = rc.Generation
+	rc.Status.SetLastAppliedSpec(&rc.Spec)
+	// active replicas have no master to set back when resumed
+	rc.Status.Suspension = nil
+	rc.Status.Drift = nil
 	r.k8sServices.UpdateCluster(rc.Namespace, rc)
 	metrics.ClusterMetrics.SetClusterOK(rc.Namespace, rc.Name)
+	metrics.ClusterMetrics.SetClusterSuspended(rc.Namespace, rc.Name, false)
+
+	// listen to the sentinels once the cluster is ready, so failovers are handled without waiting for the next resync.
+	// The sentinels of a standby always see its master as down, the operator moves it instead.
+	if rc.IsStandby() {
+		r.watcher.Stop(rc.Namespace, rc.Name)
+	} else if !rc.IsActiveReplica() {
+		r.watcher.Watch(rc)
+	}
 
 	return nil
 }
@@ -0,0 +1,91 @@
+package rediscluster
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// defaultReplBacklogSize is the repl-backlog-size of redis when config has none
+const defaultReplBacklogSize = 1024 * 1024
+
+// setMaxMemory defaults the maxmemory of the redis to a part of their memory limit when spec.memory asks for it.
+// A maxmemory that does not fit in the limit is reported by an event when it changes, or refused with the Reject overcommit.
+func (r *RedisClusterHandler) setMaxMemory(rc *redisv1beta1.RedisCluster) error {
+	warning, err := deriveMaxMemory(rc)
+	if err != nil {
+		return err
+	}
+	if warning != "" && rc.Spec.Memory != nil && rc.Spec.Memory.Overcommit == redisv1beta1.MemoryOvercommitReject {
+		return errors.New(warning)
+	}
+	if warning == rc.Status.MemoryOvercommit {
+		return nil
+	}
+	if warning != "" {
+		r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(warning)
+		r.eventsCli.MemoryOvercommit(rc, warning)
+	}
+	rc.Status.MemoryOvercommit = warning
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// deriveMaxMemory sets the maxmemory from the memory limit when config has none and spec.memory is set,
+// leaving room for the replication backlog. It returns why the maxmemory and the replication backlog
+// do not fit in the limit, whether spec.memory is set or not.
+func deriveMaxMemory(rc *redisv1beta1.RedisCluster) (string, error) {
+	limit, ok := rc.Spec.Resources.Limits[corev1.ResourceMemory]
+	if !ok || limit.IsZero() {
+		return "", nil
+	}
+	config := rc.Spec.Config
+	backlog := int64(defaultReplBacklogSize)
+	if value, ok := config["repl-backlog-size"]; ok {
+		var err error
+		if backlog, err = parseMemoryConfig(value); err != nil {
+			return "", fmt.Errorf("invalid repl-backlog-size %q: %s", value, err)
+		}
+	}
+
+	if value, ok := config["maxmemory"]; ok {
+		maxMemory, err := parseMemoryConfig(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid maxmemory %q: %s", value, err)
+		}
+		if maxMemory == 0 {
+			return fmt.Sprintf("maxmemory 0 is unlimited, the redis are killed when they reach the memory limit %s", limit.String()), nil
+		}
+		if maxMemory+backlog > limit.Value() {
+			return fmt.Sprintf("maxmemory %s and repl-backlog-size %d exceed the memory limit %s", value, backlog, limit.String()), nil
+		}
+		return "", nil
+	}
+
+	// an existing cluster keeps running without maxmemory until spec.memory is added
+	if rc.Spec.Memory == nil {
+		return "", nil
+	}
+	maxMemory := limit.Value() * int64(rc.Spec.Memory.MaxMemoryPercent) / 100
+	if maxMemory+backlog > limit.Value() {
+		maxMemory = limit.Value() - backlog
+	}
+	if maxMemory <= 0 {
+		return fmt.Sprintf("the memory limit %s is too small for repl-backlog-size %d, maxmemory is not set", limit.String(), backlog), nil
+	}
+	config["maxmemory"] = strconv.FormatInt(maxMemory, 10)
+	return "", nil
+}
+
+// parseMemoryConfig returns the bytes of a redis memory config, like 100mb
+func parseMemoryConfig(value string) (int64, error) {
+	bytes, err := util.ParseRedisMemConf(value)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(bytes, 10, 64)
+}
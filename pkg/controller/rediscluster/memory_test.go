@@ -0,0 +1,104 @@
+package rediscluster
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+)
+
+func Test_deriveMaxMemory(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         string
+		config        map[string]string
+		noMemory      bool
+		wantMaxMemory string
+		wantWarning   bool
+	}{
+		{
+			name:          "derived from the limit",
+			limit:         "1Gi",
+			config:        map[string]string{"repl-backlog-size": "62914560"},
+			wantMaxMemory: "536870912",
+		},
+		{
+			name:          "room left for the backlog",
+			limit:         "100Mi",
+			config:        map[string]string{"repl-backlog-size": "60mb"},
+			wantMaxMemory: "41943040",
+		},
+		{
+			name:        "limit smaller than the backlog",
+			limit:       "50Mi",
+			config:      map[string]string{"repl-backlog-size": "60mb"},
+			wantWarning: true,
+		},
+		{
+			name:          "explicit maxmemory",
+			limit:         "1Gi",
+			config:        map[string]string{"maxmemory": "800mb"},
+			wantMaxMemory: "800mb",
+		},
+		{
+			name:          "explicit maxmemory over the limit",
+			limit:         "1Gi",
+			config:        map[string]string{"maxmemory": "1gb", "repl-backlog-size": "60mb"},
+			wantMaxMemory: "1gb",
+			wantWarning:   true,
+		},
+		{
+			name:          "unlimited maxmemory",
+			limit:         "1Gi",
+			config:        map[string]string{"maxmemory": "0"},
+			wantMaxMemory: "0",
+			wantWarning:   true,
+		},
+		{
+			name:   "no limit",
+			config: map[string]string{},
+		},
+		{
+			name:     "not asked for",
+			limit:    "1Gi",
+			config:   map[string]string{},
+			noMemory: true,
+			// an existing cluster without spec.memory gets no maxmemory
+		},
+		{
+			name:     "over the limit without spec.memory",
+			limit:    "1Gi",
+			config:   map[string]string{"maxmemory": "2gb"},
+			noMemory: true,
+			// the overcommit is reported even if nothing is derived
+			wantMaxMemory: "2gb",
+			wantWarning:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &redisv1beta1.RedisCluster{Spec: redisv1beta1.RedisClusterSpec{
+				Config: tt.config,
+				Memory: &redisv1beta1.MemorySettings{MaxMemoryPercent: 50},
+			}}
+			if tt.noMemory {
+				rc.Spec.Memory = nil
+			}
+			if tt.limit != "" {
+				rc.Spec.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(tt.limit)}
+			}
+			warning, err := deriveMaxMemory(rc)
+			if err != nil {
+				t.Fatalf("deriveMaxMemory() error = %v", err)
+			}
+			if (warning != "") != tt.wantWarning {
+				t.Errorf("deriveMaxMemory() warning = %q, want a warning %v", warning, tt.wantWarning)
+			}
+			if got := rc.Spec.Config["maxmemory"]; got != tt.wantMaxMemory {
+				t.Errorf("deriveMaxMemory() maxmemory = %q, want %q", got, tt.wantMaxMemory)
+			}
+		})
+	}
+}
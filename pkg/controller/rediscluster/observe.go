@@ -0,0 +1,168 @@
+package rediscluster
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/client-go/tools/record"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// Pause records that the reconciliation of the cluster is paused, nothing else is done
+func (r *RedisClusterHandler) Pause(rc *redisv1beta1.RedisCluster) error {
+	conditions := rc.Status.Conditions
+	if len(conditions) > 0 && conditions[0].Type == redisv1beta1.ClusterConditionPaused {
+		return nil
+	}
+	message := fmt.Sprintf("reconciliation paused by the annotation %s", redisv1beta1.AnnotationReconcile)
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(message)
+	r.eventsCli.UpdateCluster(rc, message)
+	rc.Status.SetPausedCondition(message)
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// Observe runs the checks of CheckAndHeal without healing the cluster, the heals it would have made
+// are reported as drift in the status and as events. The spec is not applied: the cached meta is
+// left untouched, so the pending changes are applied once the cluster is reconciled again.
+func (r *RedisClusterHandler) Observe(rc *redisv1beta1.RedisCluster) error {
+	if err := rc.Validate(); err != nil {
+		return err
+	}
+	if err := r.setMaxMemory(rc); err != nil {
+		return err
+	}
+
+	// the password of a running cluster can not be changed, the validation checked it against the
+	// hash of the applied one. The status written by the older versions still has the password.
+	observed := &clustercache.Meta{
+		NameSpace: rc.Namespace,
+		Name:      rc.Name,
+		State:     clustercache.Check,
+		Obj:       rc.DeepCopy(),
+		Auth:      &util.AuthConfig{Password: rc.Spec.Password},
+	}
+	if rc.Status.LastAppliedSpec != nil && rc.Status.LastAppliedSpec.Password != "" {
+		observed.Auth.Password = rc.Status.LastAppliedSpec.Password
+		observed.Obj.Spec.Password = rc.Status.LastAppliedSpec.Password
+	}
+
+	recorder := &driftRecorder{}
+	observer := *r
+	observer.rcHealer = recorder
+	observer.k8sServices = observedServices{Services: r.k8sServices}
+	// the events of the heals are dropped, the drift is reported instead
+	observer.eventsCli = k8s.NewEvent(&record.FakeRecorder{}, r.logger)
+	observer.observing = true
+
+	err := observer.CheckAndHeal(observed)
+	message := fmt.Sprintf("%d drift found", len(recorder.drift))
+	if err != nil && err.Error() == needRequeueMsg {
+		message = "checks incomplete, waiting for the redis and sentinels"
+	} else if err != nil {
+		message = fmt.Sprintf("checks failed: %s", err)
+	}
+	return r.setDrift(rc, recorder.drift, message)
+}
+
+// setDrift records the drift of an observed cluster, an event is sent for each new one
+func (r *RedisClusterHandler) setDrift(rc *redisv1beta1.RedisCluster, drift []string, message string) error {
+	conditions := rc.Status.Conditions
+	if reflect.DeepEqual(drift, rc.Status.Drift) && len(conditions) > 0 &&
+		conditions[0].Type == redisv1beta1.ClusterConditionObserving && conditions[0].Message == message {
+		return nil
+	}
+	for _, d := range drift {
+		if !util.ContainsString(rc.Status.Drift, d) {
+			r.eventsCli.Drift(rc, d)
+		}
+	}
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("observed: %s", message), "drift", strings.Join(drift, "; "))
+	rc.Status.Drift = drift
+	rc.Status.SetObservingCondition(message)
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// observedServices drops the status updates made while a cluster is observed
+type observedServices struct {
+	k8s.Services
+}
+
+// UpdateCluster does not update the observed cluster
+func (observedServices) UpdateCluster(namespace string, cluster *redisv1beta1.RedisCluster) error {
+	return nil
+}
+
+// driftRecorder is the healer of an observed cluster, it records the heals instead of making them
+type driftRecorder struct {
+	drift []string
+}
+
+func (d *driftRecorder) record(format string, a ...interface{}) error {
+	d.drift = append(d.drift, fmt.Sprintf(format, a...))
+	return nil
+}
+
+func (d *driftRecorder) MakeMaster(ip string, auth *util.AuthConfig) error {
+	return d.record("make %s the master", ip)
+}
+
+func (d *driftRecorder) SetOldestAsMaster(rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return d.record("make the oldest redis the master")
+}
+
+func (d *driftRecorder) SetMasterOnAll(masterIP string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return d.record("make all the redis replicate from %s", masterIP)
+}
+
+func (d *driftRecorder) NewSentinelMonitor(ip string, monitor string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return d.record("make sentinel %s monitor %s", ip, monitor)
+}
+
+func (d *driftRecorder) RestoreSentinel(ip string, auth *util.AuthConfig) error {
+	return d.record("reset sentinel %s", ip)
+}
+
+func (d *driftRecorder) SetSentinelCustomConfig(ip string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return d.record("set the custom config of sentinel %s", ip)
+}
+
+func (d *driftRecorder) SetRedisCustomConfig(ip string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return d.record("set the custom config of redis %s", ip)
+}
+
+func (d *driftRecorder) SetReadReplicaCustomConfig(ip string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return d.record("set the custom config of read replica %s", ip)
+}
+
+func (d *driftRecorder) SetMasterOnReadReplicas(masterIP string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	return d.record("make the read replicas replicate from %s", masterIP)
+}
+
+func (d *driftRecorder) SetRoleLabels(masterIP string, rc *redisv1beta1.RedisCluster) error {
+	return d.record("label the redis pods with %s as the master", masterIP)
+}
+
+func (d *driftRecorder) SentinelFailover(sentinel string, auth *util.AuthConfig) error {
+	return d.record("failover through sentinel %s", sentinel)
+}
+
+func (d *driftRecorder) SetActiveReplicaPeers(ip string, peers []string, auth *util.AuthConfig) error {
+	return d.record("make %s replicate from %s", ip, strings.Join(peers, ", "))
+}
+
+func (d *driftRecorder) SetExternalMaster(ip string, host string, port string, masterAuth *util.AuthConfig, auth *util.AuthConfig) error {
+	return d.record("make %s replicate from %s:%s", ip, host, port)
+}
+
+func (d *driftRecorder) DetachExternalMaster(ip string, auth *util.AuthConfig) error {
+	return d.record("detach %s from its external master", ip)
+}
+
+func (d *driftRecorder) BgSave(ip string, auth *util.AuthConfig) error {
+	return d.record("save the data of %s", ip)
+}
@@ -0,0 +1,156 @@
+package rediscluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/service"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func Test_driftRecorder(t *testing.T) {
+	var healer service.RedisClusterHeal = &driftRecorder{}
+	healer.MakeMaster("10.0.0.1", nil)
+	healer.SetMasterOnAll("10.0.0.1", nil, nil)
+	healer.RestoreSentinel("10.0.0.9", nil)
+
+	want := []string{
+		"make 10.0.0.1 the master",
+		"make all the redis replicate from 10.0.0.1",
+		"reset sentinel 10.0.0.9",
+	}
+	if got := healer.(*driftRecorder).drift; !reflect.DeepEqual(got, want) {
+		t.Errorf("driftRecorder drift = %v, want %v", got, want)
+	}
+}
+
+func newObserveTestCluster() *redisv1beta1.RedisCluster {
+	return &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec:       redisv1beta1.RedisClusterSpec{Size: 3, Password: "secret"},
+	}
+}
+
+func TestRedisClusterHandler_Observe(t *testing.T) {
+	tests := []struct {
+		name         string
+		legacy       string
+		wantPassword string
+	}{
+		{name: "spec password", wantPassword: "secret"},
+		{name: "legacy applied password", legacy: "applied", wantPassword: "applied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newObserveTestCluster()
+			if tt.legacy != "" {
+				// the status written by the older versions has the password and no hash
+				rc.Status.LastAppliedSpec = rc.Spec.DeepCopy()
+				rc.Status.LastAppliedSpec.Password = tt.legacy
+			}
+			// a resuming cluster updates its status in CheckAndHeal, then the masters can't be counted
+			rc.Status.Suspension = &redisv1beta1.SuspensionStatus{Phase: redisv1beta1.SuspensionPhaseResuming, MasterPod: "redis-1"}
+			services := &fakeServices{pods: map[string][]corev1.Pod{
+				util.GetRedisName(rc): {newTestPod("redis-1", "10.0.0.11", "v1", true)},
+			}}
+			checker := &fakeChecker{errs: map[string]error{"GetNumberMasters ": errors.New("redis unreachable")}}
+			r, healer, recorder := newTestHandler(services, checker)
+
+			if err := r.Observe(rc); err != nil {
+				t.Fatalf("Observe() error = %v", err)
+			}
+			if len(healer.drift) != 0 {
+				t.Errorf("Observe() healed %v", healer.drift)
+			}
+			if checker.auth == nil || checker.auth.Password != tt.wantPassword {
+				t.Errorf("Observe() auth = %+v, want password %q", checker.auth, tt.wantPassword)
+			}
+			wantDrift := []string{"make all the redis replicate from 10.0.0.11"}
+			if !reflect.DeepEqual(rc.Status.Drift, wantDrift) {
+				t.Errorf("Observe() drift = %v, want %v", rc.Status.Drift, wantDrift)
+			}
+			if rc.Status.Suspension == nil {
+				t.Errorf("Observe() applied the heals to the status of the cluster")
+			}
+			if message := rc.Status.Conditions[0].Message; message != "checks failed: redis unreachable" {
+				t.Errorf("Observe() condition message = %q", message)
+			}
+			// only the drift is written, the update made in CheckAndHeal is dropped
+			if services.clusterUpdates != 1 {
+				t.Errorf("Observe() updated the cluster %d times, want 1", services.clusterUpdates)
+			}
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			if wantEvents := []string{"Warning Drift make all the redis replicate from 10.0.0.11"}; !reflect.DeepEqual(events, wantEvents) {
+				t.Errorf("Observe() events = %v, want %v", events, wantEvents)
+			}
+		})
+	}
+}
+
+func TestRedisClusterHandler_setDrift(t *testing.T) {
+	tests := []struct {
+		name        string
+		drift       []string
+		message     string
+		wantEvents  []string
+		wantUpdated bool
+	}{
+		{
+			name:    "same drift",
+			drift:   []string{"reset sentinel 10.0.0.9"},
+			message: "1 drift found",
+		},
+		{
+			name:        "new drift",
+			drift:       []string{"reset sentinel 10.0.0.9", "make 10.0.0.1 the master"},
+			message:     "2 drift found",
+			wantEvents:  []string{"Warning Drift make 10.0.0.1 the master"},
+			wantUpdated: true,
+		},
+		{
+			name:        "drift gone",
+			message:     "0 drift found",
+			wantUpdated: true,
+		},
+		{
+			name:        "same drift, new message",
+			drift:       []string{"reset sentinel 10.0.0.9"},
+			message:     "checks incomplete, waiting for the redis and sentinels",
+			wantUpdated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newObserveTestCluster()
+			rc.Status.Drift = []string{"reset sentinel 10.0.0.9"}
+			rc.Status.SetObservingCondition("1 drift found")
+			services := &fakeServices{}
+			r, _, recorder := newTestHandler(services, &fakeChecker{})
+
+			if err := r.setDrift(rc, tt.drift, tt.message); err != nil {
+				t.Fatalf("setDrift() error = %v", err)
+			}
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("setDrift() events = %v, want %v", events, tt.wantEvents)
+			}
+			if updated := services.clusterUpdates > 0; updated != tt.wantUpdated {
+				t.Errorf("setDrift() updated = %v, want %v", updated, tt.wantUpdated)
+			}
+			if tt.wantUpdated && !reflect.DeepEqual(rc.Status.Drift, tt.drift) {
+				t.Errorf("setDrift() drift = %v, want %v", rc.Status.Drift, tt.drift)
+			}
+		})
+	}
+}
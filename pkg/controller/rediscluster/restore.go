@@ -0,0 +1,121 @@
+package rediscluster
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// checkRestore records the progress of the restore in the status: the download of the RDB by the
+// init container of the first redis pod, then its loading by redis
+func (r *RedisClusterHandler) checkRestore(meta *clustercache.Meta) {
+	rc := meta.Obj
+	now := time.Now().Format(time.RFC3339)
+	status := &redisv1beta1.RestoreStatus{
+		Source:    rc.Spec.Restore.From.URL,
+		Phase:     redisv1beta1.RestorePhaseDownloading,
+		StartTime: now,
+	}
+	if rc.Status.Restore != nil {
+		status = rc.Status.Restore.DeepCopy()
+	} else if rc.Status.LastAppliedSpec != nil {
+		// the restore was added to a running cluster, its data is kept
+		status.Phase = redisv1beta1.RestorePhaseSkipped
+		status.Message = "restore ignored, the cluster already exists"
+		status.CompletionTime = now
+	}
+
+	if status.Phase != redisv1beta1.RestorePhaseSkipped {
+		pod, err := r.k8sServices.GetPod(rc.Namespace, util.GetFirstRedisPodName(rc))
+		if err == nil {
+			status.Phase, status.Message = getRestoreProgress(pod)
+			if status.Phase == redisv1beta1.RestorePhaseLoading && pod.Status.PodIP != "" {
+				loading, loaded, err := r.rcChecker.GetLoadingStatus(pod.Status.PodIP, meta.Auth)
+				if err == nil && loading {
+					status.Message = fmt.Sprintf("loading the data, %.1f%%", loaded)
+				}
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(status, rc.Status.Restore) {
+		r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("restore %s: %s", status.Phase, status.Message))
+		rc.Status.Restore = status
+		r.k8sServices.UpdateCluster(rc.Namespace, rc)
+	}
+}
+
+// setRestoredMaster makes the pod loaded from the RDB the first master, instead of the oldest pod,
+// the other redis do a full sync from it
+func (r *RedisClusterHandler) setRestoredMaster(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	pod, err := r.k8sServices.GetPod(rc.Namespace, util.GetFirstRedisPodName(rc))
+	if err != nil {
+		return err
+	}
+	if pod.Status.PodIP == "" {
+		return needRequeueErr
+	}
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("restored pod %s is the master", pod.Name))
+	r.eventsCli.UpdateCluster(rc, "set restored master")
+	if err := r.rcHealer.SetMasterOnAll(pod.Status.PodIP, rc, meta.Auth); err != nil {
+		return err
+	}
+	keys, err := r.rcChecker.GetKeyCount(pod.Status.PodIP, meta.Auth)
+	if err != nil {
+		return err
+	}
+
+	rc.Status.Restore.Phase = redisv1beta1.RestorePhaseCompleted
+	rc.Status.Restore.Keys = keys
+	rc.Status.Restore.CompletionTime = time.Now().Format(time.RFC3339)
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("restore completed with %d keys", keys))
+	r.k8sServices.UpdateCluster(rc.Namespace, rc)
+	return nil
+}
+
+// restoreSteps are the init containers of a restore in their order, with what they are doing
+var restoreSteps = []struct {
+	container string
+	running   string
+	failed    string
+}{
+	{container: util.RestoreContainerName, running: "downloading the backup", failed: "download failed"},
+	{container: util.RestoreAOFContainerName, running: "truncating the AOF", failed: "AOF truncation failed"},
+}
+
+// getRestoreProgress returns the phase of the restore from the state of the init containers downloading
+// the RDB or the AOF files, and truncating the AOF files to the point in time
+func getRestoreProgress(pod *corev1.Pod) (redisv1beta1.RestorePhase, string) {
+	phase, message := redisv1beta1.RestorePhaseDownloading, fmt.Sprintf("waiting for pod %s", pod.Name)
+	for _, step := range restoreSteps {
+		for _, cs := range pod.Status.InitContainerStatuses {
+			if cs.Name != step.container {
+				continue
+			}
+			if t := cs.State.Terminated; t != nil {
+				if t.ExitCode != 0 {
+					return redisv1beta1.RestorePhaseDownloading, fmt.Sprintf("%s: %s", step.failed, strings.TrimSpace(t.Message))
+				}
+				if m := strings.TrimSpace(t.Message); m != "" {
+					message = m
+				}
+				phase = redisv1beta1.RestorePhaseLoading
+				break
+			}
+			// the init container is restarted after a failure
+			if t := cs.LastTerminationState.Terminated; t != nil && t.ExitCode != 0 {
+				return redisv1beta1.RestorePhaseDownloading, fmt.Sprintf("%s: %s", step.failed, strings.TrimSpace(t.Message))
+			}
+			return redisv1beta1.RestorePhaseDownloading, step.running
+		}
+	}
+	return phase, message
+}
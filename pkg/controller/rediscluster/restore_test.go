@@ -0,0 +1,95 @@
+package rediscluster
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func Test_getRestoreProgress(t *testing.T) {
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	done := func(message string) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}}
+	}
+	failed := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "no such key\n"}}
+
+	tests := []struct {
+		name        string
+		statuses    []corev1.ContainerStatus
+		wantPhase   redisv1beta1.RestorePhase
+		wantMessage string
+	}{
+		{
+			name:        "not started",
+			wantPhase:   redisv1beta1.RestorePhaseDownloading,
+			wantMessage: "waiting for pod redis-cluster-test-0",
+		},
+		{
+			name:        "downloading",
+			statuses:    []corev1.ContainerStatus{{Name: util.RestoreContainerName, State: running}},
+			wantPhase:   redisv1beta1.RestorePhaseDownloading,
+			wantMessage: "downloading the backup",
+		},
+		{
+			name:        "download failed",
+			statuses:    []corev1.ContainerStatus{{Name: util.RestoreContainerName, State: failed}},
+			wantPhase:   redisv1beta1.RestorePhaseDownloading,
+			wantMessage: "download failed: no such key",
+		},
+		{
+			name:        "download restarted after a failure",
+			statuses:    []corev1.ContainerStatus{{Name: util.RestoreContainerName, State: running, LastTerminationState: failed}},
+			wantPhase:   redisv1beta1.RestorePhaseDownloading,
+			wantMessage: "download failed: no such key",
+		},
+		{
+			name:        "rdb downloaded",
+			statuses:    []corev1.ContainerStatus{{Name: util.RestoreContainerName, State: done("downloaded 42 bytes\n")}},
+			wantPhase:   redisv1beta1.RestorePhaseLoading,
+			wantMessage: "downloaded 42 bytes",
+		},
+		{
+			name: "aof truncating",
+			statuses: []corev1.ContainerStatus{
+				{Name: util.RestoreContainerName, State: done("downloaded 3 aof files")},
+				{Name: util.RestoreAOFContainerName, State: running},
+			},
+			wantPhase:   redisv1beta1.RestorePhaseDownloading,
+			wantMessage: "truncating the AOF",
+		},
+		{
+			name: "aof truncation failed",
+			statuses: []corev1.ContainerStatus{
+				{Name: util.RestoreContainerName, State: done("downloaded 3 aof files")},
+				{Name: util.RestoreAOFContainerName, State: failed},
+			},
+			wantPhase:   redisv1beta1.RestorePhaseDownloading,
+			wantMessage: "AOF truncation failed: no such key",
+		},
+		{
+			name: "aof truncated",
+			statuses: []corev1.ContainerStatus{
+				{Name: util.RestoreContainerName, State: done("downloaded 3 aof files")},
+				{Name: util.RestoreAOFContainerName, State: done("truncated at 2024-06-01T10:00:00Z")},
+			},
+			wantPhase:   redisv1beta1.RestorePhaseLoading,
+			wantMessage: "truncated at 2024-06-01T10:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster-test-0"},
+				Status:     corev1.PodStatus{InitContainerStatuses: tt.statuses},
+			}
+			phase, message := getRestoreProgress(pod)
+			if phase != tt.wantPhase || message != tt.wantMessage {
+				t.Errorf("getRestoreProgress() = %s, %q, want %s, %q", phase, message, tt.wantPhase, tt.wantMessage)
+			}
+		})
+	}
+}
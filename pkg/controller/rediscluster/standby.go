@@ -0,0 +1,113 @@
+package rediscluster
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const externalPasswordKey = "password"
+
+// setStandbyMaster makes sure one redis of a standby replicates from the external master and returns it.
+// None of the redis is a master, the replicas have the priority 0 so the sentinels never promote them,
+// when the master of the standby is lost another redis replicates from the external master instead.
+// The sentinels can't make this failover: they take a master that reports itself as a replica for down.
+// The external master is matched on its host and port.
+func (r *RedisClusterHandler) setStandbyMaster(meta *clustercache.Meta) (string, error) {
+	rc := meta.Obj
+	replicaOf := rc.Spec.ReplicaOf
+	redises, err := r.rcChecker.GetRedisesIPs(rc, meta.Auth)
+	if err != nil {
+		return "", err
+	}
+	external := net.JoinHostPort(replicaOf.Host, strconv.Itoa(int(replicaOf.Port)))
+	var linked, masters []string
+	for _, rip := range redises {
+		master, err := r.rcChecker.GetReplicaMasterAddr(rip, meta.Auth)
+		if err != nil {
+			return "", err
+		}
+		switch master {
+		case external:
+			linked = append(linked, rip)
+		case "":
+			masters = append(masters, rip)
+		}
+	}
+
+	master := getStandbyMaster(redises, linked, masters, rc.Status.MasterIP)
+	if master == "" {
+		return "", fmt.Errorf("no redis to replicate from %s", external)
+	}
+	if !util.ContainsString(linked, master) {
+		externalAuth, err := r.getExternalAuth(rc)
+		if err != nil {
+			return "", err
+		}
+		r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("redis %s replicates from the external master %s", master, external))
+		r.eventsCli.UpdateCluster(rc, "replicate from the external master")
+		port := strconv.Itoa(int(replicaOf.Port))
+		if err := r.rcHealer.SetExternalMaster(master, replicaOf.Host, port, externalAuth, meta.Auth); err != nil {
+			return "", err
+		}
+	}
+	rc.Status.ReplicaOf = external
+	return master, nil
+}
+
+// promoteStandby detaches the master of a former standby from the external master, it becomes a normal cluster
+func (r *RedisClusterHandler) promoteStandby(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("promoting the standby, detach from %s", rc.Status.ReplicaOf))
+	r.eventsCli.UpdateCluster(rc, "promote standby")
+	redises, err := r.rcChecker.GetRedisesIPs(rc, meta.Auth)
+	if err != nil {
+		return err
+	}
+	// without the last master a new one is elected
+	if util.ContainsString(redises, rc.Status.MasterIP) {
+		if err := r.rcHealer.DetachExternalMaster(rc.Status.MasterIP, meta.Auth); err != nil {
+			return err
+		}
+	}
+	rc.Status.ReplicaOf = ""
+	r.k8sServices.UpdateCluster(rc.Namespace, rc)
+	return nil
+}
+
+func (r *RedisClusterHandler) getExternalAuth(rc *redisv1beta1.RedisCluster) (*util.AuthConfig, error) {
+	if rc.Spec.ReplicaOf.AuthSecret == "" {
+		return &util.AuthConfig{}, nil
+	}
+	secret, err := r.k8sServices.GetSecret(rc.Namespace, rc.Spec.ReplicaOf.AuthSecret)
+	if err != nil {
+		return nil, err
+	}
+	password, ok := secret.Data[externalPasswordKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %s", secret.Name, externalPasswordKey)
+	}
+	return &util.AuthConfig{Password: string(password)}, nil
+}
+
+// getStandbyMaster returns the redis that replicates from the external master: one already linked, or
+// a redis promoted by hand, or any redis. The last master is kept when it is one of them.
+func getStandbyMaster(redises, linked, masters []string, last string) string {
+	candidates := redises
+	if len(linked) > 0 {
+		candidates = linked
+	} else if len(masters) > 0 {
+		candidates = masters
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	if util.ContainsString(candidates, last) {
+		return last
+	}
+	return candidates[0]
+}
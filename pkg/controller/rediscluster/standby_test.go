@@ -0,0 +1,53 @@
+package rediscluster
+
+import "testing"
+
+func Test_getStandbyMaster(t *testing.T) {
+	redises := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	tests := []struct {
+		name    string
+		linked  []string
+		masters []string
+		last    string
+		want    string
+	}{
+		{
+			name:    "linked redis",
+			linked:  []string{"10.0.0.2"},
+			masters: []string{"10.0.0.1"},
+			want:    "10.0.0.2",
+		},
+		{
+			name:   "last master kept among the linked redis",
+			linked: []string{"10.0.0.1", "10.0.0.3"},
+			last:   "10.0.0.3",
+			want:   "10.0.0.3",
+		},
+		{
+			name:    "master promoted by hand",
+			masters: []string{"10.0.0.2"},
+			last:    "10.0.0.1",
+			want:    "10.0.0.2",
+		},
+		{
+			name: "last master lost",
+			last: "10.0.0.9",
+			want: "10.0.0.1",
+		},
+		{
+			name: "last master restarted",
+			last: "10.0.0.2",
+			want: "10.0.0.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getStandbyMaster(redises, tt.linked, tt.masters, tt.last); got != tt.want {
+				t.Errorf("getStandbyMaster() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := getStandbyMaster(nil, nil, nil, ""); got != "" {
+		t.Errorf("getStandbyMaster() = %v, want no redis", got)
+	}
+}
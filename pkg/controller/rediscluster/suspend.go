@@ -0,0 +1,154 @@
+package rediscluster
+
+import (
+	"fmt"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/metrics"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// suspend saves the data of every redis and records the master, then scales the statefulsets down to zero.
+// The persistent volume claims are kept, the sentinels are not watched while the cluster is down.
+func (r *RedisClusterHandler) suspend(meta *clustercache.Meta, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	rc := meta.Obj
+	if !rc.IsSuspended() {
+		if err := r.saveBeforeSuspend(meta); err != nil {
+			return err
+		}
+	}
+	r.watcher.Stop(rc.Namespace, rc.Name)
+
+	if err := r.Ensure(withRollbackImage(rc), labels, ownerRefs); err != nil {
+		r.eventsCli.FailedCluster(rc, err.Error())
+		rc.Status.SetFailedCondition(err.Error())
+		r.k8sServices.UpdateCluster(rc.Namespace, rc)
+		return err
+	}
+
+	conditions := rc.Status.Conditions
+	if len(conditions) == 0 || conditions[0].Type != redisv1beta1.ClusterConditionSuspended || rc.Status.ObservedGeneration != rc.Generation {
+		r.eventsCli.UpdateCluster(rc, "cluster suspended")
+		rc.Status.SetSuspendedCondition(fmt.Sprintf("Cluster suspended, saved at %s", rc.Status.Suspension.SaveTime))
+		rc.Status.ObservedGeneration = rc.Generation
+		r.k8sServices.UpdateCluster(rc.Namespace, rc)
+	}
+	metrics.ClusterMetrics.SetClusterSuspended(rc.Namespace, rc.Name, true)
+	return nil
+}
+
+// saveBeforeSuspend starts a background save on every running redis and returns needRequeueErr until
+// all of them are written. The master has the newest RDB, it is recorded to be the master when resumed.
+func (r *RedisClusterHandler) saveBeforeSuspend(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	status := rc.Status.Suspension
+	if status == nil || status.Phase != redisv1beta1.SuspensionPhaseSaving {
+		status = &redisv1beta1.SuspensionStatus{
+			Phase:    redisv1beta1.SuspensionPhaseSaving,
+			SaveTime: time.Now().Format(time.RFC3339),
+		}
+		if rc.Spec.Storage.PersistentVolumeClaim == nil {
+			status.Phase = redisv1beta1.SuspensionPhaseSuspended
+			status.Message = "the redis have no persistent storage, the data is lost"
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info("suspending the cluster without persistent storage")
+			rc.Status.Suspension = status
+			return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+		}
+		// the start of the saves is recorded first, the saves written before it are not taken
+		rc.Status.Suspension = status
+		if err := r.k8sServices.UpdateCluster(rc.Namespace, rc); err != nil {
+			return err
+		}
+	}
+	start, err := time.Parse(time.RFC3339, status.SaveTime)
+	if err != nil {
+		return err
+	}
+
+	pods, err := r.k8sServices.GetStatefulSetPods(rc.Namespace, util.GetRedisName(rc))
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	saving := false
+	status.MasterPod = ""
+	if pods != nil {
+		for _, pod := range pods.Items {
+			if pod.Status.PodIP == "" || !util.IsPodReady(&pod) || pod.DeletionTimestamp != nil {
+				continue
+			}
+			save, err := r.rcChecker.GetSaveStatus(pod.Status.PodIP, meta.Auth)
+			if err != nil {
+				return err
+			}
+			if save.InProgress {
+				r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("wait for the save of %s", pod.Name))
+				saving = true
+				continue
+			}
+			if save.LastSaveTime < start.Unix() {
+				// not saved yet, or the save failed and is started again
+				if !save.LastSaveOK {
+					r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("background save failed on %s, retrying", pod.Name))
+				}
+				r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("saving %s before the suspension", pod.Name))
+				if err := r.rcHealer.BgSave(pod.Status.PodIP, meta.Auth); err != nil {
+					return err
+				}
+				saving = true
+				continue
+			}
+			if pod.Status.PodIP == rc.Status.MasterIP {
+				status.MasterPod = pod.Name
+			}
+		}
+	}
+	if saving {
+		return needRequeueErr
+	}
+	if status.MasterPod == "" {
+		status.Message = "no master was saved, a master is elected when the cluster is resumed"
+	}
+
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("suspending the cluster, master %q", status.MasterPod))
+	status.Phase = redisv1beta1.SuspensionPhaseSuspended
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// resume scales the statefulsets of a suspended cluster back up
+func (r *RedisClusterHandler) resume(rc *redisv1beta1.RedisCluster) error {
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info("resuming the cluster")
+	r.eventsCli.UpdateCluster(rc, "cluster resumed")
+	rc.Status.Suspension.Phase = redisv1beta1.SuspensionPhaseResuming
+	rc.Status.SetCreateCondition("Cluster resuming")
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// setResumedMaster makes the master saved when the cluster was suspended the master again,
+// instead of the oldest pod, the other redis do a full sync from it
+func (r *RedisClusterHandler) setResumedMaster(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	if name := rc.Status.Suspension.MasterPod; name != "" {
+		pod, err := r.k8sServices.GetPod(rc.Namespace, name)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+		// the cluster may have been scaled down while it was suspended
+		if err == nil {
+			if pod.Status.PodIP == "" {
+				return needRequeueErr
+			}
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("resumed pod %s is the master", pod.Name))
+			r.eventsCli.UpdateCluster(rc, "set resumed master")
+			if err := r.rcHealer.SetMasterOnAll(pod.Status.PodIP, rc, meta.Auth); err != nil {
+				return err
+			}
+		}
+	}
+	rc.Status.Suspension = nil
+	return r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
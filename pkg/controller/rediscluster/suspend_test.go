@@ -0,0 +1,197 @@
+package rediscluster
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func newSuspendTestCluster(suspension *redisv1beta1.SuspensionStatus) *redisv1beta1.RedisCluster {
+	rc := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: redisv1beta1.RedisClusterSpec{
+			Size:    2,
+			Storage: redisv1beta1.RedisStorage{PersistentVolumeClaim: &corev1.PersistentVolumeClaim{}},
+		},
+	}
+	rc.Status.MasterIP = "10.0.0.10"
+	rc.Status.Suspension = suspension
+	return rc
+}
+
+func Test_saveBeforeSuspend(t *testing.T) {
+	start := time.Unix(1000, 0)
+	saving := func() *redisv1beta1.SuspensionStatus {
+		return &redisv1beta1.SuspensionStatus{Phase: redisv1beta1.SuspensionPhaseSaving, SaveTime: start.Format(time.RFC3339)}
+	}
+	savedAfter := &redis.SaveStatus{LastSaveTime: 2000, LastSaveOK: true}
+	tests := []struct {
+		name        string
+		suspension  *redisv1beta1.SuspensionStatus
+		masterReady bool
+		saves       map[string]*redis.SaveStatus
+		wantErr     error
+		wantDrift   []string
+		wantPhase   redisv1beta1.SuspensionPhase
+		wantMaster  string
+		wantMessage string
+	}{
+		{
+			name:        "all saved after the start",
+			suspension:  saving(),
+			masterReady: true,
+			saves:       map[string]*redis.SaveStatus{"10.0.0.10": savedAfter, "10.0.0.11": savedAfter},
+			wantPhase:   redisv1beta1.SuspensionPhaseSuspended,
+			wantMaster:  "redis-0",
+		},
+		{
+			name:        "saved before the start",
+			suspension:  saving(),
+			masterReady: true,
+			saves: map[string]*redis.SaveStatus{
+				"10.0.0.10": {LastSaveTime: 500, LastSaveOK: true},
+				"10.0.0.11": savedAfter,
+			},
+			wantErr:   needRequeueErr,
+			wantDrift: []string{"save the data of 10.0.0.10"},
+			wantPhase: redisv1beta1.SuspensionPhaseSaving,
+		},
+		{
+			name:        "failed save is retried",
+			suspension:  saving(),
+			masterReady: true,
+			saves: map[string]*redis.SaveStatus{
+				"10.0.0.10": savedAfter,
+				"10.0.0.11": {LastSaveTime: 500, LastSaveOK: false},
+			},
+			wantErr:    needRequeueErr,
+			wantDrift:  []string{"save the data of 10.0.0.11"},
+			wantPhase:  redisv1beta1.SuspensionPhaseSaving,
+			wantMaster: "redis-0",
+		},
+		{
+			name:        "save in progress",
+			suspension:  saving(),
+			masterReady: true,
+			saves: map[string]*redis.SaveStatus{
+				"10.0.0.10": {InProgress: true, LastSaveTime: 500, LastSaveOK: true},
+				"10.0.0.11": savedAfter,
+			},
+			wantErr:   needRequeueErr,
+			wantPhase: redisv1beta1.SuspensionPhaseSaving,
+		},
+		{
+			name:        "master not saved",
+			suspension:  saving(),
+			masterReady: false,
+			saves:       map[string]*redis.SaveStatus{"10.0.0.11": savedAfter},
+			wantPhase:   redisv1beta1.SuspensionPhaseSuspended,
+			wantMessage: "no master was saved, a master is elected when the cluster is resumed",
+		},
+		{
+			name:        "new suspension saves every redis",
+			masterReady: true,
+			saves: map[string]*redis.SaveStatus{
+				"10.0.0.10": {LastSaveTime: 500, LastSaveOK: true},
+				"10.0.0.11": {LastSaveTime: 500, LastSaveOK: true},
+			},
+			wantErr:   needRequeueErr,
+			wantDrift: []string{"save the data of 10.0.0.10", "save the data of 10.0.0.11"},
+			wantPhase: redisv1beta1.SuspensionPhaseSaving,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newSuspendTestCluster(tt.suspension)
+			services := &fakeServices{pods: map[string][]corev1.Pod{
+				util.GetRedisName(rc): {
+					newTestPod("redis-0", "10.0.0.10", "v1", tt.masterReady),
+					newTestPod("redis-1", "10.0.0.11", "v1", true),
+				},
+			}}
+			r, healer, _ := newTestHandler(services, &fakeChecker{saves: tt.saves})
+			meta := &clustercache.Meta{Obj: rc, Auth: &util.AuthConfig{}}
+
+			if err := r.saveBeforeSuspend(meta); err != tt.wantErr {
+				t.Fatalf("saveBeforeSuspend() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(healer.drift, tt.wantDrift) {
+				t.Errorf("saveBeforeSuspend() saves = %v, want %v", healer.drift, tt.wantDrift)
+			}
+			status := rc.Status.Suspension
+			if status.Phase != tt.wantPhase || status.MasterPod != tt.wantMaster || status.Message != tt.wantMessage {
+				t.Errorf("saveBeforeSuspend() status = %+v, want phase %s, master %q, message %q", status, tt.wantPhase, tt.wantMaster, tt.wantMessage)
+			}
+			if tt.suspension == nil && services.clusterUpdates != 1 {
+				t.Errorf("saveBeforeSuspend() updated the cluster %d times, want the start of the saves recorded", services.clusterUpdates)
+			}
+		})
+	}
+}
+
+func Test_setResumedMaster(t *testing.T) {
+	tests := []struct {
+		name          string
+		masterPod     string
+		pods          []corev1.Pod
+		wantErr       error
+		wantDrift     []string
+		wantResumed   bool
+		wantEventSent bool
+	}{
+		{
+			name:          "saved master is the master again",
+			masterPod:     "redis-1",
+			pods:          []corev1.Pod{newTestPod("redis-0", "10.0.0.10", "v1", true), newTestPod("redis-1", "10.0.0.11", "v1", true)},
+			wantDrift:     []string{"make all the redis replicate from 10.0.0.11"},
+			wantResumed:   true,
+			wantEventSent: true,
+		},
+		{
+			name:        "saved master scaled down",
+			masterPod:   "redis-2",
+			pods:        []corev1.Pod{newTestPod("redis-0", "10.0.0.10", "v1", true)},
+			wantResumed: true,
+		},
+		{
+			name:      "saved master without ip",
+			masterPod: "redis-1",
+			pods:      []corev1.Pod{newTestPod("redis-1", "", "v1", false)},
+			wantErr:   needRequeueErr,
+		},
+		{
+			name:        "no saved master",
+			pods:        []corev1.Pod{newTestPod("redis-0", "10.0.0.10", "v1", true)},
+			wantResumed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newSuspendTestCluster(&redisv1beta1.SuspensionStatus{Phase: redisv1beta1.SuspensionPhaseResuming, MasterPod: tt.masterPod})
+			services := &fakeServices{pods: map[string][]corev1.Pod{util.GetRedisName(rc): tt.pods}}
+			r, healer, recorder := newTestHandler(services, &fakeChecker{})
+			meta := &clustercache.Meta{Obj: rc, Auth: &util.AuthConfig{}}
+
+			if err := r.setResumedMaster(meta); err != tt.wantErr {
+				t.Fatalf("setResumedMaster() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(healer.drift, tt.wantDrift) {
+				t.Errorf("setResumedMaster() heals = %v, want %v", healer.drift, tt.wantDrift)
+			}
+			if resumed := rc.Status.Suspension == nil; resumed != tt.wantResumed {
+				t.Errorf("setResumedMaster() suspension = %+v, want resumed %v", rc.Status.Suspension, tt.wantResumed)
+			}
+			if sent := len(recorder.Events) > 0; sent != tt.wantEventSent {
+				t.Errorf("setResumedMaster() event sent = %v, want %v", sent, tt.wantEventSent)
+			}
+		})
+	}
+}
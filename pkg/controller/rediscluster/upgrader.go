@@ -0,0 +1,496 @@
+package rediscluster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+// prepareUpgrade records the canary upgrade of an image change in the status,
+// or forgets it when the image is set back to the previous one.
+func (r *RedisClusterHandler) prepareUpgrade(rc *redisv1beta1.RedisCluster) {
+	up := rc.Status.Upgrade
+	switch {
+	case up != nil && rc.Spec.Image == up.PreviousImage:
+		rc.Status.Upgrade = nil
+	case up != nil && rc.Spec.Image != up.TargetImage && isCanaryUpgrade(rc):
+		// a new image while the previous upgrade is not finished, start again with a new canary
+		rc.Status.Upgrade = &redisv1beta1.UpgradeStatus{
+			PreviousImage: up.PreviousImage,
+			TargetImage:   rc.Spec.Image,
+			Phase:         redisv1beta1.UpgradePhaseCanary,
+		}
+	case up != nil && rc.Spec.Image != up.TargetImage:
+		rc.Status.Upgrade = nil
+	case up == nil && isCanaryUpgrade(rc) && rc.Status.LastAppliedSpec != nil &&
+		rc.Status.LastAppliedSpec.Image != rc.Spec.Image:
+		rc.Status.Upgrade = &redisv1beta1.UpgradeStatus{
+			PreviousImage: rc.Status.LastAppliedSpec.Image,
+			TargetImage:   rc.Spec.Image,
+			Phase:         redisv1beta1.UpgradePhaseCanary,
+		}
+	default:
+		return
+	}
+	r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+func isCanaryUpgrade(rc *redisv1beta1.RedisCluster) bool {
+	return rc.Spec.UpgradeStrategy != nil && rc.Spec.UpgradeStrategy.Canary != nil
+}
+
+// withRollbackImage returns the RedisCluster the resources are generated from,
+// it runs the previous image when the canary failed.
+func withRollbackImage(rc *redisv1beta1.RedisCluster) *redisv1beta1.RedisCluster {
+	if rc.Status.Upgrade == nil || rc.Status.Upgrade.Phase != redisv1beta1.UpgradePhaseFailed {
+		return rc
+	}
+	rollback := rc.DeepCopy()
+	rollback.Spec.Image = rc.Status.Upgrade.PreviousImage
+	return rollback
+}
+
+// upgrade restarts the redis pods, the read replicas then the sentinel pods that do not run the
+// current revision of their statefulset
+func (r *RedisClusterHandler) upgrade(meta *clustercache.Meta) error {
+	if err := r.upgradeRedis(meta); err != nil {
+		return err
+	}
+	if err := r.upgradeReadReplicas(meta); err != nil {
+		return err
+	}
+	if meta.Obj.IsActiveReplica() {
+		return nil
+	}
+	return r.upgradeSentinel(meta)
+}
+
+// upgradeRedis restarts the redis pods that do not run the current revision of the statefulset.
+// The statefulset uses the OnDelete update strategy, so the operator chooses the order:
+// the replicas are restarted one at a time, waiting for each of them to be synced with the master,
+// then the sentinels fail over to an upgraded replica and the old master is restarted last.
+func (r *RedisClusterHandler) upgradeRedis(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	ss, err := r.k8sServices.GetStatefulSet(rc.Namespace, util.GetRedisName(rc))
+	if err != nil {
+		return err
+	}
+	// the update revision is not known until the statefulset controller has seen the last spec
+	if ss.Status.ObservedGeneration != ss.Generation {
+		return needRequeueErr
+	}
+	if ss.Status.UpdateRevision == "" {
+		return nil
+	}
+	pods, err := r.k8sServices.GetStatefulSetPods(rc.Namespace, util.GetRedisName(rc))
+	if err != nil {
+		return err
+	}
+	outdated := getOutdatedPods(pods.Items, ss.Status.UpdateRevision)
+	up := rc.Status.Upgrade
+
+	if up != nil && up.Phase == redisv1beta1.UpgradePhaseFailed {
+		// restart the canary with the previous image, without waiting for it to be ready
+		for _, pod := range outdated {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("rolling back pod %s", pod.Name))
+			if err := r.k8sServices.DeletePod(rc.Namespace, pod.Name); err != nil {
+				return err
+			}
+		}
+		if len(outdated) > 0 {
+			return needRequeueErr
+		}
+		// rolled back, the failure stays in the status until the image is changed
+		return nil
+	}
+	if len(outdated) == 0 {
+		rc.Status.Upgrade = nil
+		return nil
+	}
+
+	if rc.IsActiveReplica() {
+		return r.upgradeActiveReplicas(rc, pods.Items, outdated)
+	}
+
+	// the master of a standby is a replica of the external master
+	master := rc.Status.MasterIP
+	if !rc.IsStandby() {
+		if master, err = r.rcChecker.GetMasterIP(rc, meta.Auth); err != nil {
+			return err
+		}
+	}
+	if up != nil && up.Phase == redisv1beta1.UpgradePhaseCanary {
+		return r.upgradeCanary(meta, ss.Status.UpdateRevision, pods.Items, outdated, master)
+	}
+	if err := r.waitRedisSynced(meta, pods.Items, master); err != nil {
+		return err
+	}
+
+	updated := len(pods.Items) - len(outdated)
+	r.setUpgradingCondition(rc, fmt.Sprintf("Upgrading redis pods, %d of %d updated", updated, len(pods.Items)))
+
+	// the master is restarted last
+	sortPodsByOrdinalDesc(outdated)
+	pod := outdated[0]
+	for _, p := range outdated {
+		if p.Status.PodIP != master {
+			pod = p
+			break
+		}
+	}
+	// the sentinels never promote a replica of a standby, another redis replicates from the external master
+	// once the master is restarted
+	if pod.Status.PodIP == master && updated > 0 && !rc.IsStandby() {
+		sentinels, err := r.rcChecker.GetSentinelsIPs(rc)
+		if err != nil {
+			return err
+		}
+		if len(sentinels) == 0 {
+			return errors.New("no sentinel available to fail over the master")
+		}
+		r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("failover master %s before restarting it", pod.Name))
+		if err := r.rcHealer.SentinelFailover(sentinels[0], meta.Auth); err != nil {
+			return err
+		}
+		return needRequeueErr
+	}
+
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("restarting pod %s", pod.Name))
+	if err := r.k8sServices.DeletePod(rc.Namespace, pod.Name); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// upgradeActiveReplicas restarts the outdated pods one at a time, all the nodes accept writes
+// so there is no failover, the next pod is restarted once all of them are ready
+func (r *RedisClusterHandler) upgradeActiveReplicas(rc *redisv1beta1.RedisCluster, pods, outdated []corev1.Pod) error {
+	for _, pod := range pods {
+		if !util.IsPodReady(&pod) {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("wait for redis %s ready", pod.Name))
+			return needRequeueErr
+		}
+	}
+	updated := len(pods) - len(outdated)
+	r.setUpgradingCondition(rc, fmt.Sprintf("Upgrading redis pods, %d of %d updated", updated, len(pods)))
+
+	sortPodsByOrdinalDesc(outdated)
+	pod := outdated[0]
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("restarting pod %s", pod.Name))
+	if err := r.k8sServices.DeletePod(rc.Namespace, pod.Name); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// upgradeReadReplicas restarts the outdated read replicas one at a time, once the redis are upgraded.
+// The next one is restarted only when every read replica is ready and synced with the master,
+// so the read replica service keeps serving during the upgrade.
+func (r *RedisClusterHandler) upgradeReadReplicas(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	if rc.Spec.ReadReplicas == nil || rc.Spec.ReadReplicas.Replicas == 0 {
+		return nil
+	}
+	ss, err := r.k8sServices.GetStatefulSet(rc.Namespace, util.GetReadReplicaName(rc))
+	if err != nil {
+		return err
+	}
+	if ss.Status.ObservedGeneration != ss.Generation {
+		return needRequeueErr
+	}
+	if ss.Status.UpdateRevision == "" {
+		return nil
+	}
+	pods, err := r.k8sServices.GetStatefulSetPods(rc.Namespace, util.GetReadReplicaName(rc))
+	if err != nil {
+		return err
+	}
+	outdated := getOutdatedPods(pods.Items, ss.Status.UpdateRevision)
+	if len(outdated) == 0 {
+		return nil
+	}
+	if int32(len(pods.Items)) != rc.Spec.ReadReplicas.Replicas {
+		return needRequeueErr
+	}
+	// no read replica is the master, all of them must be synced
+	if err := r.waitRedisSynced(meta, pods.Items, ""); err != nil {
+		return err
+	}
+
+	updated := len(pods.Items) - len(outdated)
+	r.setUpgradingCondition(rc, fmt.Sprintf("Upgrading read replicas, %d of %d updated", updated, len(pods.Items)))
+
+	sortPodsByOrdinalDesc(outdated)
+	pod := outdated[0]
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("restarting read replica %s", pod.Name))
+	if err := r.k8sServices.DeletePod(rc.Namespace, pod.Name); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// upgradeSentinel restarts the outdated sentinel pods one at a time. The next one is restarted only
+// when every sentinel is ready, monitors the current master and knows all its peers, so the quorum
+// is never lost for more than one sentinel.
+func (r *RedisClusterHandler) upgradeSentinel(meta *clustercache.Meta) error {
+	rc := meta.Obj
+	ss, err := r.k8sServices.GetStatefulSet(rc.Namespace, util.GetSentinelName(rc))
+	if err != nil {
+		return err
+	}
+	if ss.Status.ObservedGeneration != ss.Generation {
+		return needRequeueErr
+	}
+	if ss.Status.UpdateRevision == "" {
+		return nil
+	}
+	pods, err := r.k8sServices.GetStatefulSetPods(rc.Namespace, util.GetSentinelName(rc))
+	if err != nil {
+		return err
+	}
+	outdated := getOutdatedPods(pods.Items, ss.Status.UpdateRevision)
+	if len(outdated) == 0 {
+		return nil
+	}
+	if int32(len(pods.Items)) != rc.Spec.Sentinel.Replicas {
+		return needRequeueErr
+	}
+
+	master, err := r.rcChecker.GetMasterIP(rc, meta.Auth)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if !util.IsPodReady(&pod) {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("wait for sentinel %s ready", pod.Name))
+			return needRequeueErr
+		}
+		if err := r.rcChecker.CheckSentinelMonitor(pod.Status.PodIP, master, meta.Auth); err != nil {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("sentinel %s: %s", pod.Name, err))
+			return needRequeueErr
+		}
+		if err := r.rcChecker.CheckSentinelMasterStatus(pod.Status.PodIP, meta.Auth); err != nil {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("sentinel %s: %s", pod.Name, err))
+			return needRequeueErr
+		}
+		if err := r.rcChecker.CheckSentinelNumberInMemory(pod.Status.PodIP, rc, meta.Auth); err != nil {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("sentinel %s: %s", pod.Name, err))
+			return needRequeueErr
+		}
+	}
+
+	updated := len(pods.Items) - len(outdated)
+	r.setUpgradingCondition(rc, fmt.Sprintf("Upgrading sentinel pods, %d of %d updated", updated, len(pods.Items)))
+
+	sortPodsByOrdinalDesc(outdated)
+	pod := outdated[0]
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(fmt.Sprintf("restarting sentinel %s", pod.Name))
+	if err := r.k8sServices.DeletePod(rc.Namespace, pod.Name); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// upgradeCanary restarts a single replica with the new image, and promotes the upgrade
+// once the canary passed the health gates for the soak period
+func (r *RedisClusterHandler) upgradeCanary(meta *clustercache.Meta, revision string, pods, outdated []corev1.Pod, master string) error {
+	rc := meta.Obj
+	up := rc.Status.Upgrade
+	if !isCanaryUpgrade(rc) {
+		// the canary was disabled during the upgrade
+		up.Phase = redisv1beta1.UpgradePhasePromoted
+		r.k8sServices.UpdateCluster(rc.Namespace, rc)
+		return needRequeueErr
+	}
+	canary := rc.Spec.UpgradeStrategy.Canary
+
+	if up.CanaryPod == "" {
+		if err := r.waitRedisSynced(meta, pods, master); err != nil {
+			return err
+		}
+		sortPodsByOrdinalDesc(outdated)
+		for _, pod := range outdated {
+			if pod.Status.PodIP == master {
+				continue
+			}
+			up.CanaryPod = pod.Name
+			r.setUpgradingCondition(rc, fmt.Sprintf("Upgrading canary pod %s to %s", pod.Name, up.TargetImage))
+			if err := r.k8sServices.DeletePod(rc.Namespace, pod.Name); err != nil {
+				return err
+			}
+			return needRequeueErr
+		}
+		// only the master is outdated, there is no replica to try the image on
+		up.Phase = redisv1beta1.UpgradePhasePromoted
+		r.k8sServices.UpdateCluster(rc.Namespace, rc)
+		return needRequeueErr
+	}
+
+	var pod *corev1.Pod
+	for i := range pods {
+		if pods[i].Name == up.CanaryPod {
+			pod = &pods[i]
+		}
+	}
+	// wait for the canary to be recreated from the new revision
+	if pod == nil || pod.Labels[appsv1.StatefulSetRevisionLabel] != revision {
+		return needRequeueErr
+	}
+
+	soak := time.Duration(canary.SoakSeconds) * time.Second
+	if err := r.checkCanary(meta, pod, canary, soak); err != nil {
+		return r.failCanary(rc, err.Error())
+	}
+	if up.SoakStartTime == "" {
+		// the canary is not ready yet
+		return needRequeueErr
+	}
+	start, err := time.Parse(time.RFC3339, up.SoakStartTime)
+	if err != nil {
+		return err
+	}
+	if time.Since(start) < soak {
+		return needRequeueErr
+	}
+
+	up.Phase = redisv1beta1.UpgradePhasePromoted
+	r.setUpgradingCondition(rc, fmt.Sprintf("Canary pod %s is healthy, upgrading the other pods", pod.Name))
+	return needRequeueErr
+}
+
+// checkCanary runs the health gates of the canary: it must be ready without restarts,
+// synced with the master and must not reply more errors than allowed. The soak starts with
+// the first successful check, when the smoke command is run.
+func (r *RedisClusterHandler) checkCanary(meta *clustercache.Meta, pod *corev1.Pod, canary *redisv1beta1.CanaryUpgrade, soak time.Duration) error {
+	rc := meta.Obj
+	up := rc.Status.Upgrade
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.RestartCount > 0 {
+			return fmt.Errorf("canary pod %s container %s restarted %d times", pod.Name, cs.Name, cs.RestartCount)
+		}
+	}
+
+	ready := util.IsPodReady(pod)
+	if up.SoakStartTime == "" {
+		if ready {
+			if err := r.rcChecker.CheckReplicaSynced(pod.Status.PodIP, meta.Auth); err == nil {
+				return r.startSoak(meta, pod, canary)
+			}
+		}
+		if time.Since(pod.CreationTimestamp.Time) > soak {
+			return fmt.Errorf("canary pod %s not ready and synced after %s", pod.Name, soak)
+		}
+		return nil
+	}
+
+	if !ready {
+		return fmt.Errorf("canary pod %s is not ready", pod.Name)
+	}
+	if err := r.rcChecker.CheckReplicaSynced(pod.Status.PodIP, meta.Auth); err != nil {
+		return err
+	}
+	errs, err := r.rcChecker.GetErrorReplies(pod.Status.PodIP, meta.Auth)
+	if err != nil {
+		return err
+	}
+	if errs-up.ErrorBaseline > canary.MaxErrors {
+		return fmt.Errorf("canary pod %s replied %d errors", pod.Name, errs-up.ErrorBaseline)
+	}
+	return nil
+}
+
+func (r *RedisClusterHandler) startSoak(meta *clustercache.Meta, pod *corev1.Pod, canary *redisv1beta1.CanaryUpgrade) error {
+	rc := meta.Obj
+	if len(canary.SmokeCommand) > 0 {
+		if err := r.rcChecker.CheckSmokeCommand(pod.Status.PodIP, canary.SmokeCommand, meta.Auth); err != nil {
+			return err
+		}
+	}
+	errs, err := r.rcChecker.GetErrorReplies(pod.Status.PodIP, meta.Auth)
+	if err != nil {
+		return err
+	}
+	rc.Status.Upgrade.ErrorBaseline = errs
+	rc.Status.Upgrade.SoakStartTime = time.Now().Format(time.RFC3339)
+	r.setUpgradingCondition(rc, fmt.Sprintf("Canary pod %s soaking for %ds", pod.Name, canary.SoakSeconds))
+	return nil
+}
+
+// failCanary stops the upgrade, the next reconcile restores the previous image on the canary
+func (r *RedisClusterHandler) failCanary(rc *redisv1beta1.RedisCluster, reason string) error {
+	up := rc.Status.Upgrade
+	up.Phase = redisv1beta1.UpgradePhaseFailed
+	up.Message = reason
+	message := fmt.Sprintf("upgrade to %s failed, rolling back to %s: %s", up.TargetImage, up.PreviousImage, reason)
+	r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).Info(message)
+	r.eventsCli.FailedCluster(rc, message)
+	rc.Status.SetFailedCondition(message)
+	r.k8sServices.UpdateCluster(rc.Namespace, rc)
+	return needRequeueErr
+}
+
+// waitRedisSynced returns needRequeueErr while a redis pod is not ready or a replica is still syncing,
+// no pod is restarted until all of them are
+func (r *RedisClusterHandler) waitRedisSynced(meta *clustercache.Meta, pods []corev1.Pod, master string) error {
+	rc := meta.Obj
+	for _, pod := range pods {
+		if !util.IsPodReady(&pod) {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("wait for pod %s ready", pod.Name))
+			return needRequeueErr
+		}
+		if pod.Status.PodIP == master {
+			continue
+		}
+		if err := r.rcChecker.CheckReplicaSynced(pod.Status.PodIP, meta.Auth); err != nil {
+			r.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(err.Error())
+			return needRequeueErr
+		}
+	}
+	return nil
+}
+
+func (r *RedisClusterHandler) setUpgradingCondition(rc *redisv1beta1.RedisCluster, message string) {
+	r.eventsCli.UpgradedCluster(rc, message)
+	rc.Status.SetUpgradingCondition(message)
+	r.k8sServices.UpdateCluster(rc.Namespace, rc)
+}
+
+// getOutdatedPods returns the pods not created from the given statefulset revision
+func getOutdatedPods(pods []corev1.Pod, revision string) []corev1.Pod {
+	outdated := []corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Labels[appsv1.StatefulSetRevisionLabel] != revision {
+			outdated = append(outdated, pod)
+		}
+	}
+	return outdated
+}
+
+func sortPodsByOrdinalDesc(pods []corev1.Pod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return getPodOrdinal(pods[i].Name) > getPodOrdinal(pods[j].Name)
+	})
+}
+
+// getPodOrdinal returns the ordinal of a statefulset pod, or -1
+func getPodOrdinal(name string) int {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
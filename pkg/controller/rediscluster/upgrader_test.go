@@ -0,0 +1,460 @@
+package rediscluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/controller/clustercache"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func podNames(pods []corev1.Pod) []string {
+	names := []string{}
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func Test_getOutdatedPods(t *testing.T) {
+	pods := []corev1.Pod{
+		newTestPod("redis-0", "10.0.0.10", "v2", true),
+		newTestPod("redis-1", "10.0.0.11", "v1", true),
+		newTestPod("redis-2", "10.0.0.12", "v1", true),
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis-3"}},
+	}
+	tests := []struct {
+		name     string
+		revision string
+		want     []string
+	}{
+		{name: "some outdated", revision: "v2", want: []string{"redis-1", "redis-2", "redis-3"}},
+		{name: "new revision", revision: "v3", want: []string{"redis-0", "redis-1", "redis-2", "redis-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podNames(getOutdatedPods(pods, tt.revision)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getOutdatedPods() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getPodOrdinal(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  string
+		want int
+	}{
+		{name: "first", pod: "redis-cluster-test-0", want: 0},
+		{name: "two digits", pod: "redis-cluster-test-12", want: 12},
+		{name: "no ordinal", pod: "redis-cluster-test", want: -1},
+		{name: "no dash", pod: "redis", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getPodOrdinal(tt.pod); got != tt.want {
+				t.Errorf("getPodOrdinal(%s) = %v, want %v", tt.pod, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sortPodsByOrdinalDesc(t *testing.T) {
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis-10"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis-0"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis-1"}},
+	}
+	sortPodsByOrdinalDesc(pods)
+	want := []string{"redis-10", "redis-2", "redis-1", "redis-0"}
+	if got := podNames(pods); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortPodsByOrdinalDesc() = %v, want %v", got, want)
+	}
+}
+
+func newUpgradeTestCluster() *redisv1beta1.RedisCluster {
+	return &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec:       redisv1beta1.RedisClusterSpec{Size: 3, Image: "redis:5.0.5-alpine"},
+	}
+}
+
+func Test_upgradeRedis(t *testing.T) {
+	rc := newUpgradeTestCluster()
+	name := util.GetRedisName(rc)
+	pod := func(ordinal, revision string) corev1.Pod {
+		return newTestPod(name+"-"+ordinal, "10.0.0.1"+ordinal, revision, true)
+	}
+	tests := []struct {
+		name        string
+		standby     bool
+		pods        []corev1.Pod
+		master      string
+		errs        map[string]error
+		wantDeleted []string
+		wantDrift   []string
+		wantErr     error
+	}{
+		{
+			name:        "replicas before the master",
+			pods:        []corev1.Pod{pod("0", "v1"), pod("1", "v1"), pod("2", "v1")},
+			master:      "10.0.0.12",
+			wantDeleted: []string{name + "-1"},
+			wantErr:     needRequeueErr,
+		},
+		{
+			name:      "failover before restarting the master",
+			pods:      []corev1.Pod{pod("0", "v2"), pod("1", "v2"), pod("2", "v1")},
+			master:    "10.0.0.12",
+			wantDrift: []string{"failover through sentinel 10.0.0.20"},
+			wantErr:   needRequeueErr,
+		},
+		{
+			name:        "no failover without an upgraded replica",
+			pods:        []corev1.Pod{pod("0", "v1")},
+			master:      "10.0.0.10",
+			wantDeleted: []string{name + "-0"},
+			wantErr:     needRequeueErr,
+		},
+		{
+			name:        "standby restarts its master without failover",
+			standby:     true,
+			pods:        []corev1.Pod{pod("0", "v2"), pod("1", "v2"), pod("2", "v1")},
+			master:      "10.0.0.12",
+			wantDeleted: []string{name + "-2"},
+			wantErr:     needRequeueErr,
+		},
+		{
+			name:    "wait for a syncing replica",
+			pods:    []corev1.Pod{pod("0", "v1"), pod("1", "v1"), pod("2", "v1")},
+			master:  "10.0.0.12",
+			errs:    map[string]error{"CheckReplicaSynced 10.0.0.10": errors.New("syncing")},
+			wantErr: needRequeueErr,
+		},
+		{
+			name:   "upgraded",
+			pods:   []corev1.Pod{pod("0", "v2"), pod("1", "v2"), pod("2", "v2")},
+			master: "10.0.0.12",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newUpgradeTestCluster()
+			if tt.standby {
+				rc.Spec.ReplicaOf = &redisv1beta1.ReplicaOfSpec{}
+				rc.Status.MasterIP = tt.master
+			}
+			services := &fakeServices{
+				statefulSets: map[string]*appsv1.StatefulSet{name: newTestStatefulSet(name, "v2")},
+				pods:         map[string][]corev1.Pod{name: tt.pods},
+			}
+			checker := &fakeChecker{master: tt.master, sentinels: []string{"10.0.0.20"}, errs: tt.errs}
+			if tt.standby {
+				// the master of a standby comes from the status
+				checker.errs = map[string]error{"GetMasterIP ": errors.New("no master")}
+			}
+			r, healer, _ := newTestHandler(services, checker)
+
+			err := r.upgradeRedis(&clustercache.Meta{Obj: rc, Auth: &util.AuthConfig{}})
+			if err != tt.wantErr {
+				t.Errorf("upgradeRedis() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(services.deleted, tt.wantDeleted) {
+				t.Errorf("upgradeRedis() deleted %v, want %v", services.deleted, tt.wantDeleted)
+			}
+			if !reflect.DeepEqual(healer.drift, tt.wantDrift) {
+				t.Errorf("upgradeRedis() heals %v, want %v", healer.drift, tt.wantDrift)
+			}
+		})
+	}
+}
+
+func Test_prepareUpgrade(t *testing.T) {
+	canary := &redisv1beta1.UpgradeStrategy{Canary: &redisv1beta1.CanaryUpgrade{SoakSeconds: 60}}
+	tests := []struct {
+		name        string
+		image       string
+		strategy    *redisv1beta1.UpgradeStrategy
+		lastApplied string
+		upgrade     *redisv1beta1.UpgradeStatus
+		want        *redisv1beta1.UpgradeStatus
+		wantUpdate  bool
+	}{
+		{
+			name:       "set back to the previous image",
+			image:      "redis:5.0.4",
+			strategy:   canary,
+			upgrade:    &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhaseFailed},
+			wantUpdate: true,
+		},
+		{
+			name:       "new image during a canary",
+			image:      "redis:5.0.6",
+			strategy:   canary,
+			upgrade:    &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", CanaryPod: "redis-2", Phase: redisv1beta1.UpgradePhaseCanary},
+			want:       &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.6", Phase: redisv1beta1.UpgradePhaseCanary},
+			wantUpdate: true,
+		},
+		{
+			name:       "new image without a canary",
+			image:      "redis:5.0.6",
+			upgrade:    &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhasePromoted},
+			wantUpdate: true,
+		},
+		{
+			name:        "new canary",
+			image:       "redis:5.0.5",
+			strategy:    canary,
+			lastApplied: "redis:5.0.4",
+			want:        &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhaseCanary},
+			wantUpdate:  true,
+		},
+		{
+			name:        "failed canary keeps the previous image",
+			image:       "redis:5.0.5",
+			strategy:    canary,
+			lastApplied: "redis:5.0.5",
+			upgrade:     &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhaseFailed},
+			want:        &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhaseFailed},
+		},
+		{
+			name:        "same image",
+			image:       "redis:5.0.4",
+			strategy:    canary,
+			lastApplied: "redis:5.0.4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newUpgradeTestCluster()
+			rc.Spec.Image = tt.image
+			rc.Spec.UpgradeStrategy = tt.strategy
+			rc.Status.Upgrade = tt.upgrade
+			if tt.lastApplied != "" {
+				rc.Status.LastAppliedSpec = &redisv1beta1.RedisClusterSpec{Image: tt.lastApplied}
+			}
+			services := &fakeServices{}
+			r, _, _ := newTestHandler(services, &fakeChecker{})
+
+			r.prepareUpgrade(rc)
+			if !reflect.DeepEqual(rc.Status.Upgrade, tt.want) {
+				t.Errorf("prepareUpgrade() upgrade = %+v, want %+v", rc.Status.Upgrade, tt.want)
+			}
+			if got := services.clusterUpdates > 0; got != tt.wantUpdate {
+				t.Errorf("prepareUpgrade() updated the status = %v, want %v", got, tt.wantUpdate)
+			}
+		})
+	}
+}
+
+func Test_withRollbackImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		upgrade *redisv1beta1.UpgradeStatus
+		want    string
+	}{
+		{name: "no upgrade", want: "redis:5.0.5"},
+		{
+			name:    "canary",
+			upgrade: &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhaseCanary},
+			want:    "redis:5.0.5",
+		},
+		{
+			name:    "failed canary",
+			upgrade: &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhaseFailed},
+			want:    "redis:5.0.4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newUpgradeTestCluster()
+			rc.Spec.Image = "redis:5.0.5"
+			rc.Status.Upgrade = tt.upgrade
+			if got := withRollbackImage(rc).Spec.Image; got != tt.want {
+				t.Errorf("withRollbackImage() image = %v, want %v", got, tt.want)
+			}
+			if rc.Spec.Image != "redis:5.0.5" {
+				t.Errorf("withRollbackImage() changed the spec of the cluster to %v", rc.Spec.Image)
+			}
+		})
+	}
+}
+
+func Test_checkCanary(t *testing.T) {
+	canary := &redisv1beta1.CanaryUpgrade{SoakSeconds: 60, MaxErrors: 5}
+	soak := 60 * time.Second
+	tests := []struct {
+		name       string
+		ready      bool
+		restarts   int32
+		age        time.Duration
+		soaking    bool
+		errs       map[string]error
+		errReplies int64
+		wantErr    bool
+		wantSoak   bool
+	}{
+		{name: "restarted", ready: true, restarts: 1, wantErr: true},
+		{name: "ready and synced starts the soak", ready: true, errReplies: 3, wantSoak: true},
+		{name: "not ready yet", age: 10 * time.Second},
+		{name: "not ready after the soak period", age: 2 * soak, wantErr: true},
+		{
+			name:    "syncing after the soak period",
+			ready:   true,
+			age:     2 * soak,
+			errs:    map[string]error{"CheckReplicaSynced 10.0.0.12": errors.New("syncing")},
+			wantErr: true,
+		},
+		{
+			name:    "smoke command failed",
+			ready:   true,
+			errs:    map[string]error{"CheckSmokeCommand 10.0.0.12": errors.New("ERR unknown command")},
+			wantErr: true,
+		},
+		{name: "not ready while soaking", soaking: true, wantErr: true, wantSoak: true},
+		{name: "errors while soaking", ready: true, soaking: true, errReplies: 9, wantErr: true, wantSoak: true},
+		{name: "healthy while soaking", ready: true, soaking: true, errReplies: 7, wantSoak: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newUpgradeTestCluster()
+			rc.Status.Upgrade = &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", Phase: redisv1beta1.UpgradePhaseCanary}
+			if tt.soaking {
+				rc.Status.Upgrade.SoakStartTime = time.Now().Format(time.RFC3339)
+				rc.Status.Upgrade.ErrorBaseline = 3
+			}
+			pod := newTestPod("redis-cluster-test-2", "10.0.0.12", "v2", tt.ready)
+			pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-tt.age))
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "redis", RestartCount: tt.restarts}}
+			checker := &fakeChecker{errs: tt.errs, errReplies: map[string]int64{"10.0.0.12": tt.errReplies}}
+			r, _, _ := newTestHandler(&fakeServices{}, checker)
+
+			canary := canary.DeepCopy()
+			canary.SmokeCommand = []string{"PING"}
+			err := r.checkCanary(&clustercache.Meta{Obj: rc, Auth: &util.AuthConfig{}}, &pod, canary, soak)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkCanary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := rc.Status.Upgrade.SoakStartTime != ""; got != tt.wantSoak {
+				t.Errorf("checkCanary() soaking = %v, want %v", got, tt.wantSoak)
+			}
+			if tt.wantSoak && !tt.soaking && rc.Status.Upgrade.ErrorBaseline != tt.errReplies {
+				t.Errorf("checkCanary() error baseline = %v, want %v", rc.Status.Upgrade.ErrorBaseline, tt.errReplies)
+			}
+		})
+	}
+}
+
+func Test_failCanary(t *testing.T) {
+	rc := newUpgradeTestCluster()
+	rc.Status.Upgrade = &redisv1beta1.UpgradeStatus{PreviousImage: "redis:5.0.4", TargetImage: "redis:5.0.5", CanaryPod: "redis-cluster-test-2", Phase: redisv1beta1.UpgradePhaseCanary}
+	services := &fakeServices{}
+	r, _, recorder := newTestHandler(services, &fakeChecker{})
+
+	if err := r.failCanary(rc, "canary pod redis-cluster-test-2 is not ready"); err != needRequeueErr {
+		t.Errorf("failCanary() error = %v, want %v", err, needRequeueErr)
+	}
+	up := rc.Status.Upgrade
+	if up.Phase != redisv1beta1.UpgradePhaseFailed || up.PreviousImage != "redis:5.0.4" || up.Message != "canary pod redis-cluster-test-2 is not ready" {
+		t.Errorf("failCanary() upgrade = %+v", up)
+	}
+	if rc.Status.Conditions[0].Type != redisv1beta1.ClusterConditionFailed || services.clusterUpdates != 1 {
+		t.Errorf("failCanary() conditions = %+v, %d status updates", rc.Status.Conditions, services.clusterUpdates)
+	}
+	want := "Warning Failed upgrade to redis:5.0.5 failed, rolling back to redis:5.0.4: canary pod redis-cluster-test-2 is not ready"
+	if event := <-recorder.Events; event != want {
+		t.Errorf("failCanary() event = %q, want %q", event, want)
+	}
+	if got := withRollbackImage(rc).Spec.Image; got != "redis:5.0.4" {
+		t.Errorf("withRollbackImage() after failCanary = %v, want redis:5.0.4", got)
+	}
+}
+
+func Test_upgradeSentinel(t *testing.T) {
+	rc := newUpgradeTestCluster()
+	name := util.GetSentinelName(rc)
+	pod := func(ordinal, revision string, ready bool) corev1.Pod {
+		return newTestPod(name+"-"+ordinal, "10.0.0.2"+ordinal, revision, ready)
+	}
+	outdated := []corev1.Pod{pod("0", "v1", true), pod("1", "v1", true), pod("2", "v1", true)}
+	tests := []struct {
+		name        string
+		pods        []corev1.Pod
+		errs        map[string]error
+		wantDeleted []string
+		wantErr     error
+	}{
+		{
+			name:        "quorum kept",
+			pods:        outdated,
+			wantDeleted: []string{name + "-2"},
+			wantErr:     needRequeueErr,
+		},
+		{
+			name:        "next sentinel",
+			pods:        []corev1.Pod{pod("0", "v1", true), pod("1", "v1", true), pod("2", "v2", true)},
+			wantDeleted: []string{name + "-1"},
+			wantErr:     needRequeueErr,
+		},
+		{
+			name:    "sentinel not ready",
+			pods:    []corev1.Pod{pod("0", "v1", true), pod("1", "v1", true), pod("2", "v2", false)},
+			wantErr: needRequeueErr,
+		},
+		{
+			name:    "sentinel missing",
+			pods:    []corev1.Pod{pod("0", "v1", true), pod("1", "v1", true)},
+			wantErr: needRequeueErr,
+		},
+		{
+			name:    "monitors another master",
+			pods:    outdated,
+			errs:    map[string]error{"CheckSentinelMonitor 10.0.0.21": errors.New("monitor mismatch")},
+			wantErr: needRequeueErr,
+		},
+		{
+			name:    "master down",
+			pods:    outdated,
+			errs:    map[string]error{"CheckSentinelMasterStatus 10.0.0.20": errors.New("the sentinel flags the master as down")},
+			wantErr: needRequeueErr,
+		},
+		{
+			name:    "peer missing",
+			pods:    outdated,
+			errs:    map[string]error{"CheckSentinelNumberInMemory 10.0.0.22": errors.New("sentinels in memory mismatch")},
+			wantErr: needRequeueErr,
+		},
+		{
+			name: "upgraded",
+			pods: []corev1.Pod{pod("0", "v2", true), pod("1", "v2", true), pod("2", "v2", true)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newUpgradeTestCluster()
+			rc.Spec.Sentinel.Replicas = 3
+			services := &fakeServices{
+				statefulSets: map[string]*appsv1.StatefulSet{name: newTestStatefulSet(name, "v2")},
+				pods:         map[string][]corev1.Pod{name: tt.pods},
+			}
+			r, _, _ := newTestHandler(services, &fakeChecker{master: "10.0.0.10", errs: tt.errs})
+
+			err := r.upgradeSentinel(&clustercache.Meta{Obj: rc, Auth: &util.AuthConfig{}})
+			if err != tt.wantErr {
+				t.Errorf("upgradeSentinel() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(services.deleted, tt.wantDeleted) {
+				t.Errorf("upgradeSentinel() deleted %v, want %v", services.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package redisfailover
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const requeueTime = 10 * time.Second
+
+var (
+	controllerFlagSet *pflag.FlagSet
+	// importEnabled starts the controller importing the annotated RedisFailovers, their CRD must be installed
+	importEnabled bool
+
+	log = logf.Log.WithName("controller_redisfailover")
+)
+
+func init() {
+	controllerFlagSet = pflag.NewFlagSet("redisfailover", pflag.ExitOnError)
+	controllerFlagSet.BoolVar(&importEnabled, "import-redisfailover", false, "import the spotahome RedisFailovers annotated with redis.kun/import=true")
+}
+
+func FlagSet() *pflag.FlagSet {
+	return controllerFlagSet
+}
+
+// Add creates a new RedisFailover import Controller and adds it to the Manager when the import is enabled.
+// The Manager will set fields on the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	if !importEnabled {
+		return nil
+	}
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	handler := &RedisFailoverHandler{
+		k8sServices: k8s.New(mgr.GetClient(), log),
+		redisClient: redis.New(),
+		logger:      log,
+	}
+	return &ReconcileRedisFailover{handler: handler}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("redisfailover-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	pred := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return util.ShouldManage(e.MetaNew) && e.MetaNew.GetAnnotations()[AnnotationImport] == "true"
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return util.ShouldManage(e.Meta) && e.Meta.GetAnnotations()[AnnotationImport] == "true"
+		},
+	}
+
+	// Watch the RedisFailovers of the spotahome redis-operator
+	rf := &unstructured.Unstructured{}
+	rf.SetGroupVersionKind(k8s.RedisFailoverGVK)
+	return c.Watch(&source.Kind{Type: rf}, &handler.EnqueueRequestForObject{}, pred)
+}
+
+var _ reconcile.Reconciler = &ReconcileRedisFailover{}
+
+// ReconcileRedisFailover imports a RedisFailover
+type ReconcileRedisFailover struct {
+	handler *RedisFailoverHandler
+}
+
+// Reconcile moves the import of the RedisFailover to its next step, it is requeued until the data is imported.
+// Once the RedisFailover is deleted its remaining objects are cleaned up.
+func (r *ReconcileRedisFailover) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.V(3).Info("Reconciling RedisFailover")
+
+	instance, err := r.handler.k8sServices.GetRedisFailover(request.Namespace, request.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// the import goes on after the RedisFailover is deleted, and a failed cleanup is retried
+			if err := r.handler.Cleanup(request.Namespace, request.Name); err != nil {
+				if err == needRequeueErr {
+					return reconcile.Result{RequeueAfter: requeueTime}, nil
+				}
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !util.ShouldManage(instance) || instance.GetAnnotations()[AnnotationImport] != "true" {
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.handler.Do(instance); err != nil {
+		if err == needRequeueErr {
+			return reconcile.Result{RequeueAfter: requeueTime}, nil
+		}
+		reqLogger.Error(err, "Reconcile handler")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,432 @@
+package redisfailover
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const (
+	// AnnotationImport opts a RedisFailover in the import
+	AnnotationImport = "redis.kun/import"
+	// AnnotationImportedFrom is set on the RedisCluster created from a RedisFailover
+	AnnotationImportedFrom = "redis.kun/imported-from"
+	// LabelRedisFailoverKey links the migration of the import to its RedisFailover
+	LabelRedisFailoverKey = "redis.kun/redisfailover"
+
+	importMigrationSuffix = "-import"
+	passwordKey           = "password"
+	redisPort             = 6379
+)
+
+var needRequeueErr = errors.New("need requeue")
+
+// redisFailoverSpec is the part of the spec of a spotahome RedisFailover that is imported
+type redisFailoverSpec struct {
+	Redis    redisFailoverSettings `json:"redis,omitempty"`
+	Sentinel redisFailoverSettings `json:"sentinel,omitempty"`
+	Auth     struct {
+		SecretPath string `json:"secretPath,omitempty"`
+	} `json:"auth,omitempty"`
+}
+
+type redisFailoverSettings struct {
+	Image           string                      `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy           `json:"imagePullPolicy,omitempty"`
+	Replicas        int32                       `json:"replicas,omitempty"`
+	Resources       corev1.ResourceRequirements `json:"resources,omitempty"`
+	CustomConfig    []string                    `json:"customConfig,omitempty"`
+	Command         []string                    `json:"command,omitempty"`
+	Storage         redisv1beta1.RedisStorage   `json:"storage,omitempty"`
+	Exporter        redisv1beta1.RedisExporter  `json:"exporter,omitempty"`
+	Affinity        *corev1.Affinity            `json:"affinity,omitempty"`
+	SecurityContext *corev1.PodSecurityContext  `json:"securityContext,omitempty"`
+	Tolerations     []corev1.Toleration         `json:"tolerations,omitempty"`
+	NodeSelector    map[string]string           `json:"nodeSelector,omitempty"`
+	PodAnnotations  map[string]string           `json:"podAnnotations,omitempty"`
+}
+
+// RedisFailoverHandler imports a spotahome RedisFailover: an equivalent RedisCluster replicates from its master
+// through a RedisMigration, the RedisFailover is deleted once the cluster is synced so its services can select
+// the new pods, then its other objects are deleted.
+type RedisFailoverHandler struct {
+	k8sServices k8s.Services
+	redisClient redis.Client
+	logger      logr.Logger
+}
+
+// Do moves the import of the RedisFailover to its next step
+func (r *RedisFailoverHandler) Do(rf *unstructured.Unstructured) error {
+	logger := r.logger.WithValues("namespace", rf.GetNamespace(), "name", rf.GetName())
+	m, err := r.k8sServices.GetMigration(rf.GetNamespace(), getImportMigrationName(rf.GetName()))
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return r.start(rf)
+		}
+		return err
+	}
+
+	switch m.Status.Phase {
+	case redisv1beta1.MigrationPhaseSyncing:
+		if !m.Spec.Cutover {
+			return r.orphanOnceSynced(rf, m)
+		}
+	case redisv1beta1.MigrationPhaseCompleted:
+		logger.Info("data imported, deleting the RedisFailover")
+		if err := r.k8sServices.OrphanRedisFailover(rf.GetNamespace(), rf.GetName()); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+		return r.Cleanup(rf.GetNamespace(), rf.GetName())
+	case redisv1beta1.MigrationPhaseFailed, redisv1beta1.MigrationPhaseRolledBack:
+		logger.Info(fmt.Sprintf("import stopped, migration %s is %s: %s", m.Name, m.Status.Phase, m.Status.Message))
+		return nil
+	}
+	logger.V(2).Info(fmt.Sprintf("migration %s is %s, lag %d", m.Name, m.Status.Phase, m.Status.Lag))
+	return needRequeueErr
+}
+
+// start creates the RedisCluster as a standby of the master of the RedisFailover, and the migration that
+// promotes it once it is synced
+func (r *RedisFailoverHandler) start(rf *unstructured.Unstructured) error {
+	logger := r.logger.WithValues("namespace", rf.GetNamespace(), "name", rf.GetName())
+	spec := &redisFailoverSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(getSpec(rf), spec); err != nil {
+		return err
+	}
+
+	rc, err := r.k8sServices.GetCluster(rf.GetNamespace(), rf.GetName())
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	if rc != nil && rc.Annotations[AnnotationImportedFrom] != rf.GetName() {
+		logger.Info(fmt.Sprintf("import skipped, redis cluster %s already exists", rc.Name))
+		return nil
+	}
+
+	// the sentinels of the RedisFailover know its master
+	svc, err := r.k8sServices.GetService(rf.GetNamespace(), getSentinelServiceName(rf.GetName()))
+	if err != nil {
+		return err
+	}
+	master, err := r.redisClient.GetSentinelMonitor(svc.Spec.ClusterIP, &util.AuthConfig{})
+	if err != nil {
+		return err
+	}
+	source := redisv1beta1.ReplicaOfSpec{Host: master, Port: redisPort, AuthSecret: spec.Auth.SecretPath}
+
+	if rc == nil {
+		password, err := r.getPassword(rf.GetNamespace(), spec.Auth.SecretPath)
+		if err != nil {
+			return err
+		}
+		rc = generateRedisCluster(rf, spec, source, password)
+		if err := r.k8sServices.CreateCluster(rf.GetNamespace(), rc); err != nil && !kerrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	logger.Info(fmt.Sprintf("importing the data of master %s into redis cluster %s", master, rc.Name))
+	if err := r.k8sServices.CreateMigration(rf.GetNamespace(), generateMigration(rf, source)); err != nil && !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	return needRequeueErr
+}
+
+// orphanOnceSynced deletes the RedisFailover once the RedisCluster is synced, keeping the objects it owns.
+// The spotahome operator would put back the selectors of the services of a RedisFailover it still reconciles,
+// the cutover is started by Cleanup once the RedisFailover is gone.
+func (r *RedisFailoverHandler) orphanOnceSynced(rf *unstructured.Unstructured, m *redisv1beta1.RedisMigration) error {
+	logger := r.logger.WithValues("namespace", rf.GetNamespace(), "name", rf.GetName())
+	rc, err := r.getSyncedCluster(rf.GetNamespace(), m)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("redis cluster %s is synced, deleting the RedisFailover before the cutover", rc.Name))
+	if err := r.k8sServices.OrphanRedisFailover(rf.GetNamespace(), rf.GetName()); err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return needRequeueErr
+}
+
+// startCutover makes the sentinel and replica services of the deleted RedisFailover select the pods of the
+// RedisCluster, then starts the cutover of the migration. The writes are refused from then on until the cluster
+// is promoted: the standby replies READONLY, and the source is made read-only by the cutover. The master service
+// keeps selecting the source until the migration is completed, it would select a read-only standby otherwise.
+func (r *RedisFailoverHandler) startCutover(namespace string, name string, m *redisv1beta1.RedisMigration) error {
+	logger := r.logger.WithValues("namespace", namespace, "name", name)
+	rc, err := r.getSyncedCluster(namespace, m)
+	if err != nil {
+		return err
+	}
+	for old, svc := range map[string]string{
+		getSentinelServiceName(name): util.GetSentinelName(rc),
+		getReplicaServiceName(name):  util.GetRedisReplicaName(rc),
+	} {
+		if err := r.repointService(namespace, old, svc); err != nil {
+			return err
+		}
+	}
+	logger.Info(fmt.Sprintf("services select redis cluster %s, starting the cutover", rc.Name))
+	m.Spec.Cutover = true
+	if err := r.k8sServices.UpdateMigration(m.Namespace, m); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// getSyncedCluster returns the RedisCluster of the migration, it is requeued until the cluster is synced
+// with the master of the RedisFailover
+func (r *RedisFailoverHandler) getSyncedCluster(namespace string, m *redisv1beta1.RedisMigration) (*redisv1beta1.RedisCluster, error) {
+	rc, err := r.k8sServices.GetCluster(namespace, m.Spec.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+	if rc.Status.ReplicaOf == "" || rc.Status.MasterIP == "" {
+		return nil, needRequeueErr
+	}
+	synced, err := r.redisClient.IsReplicaSynced(rc.Status.MasterIP, &util.AuthConfig{Password: rc.Spec.Password})
+	if err != nil {
+		return nil, err
+	}
+	if !synced {
+		return nil, needRequeueErr
+	}
+	return rc, nil
+}
+
+// Cleanup moves the import of a deleted RedisFailover to its next step: it starts the cutover once the
+// RedisCluster is synced, and once the import is completed it makes the services of the RedisFailover select
+// the pods of the RedisCluster, so the clients keep their addresses, and deletes its other objects.
+func (r *RedisFailoverHandler) Cleanup(namespace string, name string) error {
+	m, err := r.k8sServices.GetMigration(namespace, getImportMigrationName(name))
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if m.Labels[LabelRedisFailoverKey] != name {
+		return nil
+	}
+	switch m.Status.Phase {
+	case redisv1beta1.MigrationPhaseFailed, redisv1beta1.MigrationPhaseRolledBack:
+		return nil
+	case redisv1beta1.MigrationPhaseSyncing:
+		if !m.Spec.Cutover {
+			return r.startCutover(namespace, name, m)
+		}
+	}
+	if m.Status.Phase != redisv1beta1.MigrationPhaseCompleted {
+		return needRequeueErr
+	}
+	rc, err := r.k8sServices.GetCluster(namespace, m.Spec.ClusterName)
+	if err != nil {
+		return err
+	}
+	if err := r.repointServices(namespace, name, rc); err != nil {
+		return err
+	}
+
+	redisName, sentinelName := getRedisName(name), getSentinelServiceName(name)
+	for _, del := range []func() error{
+		func() error { return r.k8sServices.DeleteStatefulSet(namespace, redisName) },
+		func() error { return r.k8sServices.DeleteDeployment(namespace, sentinelName) },
+		func() error { return r.k8sServices.DeleteService(namespace, redisName) },
+		func() error { return r.k8sServices.DeletePodDisruptionBudget(namespace, redisName) },
+		func() error { return r.k8sServices.DeletePodDisruptionBudget(namespace, sentinelName) },
+		func() error { return r.k8sServices.DeleteConfigMap(namespace, redisName) },
+		func() error { return r.k8sServices.DeleteConfigMap(namespace, sentinelName) },
+		func() error { return r.k8sServices.DeleteConfigMap(namespace, getShutdownConfigMapName(name)) },
+		func() error { return r.k8sServices.DeleteConfigMap(namespace, getReadinessConfigMapName(name)) },
+	} {
+		if err := del(); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// repointServices makes the services of the RedisFailover select the sentinels, the master and the replicas of the RedisCluster
+func (r *RedisFailoverHandler) repointServices(namespace string, name string, rc *redisv1beta1.RedisCluster) error {
+	for old, svc := range map[string]string{
+		getSentinelServiceName(name): util.GetSentinelName(rc),
+		getMasterServiceName(name):   util.GetRedisMasterName(rc),
+		getReplicaServiceName(name):  util.GetRedisReplicaName(rc),
+	} {
+		if err := r.repointService(namespace, old, svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repointService makes the service of the RedisFailover select the pods of the service of the RedisCluster
+func (r *RedisFailoverHandler) repointService(namespace string, name string, target string) error {
+	svc, err := r.k8sServices.GetService(namespace, name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	targetSvc, err := r.k8sServices.GetService(namespace, target)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(svc.Spec.Selector, targetSvc.Spec.Selector) && len(svc.OwnerReferences) == 0 {
+		return nil
+	}
+	r.logger.WithValues("namespace", namespace, "name", name).Info(fmt.Sprintf("service %s selects the pods of %s", name, target))
+	svc.Spec.Selector = targetSvc.Spec.Selector
+	svc.OwnerReferences = nil
+	return r.k8sServices.UpdateService(namespace, svc)
+}
+
+func (r *RedisFailoverHandler) getPassword(namespace string, secretName string) (string, error) {
+	if secretName == "" {
+		return "", nil
+	}
+	secret, err := r.k8sServices.GetSecret(namespace, secretName)
+	if err != nil {
+		return "", err
+	}
+	password, ok := secret.Data[passwordKey]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", secret.Name, passwordKey)
+	}
+	return string(password), nil
+}
+
+func getSpec(rf *unstructured.Unstructured) map[string]interface{} {
+	spec, ok := rf.Object["spec"].(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+	return spec
+}
+
+// generateRedisCluster returns the RedisCluster equivalent to the RedisFailover, as a standby of its master
+func generateRedisCluster(rf *unstructured.Unstructured, spec *redisFailoverSpec, source redisv1beta1.ReplicaOfSpec, password string) *redisv1beta1.RedisCluster {
+	annotations := map[string]string{AnnotationImportedFrom: rf.GetName()}
+	if v, ok := rf.GetAnnotations()[util.AnnotationScope]; ok {
+		annotations[util.AnnotationScope] = v
+	}
+	return &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        rf.GetName(),
+			Namespace:   rf.GetNamespace(),
+			Labels:      rf.GetLabels(),
+			Annotations: annotations,
+		},
+		Spec: redisv1beta1.RedisClusterSpec{
+			Size:            spec.Redis.Replicas,
+			Image:           spec.Redis.Image,
+			ImagePullPolicy: spec.Redis.ImagePullPolicy,
+			Resources:       spec.Redis.Resources,
+			Command:         spec.Redis.Command,
+			Storage:         spec.Redis.Storage,
+			Password:        password,
+			Exporter:        spec.Redis.Exporter,
+			Affinity:        spec.Redis.Affinity,
+			SecurityContext: spec.Redis.SecurityContext,
+			ToleRations:     spec.Redis.Tolerations,
+			NodeSelector:    spec.Redis.NodeSelector,
+			Config:          parseCustomConfig(spec.Redis.CustomConfig),
+			Annotations:     spec.Redis.PodAnnotations,
+			Sentinel: redisv1beta1.SentinelSettings{
+				Image:           spec.Sentinel.Image,
+				ImagePullPolicy: spec.Sentinel.ImagePullPolicy,
+				Replicas:        spec.Sentinel.Replicas,
+				Resources:       spec.Sentinel.Resources,
+				CustomConfig:    spec.Sentinel.CustomConfig,
+				Command:         spec.Sentinel.Command,
+				Affinity:        spec.Sentinel.Affinity,
+				SecurityContext: spec.Sentinel.SecurityContext,
+				ToleRations:     spec.Sentinel.Tolerations,
+				NodeSelector:    spec.Sentinel.NodeSelector,
+				Annotations:     spec.Sentinel.PodAnnotations,
+			},
+			ReplicaOf: source.DeepCopy(),
+		},
+	}
+}
+
+// generateMigration returns the migration of the import, the cutover is started by the import once the cluster is synced
+func generateMigration(rf *unstructured.Unstructured, source redisv1beta1.ReplicaOfSpec) *redisv1beta1.RedisMigration {
+	annotations := map[string]string{}
+	if v, ok := rf.GetAnnotations()[util.AnnotationScope]; ok {
+		annotations[util.AnnotationScope] = v
+	}
+	return &redisv1beta1.RedisMigration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getImportMigrationName(rf.GetName()),
+			Namespace: rf.GetNamespace(),
+			Labels: map[string]string{
+				redisv1beta1.LabelManagedByKey: redisv1beta1.OperatorName,
+				LabelRedisFailoverKey:          rf.GetName(),
+			},
+			Annotations: annotations,
+		},
+		Spec: redisv1beta1.RedisMigrationSpec{
+			ClusterName: rf.GetName(),
+			Source:      source,
+		},
+	}
+}
+
+// parseCustomConfig turns the "name value" lines of the RedisFailover into the config of the RedisCluster
+func parseCustomConfig(lines []string) map[string]string {
+	if len(lines) == 0 {
+		return nil
+	}
+	config := make(map[string]string, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		config[fields[0]] = strings.Join(fields[1:], " ")
+	}
+	return config
+}
+
+func getImportMigrationName(name string) string {
+	return name + importMigrationSuffix
+}
+
+// the names of the objects of a RedisFailover
+func getRedisName(name string) string {
+	return "rfr-" + name
+}
+
+func getSentinelServiceName(name string) string {
+	return "rfs-" + name
+}
+
+func getMasterServiceName(name string) string {
+	return "rfrm-" + name
+}
+
+func getReplicaServiceName(name string) string {
+	return "rfrs-" + name
+}
+
+func getShutdownConfigMapName(name string) string {
+	return "rfr-s-" + name
+}
+
+func getReadinessConfigMapName(name string) string {
+	return "rfr-readiness-" + name
+}
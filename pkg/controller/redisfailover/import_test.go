@@ -0,0 +1,92 @@
+package redisfailover
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+func Test_generateRedisCluster(t *testing.T) {
+	rf := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "databases.spotahome.com/v1",
+		"kind":       "RedisFailover",
+		"metadata": map[string]interface{}{
+			"name":      "sessions",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"team": "web"},
+			"annotations": map[string]interface{}{
+				AnnotationImport:     "true",
+				util.AnnotationScope: util.AnnotationClusterScoped,
+			},
+		},
+		"spec": map[string]interface{}{
+			"redis": map[string]interface{}{
+				"replicas":     int64(3),
+				"image":        "redis:5.0-alpine",
+				"customConfig": []interface{}{"maxmemory 100mb", "save 900 1"},
+				"exporter":     map[string]interface{}{"enabled": true},
+			},
+			"sentinel": map[string]interface{}{
+				"replicas":     int64(3),
+				"customConfig": []interface{}{"down-after-milliseconds 2000"},
+			},
+			"auth": map[string]interface{}{"secretPath": "sessions-auth"},
+		},
+	}}
+	spec := &redisFailoverSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(getSpec(rf), spec); err != nil {
+		t.Fatal(err)
+	}
+	source := redisv1beta1.ReplicaOfSpec{Host: "10.0.0.5", Port: redisPort, AuthSecret: "sessions-auth"}
+
+	rc := generateRedisCluster(rf, spec, source, "secret")
+	if rc.Name != "sessions" || rc.Namespace != "default" {
+		t.Errorf("generateRedisCluster() name = %s/%s, want default/sessions", rc.Namespace, rc.Name)
+	}
+	wantAnnotations := map[string]string{AnnotationImportedFrom: "sessions", util.AnnotationScope: util.AnnotationClusterScoped}
+	if !reflect.DeepEqual(rc.Annotations, wantAnnotations) {
+		t.Errorf("generateRedisCluster() annotations = %v, want %v", rc.Annotations, wantAnnotations)
+	}
+	if rc.Spec.Size != 3 || rc.Spec.Sentinel.Replicas != 3 || rc.Spec.Image != "redis:5.0-alpine" || !rc.Spec.Exporter.Enabled {
+		t.Errorf("generateRedisCluster() spec = %+v", rc.Spec)
+	}
+	wantConfig := map[string]string{"maxmemory": "100mb", "save": "900 1"}
+	if !reflect.DeepEqual(rc.Spec.Config, wantConfig) {
+		t.Errorf("generateRedisCluster() config = %v, want %v", rc.Spec.Config, wantConfig)
+	}
+	if !reflect.DeepEqual(rc.Spec.Sentinel.CustomConfig, []string{"down-after-milliseconds 2000"}) {
+		t.Errorf("generateRedisCluster() sentinel config = %v", rc.Spec.Sentinel.CustomConfig)
+	}
+	if rc.Spec.Password != "secret" || rc.Spec.ReplicaOf == nil || *rc.Spec.ReplicaOf != source {
+		t.Errorf("generateRedisCluster() password = %q, replicaOf = %v", rc.Spec.Password, rc.Spec.ReplicaOf)
+	}
+}
+
+func Test_parseCustomConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  map[string]string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "values with spaces",
+			lines: []string{"maxmemory-policy allkeys-lru", "save 900 1 300 10", "  "},
+			want:  map[string]string{"maxmemory-policy": "allkeys-lru", "save": "900 1 300 10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCustomConfig(tt.lines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCustomConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
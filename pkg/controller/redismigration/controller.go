@@ -0,0 +1,113 @@
+package redismigration
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/metrics"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const requeueTime = 10 * time.Second
+
+var log = logf.Log.WithName("controller_redismigration")
+
+// Add creates a new RedisMigration Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	handler := &RedisMigrationHandler{
+		k8sServices: k8s.New(mgr.GetClient(), log),
+		redisClient: redis.New(),
+		metrics:     metrics.ClusterMetrics,
+		logger:      log,
+	}
+	return &ReconcileRedisMigration{client: mgr.GetClient(), scheme: mgr.GetScheme(), handler: handler}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("redismigration-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	pred := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// Ignore updates to CR status in which case metadata.Generation does not change
+			return util.ShouldManage(e.MetaNew) && e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration()
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			if util.ShouldManage(e.Meta) {
+				metrics.ClusterMetrics.DeleteMigration(e.Meta.GetNamespace(), e.Meta.GetName())
+			}
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return util.ShouldManage(e.Meta)
+		},
+	}
+
+	// Watch for changes to primary resource RedisMigration
+	return c.Watch(&source.Kind{Type: &redisv1beta1.RedisMigration{}}, &handler.EnqueueRequestForObject{}, pred)
+}
+
+var _ reconcile.Reconciler = &ReconcileRedisMigration{}
+
+// ReconcileRedisMigration reconciles a RedisMigration object
+type ReconcileRedisMigration struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client  client.Client
+	scheme  *runtime.Scheme
+	handler *RedisMigrationHandler
+}
+
+// Reconcile moves the RedisMigration to its next phase, it is requeued until the migration is finished
+// to report the lag and follow the cluster.
+func (r *ReconcileRedisMigration) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.V(3).Info("Reconciling RedisMigration")
+
+	instance := &redisv1beta1.RedisMigration{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The cluster keeps replicating from the source until its spec.replicaOf is removed.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !util.ShouldManage(instance) || instance.IsFinished() {
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.handler.Do(instance); err != nil {
+		if err == needRequeueErr {
+			return reconcile.Result{RequeueAfter: requeueTime}, nil
+		}
+		reqLogger.Error(err, "Reconcile handler")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,367 @@
+package redismigration
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
+	"github.com/ucloud/redis-operator/pkg/metrics"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const (
+	sourcePasswordKey  = "password"
+	minReplicasToWrite = "min-replicas-to-write"
+	// readOnlyMinReplicas is more replicas than a redis can have, the source refuses all the writes
+	readOnlyMinReplicas = "65535"
+	redisPort           = "6379"
+)
+
+var needRequeueErr = errors.New("need requeue")
+
+// RedisMigrationHandler migrates the data of an external redis into a RedisCluster, the cluster runs
+// as a standby of the source until the cutover.
+type RedisMigrationHandler struct {
+	k8sServices k8s.Services
+	redisClient redis.Client
+	metrics     metrics.Instrumenter
+	logger      logr.Logger
+}
+
+// Do moves the migration to its next phase:
+// Pending: the cluster becomes a standby of the source
+// Syncing: report the lag, on cutover record the min-replicas-to-write of the source
+// CuttingOver: make the source read-only, wait for zero lag and promote the cluster
+// RollbackWindow: the source replicates from the cluster until the deadline, or is made writable on rollback
+func (r *RedisMigrationHandler) Do(m *redisv1beta1.RedisMigration) error {
+	if err := m.Validate(); err != nil {
+		return r.fail(m, err.Error())
+	}
+	rc, err := r.k8sServices.GetCluster(m.Namespace, m.Spec.ClusterName)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return r.fail(m, fmt.Sprintf("redis cluster %s not found", m.Spec.ClusterName))
+		}
+		return err
+	}
+	if err := rc.Validate(); err != nil {
+		return r.fail(m, err.Error())
+	}
+	sourceAuth, err := r.getSourceAuth(m)
+	if err != nil {
+		return err
+	}
+
+	switch m.Status.Phase {
+	case "", redisv1beta1.MigrationPhasePending:
+		return r.start(m, rc)
+	case redisv1beta1.MigrationPhaseSyncing:
+		return r.sync(m, rc, sourceAuth)
+	case redisv1beta1.MigrationPhaseCutover:
+		return r.cutover(m, rc, sourceAuth)
+	case redisv1beta1.MigrationPhaseRollback:
+		return r.rollbackWindow(m, rc, sourceAuth)
+	}
+	return nil
+}
+
+// start makes the cluster a standby of the source, its data is replaced by a full sync
+func (r *RedisMigrationHandler) start(m *redisv1beta1.RedisMigration, rc *redisv1beta1.RedisCluster) error {
+	if rc.Spec.ReplicaOf != nil && !isSameSource(rc.Spec.ReplicaOf, &m.Spec.Source) {
+		return r.fail(m, fmt.Sprintf("redis cluster %s already replicates from %s", rc.Name, rc.Spec.ReplicaOf.Host))
+	}
+	if rc.IsActiveReplica() || rc.Spec.Restore != nil || rc.Spec.CloneFrom != nil {
+		return r.fail(m, fmt.Sprintf("redis cluster %s can't replicate from an external redis", rc.Name))
+	}
+	if rc.Spec.ReplicaOf == nil {
+		r.logger.WithValues("namespace", m.Namespace, "name", m.Name).Info(fmt.Sprintf("redis cluster %s replicates from %s", rc.Name, getSourceAddr(m)))
+		if err := r.setReplicaOf(rc, m.Spec.Source.DeepCopy()); err != nil {
+			return err
+		}
+	}
+	m.Status.Phase = redisv1beta1.MigrationPhaseSyncing
+	m.Status.Message = ""
+	if err := r.k8sServices.UpdateMigrationStatus(m.Namespace, m); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// sync reports the lag of the cluster, the cutover starts once the cluster is synced with the source
+func (r *RedisMigrationHandler) sync(m *redisv1beta1.RedisMigration, rc *redisv1beta1.RedisCluster, sourceAuth *util.AuthConfig) error {
+	if rc.Spec.ReplicaOf == nil || !isSameSource(rc.Spec.ReplicaOf, &m.Spec.Source) {
+		return r.fail(m, fmt.Sprintf("redis cluster %s does not replicate from %s anymore", rc.Name, getSourceAddr(m)))
+	}
+	if rc.Status.ReplicaOf == "" || rc.Status.MasterIP == "" {
+		return r.setMessage(m, "waiting for the redis cluster to replicate from the source")
+	}
+	auth := &util.AuthConfig{Password: rc.Spec.Password}
+	synced, err := r.redisClient.IsReplicaSynced(rc.Status.MasterIP, auth)
+	if err != nil {
+		return err
+	}
+	if !synced {
+		return r.setMessage(m, "full sync from the source in progress")
+	}
+	lag, err := r.getLag(getSourceAddr(m), sourceAuth, rc.Status.MasterIP, auth)
+	if err != nil {
+		return err
+	}
+	r.metrics.SetMigrationLag(m.Namespace, m.Name, lag)
+	status := m.Status.DeepCopy()
+	status.Lag = lag
+	status.Message = ""
+
+	if m.Spec.Cutover {
+		// recorded before the source is made read-only, so it can be restored on rollback
+		current, err := r.redisClient.GetConfig(getSourceAddr(m), minReplicasToWrite, sourceAuth)
+		if err != nil {
+			return err
+		}
+		r.logger.WithValues("namespace", m.Namespace, "name", m.Name).Info(fmt.Sprintf("cutover started with a lag of %d bytes", lag))
+		status.SourceMinReplicasToWrite = current
+		status.CutoverTime = time.Now().Format(time.RFC3339)
+		status.Phase = redisv1beta1.MigrationPhaseCutover
+	}
+	if *status != m.Status {
+		m.Status = *status
+		if err := r.k8sServices.UpdateMigrationStatus(m.Namespace, m); err != nil {
+			return err
+		}
+	}
+	return needRequeueErr
+}
+
+// cutover makes the source read-only, then promotes the cluster once it has applied all the writes
+func (r *RedisMigrationHandler) cutover(m *redisv1beta1.RedisMigration, rc *redisv1beta1.RedisCluster, sourceAuth *util.AuthConfig) error {
+	logger := r.logger.WithValues("namespace", m.Namespace, "name", m.Name)
+	auth := &util.AuthConfig{Password: rc.Spec.Password}
+	if rc.Spec.ReplicaOf != nil {
+		if err := r.setSourceReadOnly(m, sourceAuth); err != nil {
+			return err
+		}
+		lag, err := r.getLag(getSourceAddr(m), sourceAuth, rc.Status.MasterIP, auth)
+		if err != nil {
+			return err
+		}
+		r.metrics.SetMigrationLag(m.Namespace, m.Name, lag)
+		if lag > 0 {
+			return r.setLag(m, lag, fmt.Sprintf("waiting for the cluster to apply the last %d bytes", lag))
+		}
+		logger.Info(fmt.Sprintf("no lag, promoting redis cluster %s", rc.Name))
+		if err := r.setReplicaOf(rc, nil); err != nil {
+			return err
+		}
+		return r.setLag(m, 0, "promoting the redis cluster")
+	}
+	// the cluster controller detaches the master and clears the status
+	if rc.Status.ReplicaOf != "" || rc.Status.MasterIP == "" {
+		return r.setLag(m, 0, "promoting the redis cluster")
+	}
+
+	if m.Spec.RollbackWindow == "" {
+		logger.Info("migration completed, the source stays read-only")
+		m.Status.Phase = redisv1beta1.MigrationPhaseCompleted
+		m.Status.Message = "the source is read-only"
+		m.Status.CompletionTime = time.Now().Format(time.RFC3339)
+		return r.k8sServices.UpdateMigrationStatus(m.Namespace, m)
+	}
+	// checked by Validate
+	window, _ := time.ParseDuration(m.Spec.RollbackWindow)
+	if err := r.followCluster(m, rc, sourceAuth); err != nil {
+		return err
+	}
+	m.Status.Phase = redisv1beta1.MigrationPhaseRollback
+	m.Status.Message = fmt.Sprintf("the source replicates from the redis cluster %s", rc.Name)
+	m.Status.RollbackDeadline = time.Now().Add(window).Format(time.RFC3339)
+	if err := r.k8sServices.UpdateMigrationStatus(m.Namespace, m); err != nil {
+		return err
+	}
+	return needRequeueErr
+}
+
+// rollbackWindow keeps the source in sync with the cluster until the deadline, a rollback makes it a writable master again
+func (r *RedisMigrationHandler) rollbackWindow(m *redisv1beta1.RedisMigration, rc *redisv1beta1.RedisCluster, sourceAuth *util.AuthConfig) error {
+	logger := r.logger.WithValues("namespace", m.Namespace, "name", m.Name)
+	source := getSourceAddr(m)
+	if m.Spec.Rollback {
+		logger.Info("rollback, the source is writable again")
+		if err := r.redisClient.MakeMaster(source, sourceAuth); err != nil {
+			return err
+		}
+		if err := r.redisClient.RunCommand(source, []string{"CONFIG", "SET", minReplicasToWrite, m.Status.SourceMinReplicasToWrite}, sourceAuth); err != nil {
+			return err
+		}
+		m.Status.Phase = redisv1beta1.MigrationPhaseRolledBack
+		m.Status.Message = fmt.Sprintf("the source is writable, the redis cluster %s is not updated anymore", rc.Name)
+		m.Status.CompletionTime = time.Now().Format(time.RFC3339)
+		return r.k8sServices.UpdateMigrationStatus(m.Namespace, m)
+	}
+
+	deadline, err := time.Parse(time.RFC3339, m.Status.RollbackDeadline)
+	if err != nil {
+		return r.fail(m, fmt.Sprintf("invalid rollbackDeadline: %s", err))
+	}
+	if time.Now().After(deadline) {
+		logger.Info("rollback window expired, the source is detached and stays read-only")
+		if err := r.redisClient.MakeMaster(source, sourceAuth); err != nil {
+			return err
+		}
+		m.Status.Phase = redisv1beta1.MigrationPhaseCompleted
+		m.Status.Message = "the source is read-only"
+		m.Status.Lag = 0
+		m.Status.CompletionTime = time.Now().Format(time.RFC3339)
+		return r.k8sServices.UpdateMigrationStatus(m.Namespace, m)
+	}
+
+	if err := r.followCluster(m, rc, sourceAuth); err != nil {
+		return err
+	}
+	lag, err := r.getLag(rc.Status.MasterIP, &util.AuthConfig{Password: rc.Spec.Password}, source, sourceAuth)
+	if err != nil {
+		return err
+	}
+	r.metrics.SetMigrationLag(m.Namespace, m.Name, lag)
+	synced, err := r.redisClient.IsReplicaSynced(source, sourceAuth)
+	if err != nil {
+		return err
+	}
+	if !synced {
+		host, port := getFollowAddr(m, rc)
+		message := fmt.Sprintf("rollback unsafe, the link of the source to %s is down or syncing, check that the source can reach it",
+			net.JoinHostPort(host, port))
+		logger.Info(message)
+		return r.setLag(m, lag, message)
+	}
+	return r.setLag(m, lag, fmt.Sprintf("the source replicates from the redis cluster %s", rc.Name))
+}
+
+// followCluster makes the source replicate from the current master of the cluster, after a failover too
+func (r *RedisMigrationHandler) followCluster(m *redisv1beta1.RedisMigration, rc *redisv1beta1.RedisCluster, sourceAuth *util.AuthConfig) error {
+	if rc.Status.MasterIP == "" {
+		return needRequeueErr
+	}
+	source := getSourceAddr(m)
+	master, err := r.redisClient.GetSlaveMasterAddr(source, sourceAuth)
+	if err != nil {
+		return err
+	}
+	host, port := getFollowAddr(m, rc)
+	if master == net.JoinHostPort(host, port) {
+		return nil
+	}
+	r.logger.WithValues("namespace", m.Namespace, "name", m.Name).Info(fmt.Sprintf("source replicates from %s", net.JoinHostPort(host, port)))
+	if err := r.redisClient.RunCommand(source, []string{"CONFIG", "SET", "masterauth", rc.Spec.Password}, sourceAuth); err != nil {
+		return err
+	}
+	return r.redisClient.MakeSlaveOfAddr(source, host, port, sourceAuth)
+}
+
+// setReplicaOf updates the spec of the cluster as stored, without the defaults set by Validate
+func (r *RedisMigrationHandler) setReplicaOf(rc *redisv1beta1.RedisCluster, replicaOf *redisv1beta1.ReplicaOfSpec) error {
+	stored, err := r.k8sServices.GetCluster(rc.Namespace, rc.Name)
+	if err != nil {
+		return err
+	}
+	stored.Spec.ReplicaOf = replicaOf
+	return r.k8sServices.UpdateClusterSpec(stored.Namespace, stored)
+}
+
+// setSourceReadOnly makes the source refuse the writes, it is asked for more replicas than it can have
+func (r *RedisMigrationHandler) setSourceReadOnly(m *redisv1beta1.RedisMigration, sourceAuth *util.AuthConfig) error {
+	return r.redisClient.RunCommand(getSourceAddr(m), []string{"CONFIG", "SET", minReplicasToWrite, readOnlyMinReplicas}, sourceAuth)
+}
+
+// getLag returns the bytes of the master not applied by the replica, the master offset is read first
+func (r *RedisMigrationHandler) getLag(master string, masterAuth *util.AuthConfig, replica string, replicaAuth *util.AuthConfig) (int64, error) {
+	masterOffset, err := r.redisClient.GetReplicationOffset(master, masterAuth)
+	if err != nil {
+		return 0, err
+	}
+	replicaOffset, err := r.redisClient.GetReplicationOffset(replica, replicaAuth)
+	if err != nil {
+		return 0, err
+	}
+	return getLag(masterOffset, replicaOffset), nil
+}
+
+func (r *RedisMigrationHandler) getSourceAuth(m *redisv1beta1.RedisMigration) (*util.AuthConfig, error) {
+	if m.Spec.Source.AuthSecret == "" {
+		return &util.AuthConfig{}, nil
+	}
+	secret, err := r.k8sServices.GetSecret(m.Namespace, m.Spec.Source.AuthSecret)
+	if err != nil {
+		return nil, err
+	}
+	password, ok := secret.Data[sourcePasswordKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %s", secret.Name, sourcePasswordKey)
+	}
+	return &util.AuthConfig{Password: string(password)}, nil
+}
+
+func (r *RedisMigrationHandler) setLag(m *redisv1beta1.RedisMigration, lag int64, message string) error {
+	if m.Status.Lag != lag || m.Status.Message != message {
+		m.Status.Lag = lag
+		m.Status.Message = message
+		if err := r.k8sServices.UpdateMigrationStatus(m.Namespace, m); err != nil {
+			return err
+		}
+	}
+	return needRequeueErr
+}
+
+func (r *RedisMigrationHandler) setMessage(m *redisv1beta1.RedisMigration, message string) error {
+	r.logger.WithValues("namespace", m.Namespace, "name", m.Name).V(2).Info(message)
+	return r.setLag(m, m.Status.Lag, message)
+}
+
+// fail records the failure, a failed migration is not retried
+func (r *RedisMigrationHandler) fail(m *redisv1beta1.RedisMigration, message string) error {
+	r.logger.WithValues("namespace", m.Namespace, "name", m.Name).Info(fmt.Sprintf("migration failed: %s", message))
+	m.Status.Phase = redisv1beta1.MigrationPhaseFailed
+	m.Status.Message = message
+	m.Status.CompletionTime = time.Now().Format(time.RFC3339)
+	return r.k8sServices.UpdateMigrationStatus(m.Namespace, m)
+}
+
+func getSourceAddr(m *redisv1beta1.RedisMigration) string {
+	return net.JoinHostPort(m.Spec.Source.Host, strconv.Itoa(int(m.Spec.Source.Port)))
+}
+
+// getFollowAddr returns the address the source replicates from during the rollback window
+func getFollowAddr(m *redisv1beta1.RedisMigration, rc *redisv1beta1.RedisCluster) (string, string) {
+	if m.Spec.AdvertiseAddress != "" {
+		// checked by Validate
+		if host, port, err := net.SplitHostPort(m.Spec.AdvertiseAddress); err == nil {
+			return host, port
+		}
+	}
+	return rc.Status.MasterIP, redisPort
+}
+
+// isSameSource is true when the standby of the cluster replicates from the source of the migration
+func isSameSource(replicaOf *redisv1beta1.ReplicaOfSpec, source *redisv1beta1.ReplicaOfSpec) bool {
+	port := strconv.Itoa(int(replicaOf.Port))
+	if replicaOf.Port == 0 {
+		port = redisPort
+	}
+	return replicaOf.Host == source.Host && port == strconv.Itoa(int(source.Port))
+}
+
+// getLag returns how many bytes the replica is behind, a replica can be ahead of the offset read
+// on the master just before
+func getLag(masterOffset, replicaOffset int64) int64 {
+	if replicaOffset >= masterOffset {
+		return 0
+	}
+	return masterOffset - replicaOffset
+}
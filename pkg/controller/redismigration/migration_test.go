@@ -0,0 +1,110 @@
+package redismigration
+
+import (
+	"testing"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+)
+
+func Test_getLag(t *testing.T) {
+	tests := []struct {
+		name          string
+		masterOffset  int64
+		replicaOffset int64
+		want          int64
+	}{
+		{
+			name:          "synced",
+			masterOffset:  1024,
+			replicaOffset: 1024,
+			want:          0,
+		},
+		{
+			name:          "behind",
+			masterOffset:  4096,
+			replicaOffset: 1024,
+			want:          3072,
+		},
+		{
+			name:          "replica read after new writes",
+			masterOffset:  1024,
+			replicaOffset: 2048,
+			want:          0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getLag(tt.masterOffset, tt.replicaOffset); got != tt.want {
+				t.Errorf("getLag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isSameSource(t *testing.T) {
+	source := &redisv1beta1.ReplicaOfSpec{Host: "10.0.0.12", Port: 6379}
+	tests := []struct {
+		name      string
+		replicaOf *redisv1beta1.ReplicaOfSpec
+		want      bool
+	}{
+		{
+			name:      "same host and port",
+			replicaOf: &redisv1beta1.ReplicaOfSpec{Host: "10.0.0.12", Port: 6379},
+			want:      true,
+		},
+		{
+			name:      "default port",
+			replicaOf: &redisv1beta1.ReplicaOfSpec{Host: "10.0.0.12"},
+			want:      true,
+		},
+		{
+			name:      "other port",
+			replicaOf: &redisv1beta1.ReplicaOfSpec{Host: "10.0.0.12", Port: 6380},
+			want:      false,
+		},
+		{
+			name:      "other host",
+			replicaOf: &redisv1beta1.ReplicaOfSpec{Host: "10.0.0.13", Port: 6379},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSameSource(tt.replicaOf, source); got != tt.want {
+				t.Errorf("isSameSource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getFollowAddr(t *testing.T) {
+	rc := &redisv1beta1.RedisCluster{Status: redisv1beta1.RedisClusterStatus{MasterIP: "172.16.0.4"}}
+	tests := []struct {
+		name      string
+		advertise string
+		wantHost  string
+		wantPort  string
+	}{
+		{
+			name:     "pod ip of the master",
+			wantHost: "172.16.0.4",
+			wantPort: "6379",
+		},
+		{
+			name:      "advertised address",
+			advertise: "redis-test-master.example.com:30379",
+			wantHost:  "redis-test-master.example.com",
+			wantPort:  "30379",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &redisv1beta1.RedisMigration{Spec: redisv1beta1.RedisMigrationSpec{AdvertiseAddress: tt.advertise}}
+			host, port := getFollowAddr(m, rc)
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("getFollowAddr() = %s, %s, want %s, %s", host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
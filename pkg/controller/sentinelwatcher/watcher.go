@@ -0,0 +1,177 @@
+package sentinelwatcher
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	rediscli "github.com/go-redis/redis"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+	"github.com/ucloud/redis-operator/pkg/util"
+)
+
+const (
+	sentinelPort = "26379"
+
+	switchMasterChannel = "+switch-master"
+	sdownChannel        = "+sdown"
+	odownChannel        = "+odown"
+	failoverEndChannel  = "+failover-end"
+)
+
+var channels = []string{switchMasterChannel, sdownChannel, odownChannel, failoverEndChannel}
+
+// Watcher keeps a subscription to the sentinels of every RedisCluster and turns the
+// sentinel notifications into reconcile requests and kubernetes events.
+type Watcher struct {
+	mu        sync.Mutex
+	subs      map[types.NamespacedName]*subscription
+	events    chan event.GenericEvent
+	eventsCli k8s.Event
+	logger    logr.Logger
+}
+
+type subscription struct {
+	addr   string
+	client *rediscli.Client
+	pubsub *rediscli.PubSub
+}
+
+// New returns a new sentinel Watcher
+func New(eventsCli k8s.Event, logger logr.Logger) *Watcher {
+	return &Watcher{
+		subs:      make(map[types.NamespacedName]*subscription),
+		events:    make(chan event.GenericEvent, 1024),
+		eventsCli: eventsCli,
+		logger:    logger,
+	}
+}
+
+// Events returns the channel the reconcile requests are sent to, to be used with a source.Channel
+func (w *Watcher) Events() <-chan event.GenericEvent {
+	return w.events
+}
+
+// Watch subscribes to the sentinels of the RedisCluster, if it is not already subscribed
+func (w *Watcher) Watch(rc *redisv1beta1.RedisCluster) {
+	key := types.NamespacedName{Namespace: rc.Namespace, Name: rc.Name}
+	addr := net.JoinHostPort(fmt.Sprintf("%s.%s", util.GetSentinelName(rc), rc.Namespace), sentinelPort)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sub, ok := w.subs[key]; ok {
+		if sub.addr == addr {
+			return
+		}
+		sub.close()
+	}
+
+	client := rediscli.NewClient(&rediscli.Options{
+		Addr: addr,
+		DB:   0,
+	})
+	sub := &subscription{
+		addr:   addr,
+		client: client,
+		pubsub: client.Subscribe(channels...),
+	}
+	w.subs[key] = sub
+	w.logger.WithValues("namespace", rc.Namespace, "name", rc.Name).V(2).Info(fmt.Sprintf("subscribe sentinel %s", addr))
+	go w.run(rc.DeepCopy(), sub.pubsub.Channel())
+}
+
+// Stop closes the subscription of the RedisCluster
+func (w *Watcher) Stop(namespace, name string) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sub, ok := w.subs[key]; ok {
+		sub.close()
+		delete(w.subs, key)
+		w.logger.WithValues("namespace", namespace, "name", name).V(2).Info("unsubscribe sentinel")
+	}
+}
+
+func (s *subscription) close() {
+	s.pubsub.Close()
+	s.client.Close()
+}
+
+// run consumes the messages until the subscription is closed
+func (w *Watcher) run(rc *redisv1beta1.RedisCluster, ch <-chan *rediscli.Message) {
+	logger := w.logger.WithValues("namespace", rc.Namespace, "name", rc.Name)
+	for msg := range ch {
+		m, err := parseMessage(msg.Channel, msg.Payload)
+		if err != nil {
+			logger.Info(err.Error())
+			continue
+		}
+		logger.V(2).Info(fmt.Sprintf("sentinel event %s %s", msg.Channel, msg.Payload))
+		switch m.channel {
+		case switchMasterChannel:
+			w.eventsCli.FailoverCluster(rc, fmt.Sprintf("failover from %s to %s", m.oldAddr(), m.addr()))
+		case odownChannel:
+			if m.instanceType == "master" {
+				w.eventsCli.MasterDown(rc, fmt.Sprintf("master %s is objectively down", m.addr()))
+			}
+		}
+		w.events <- event.GenericEvent{Meta: rc, Object: rc}
+	}
+}
+
+// message is a parsed sentinel notification
+type message struct {
+	channel      string
+	instanceType string
+	ip           string
+	port         string
+	oldIP        string
+	oldPort      string
+}
+
+func (m *message) addr() string {
+	return net.JoinHostPort(m.ip, m.port)
+}
+
+func (m *message) oldAddr() string {
+	return net.JoinHostPort(m.oldIP, m.oldPort)
+}
+
+// parseMessage parses the payload of a sentinel notification:
+// +switch-master <master name> <oldip> <oldport> <newip> <newport>
+// the others <instance-type> <name> <ip> <port> [@ <master-name> <master-ip> <master-port>]
+func parseMessage(channel, payload string) (*message, error) {
+	fields := strings.Fields(payload)
+	switch channel {
+	case switchMasterChannel:
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("invalid %s message: %q", channel, payload)
+		}
+		return &message{
+			channel:      channel,
+			instanceType: "master",
+			oldIP:        fields[1],
+			oldPort:      fields[2],
+			ip:           fields[3],
+			port:         fields[4],
+		}, nil
+	case sdownChannel, odownChannel, failoverEndChannel:
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("invalid %s message: %q", channel, payload)
+		}
+		return &message{
+			channel:      channel,
+			instanceType: fields[0],
+			ip:           fields[2],
+			port:         fields[3],
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown sentinel channel %s", channel)
+}
@@ -0,0 +1,66 @@
+package sentinelwatcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		payload string
+		want    *message
+		wantErr bool
+	}{
+		{
+			name:    "switch master",
+			channel: "+switch-master",
+			payload: "mymaster 10.0.0.1 6379 10.0.0.2 6379",
+			want: &message{channel: "+switch-master", instanceType: "master",
+				oldIP: "10.0.0.1", oldPort: "6379", ip: "10.0.0.2", port: "6379"},
+		},
+		{
+			name:    "master odown",
+			channel: "+odown",
+			payload: "master mymaster 10.0.0.1 6379 #quorum 2/2",
+			want:    &message{channel: "+odown", instanceType: "master", ip: "10.0.0.1", port: "6379"},
+		},
+		{
+			name:    "slave sdown",
+			channel: "+sdown",
+			payload: "slave 10.0.0.3:6379 10.0.0.3 6379 @ mymaster 10.0.0.1 6379",
+			want:    &message{channel: "+sdown", instanceType: "slave", ip: "10.0.0.3", port: "6379"},
+		},
+		{
+			name:    "failover end",
+			channel: "+failover-end",
+			payload: "master mymaster 10.0.0.1 6379",
+			want:    &message{channel: "+failover-end", instanceType: "master", ip: "10.0.0.1", port: "6379"},
+		},
+		{
+			name:    "short switch master",
+			channel: "+switch-master",
+			payload: "mymaster 10.0.0.1 6379",
+			wantErr: true,
+		},
+		{
+			name:    "unknown channel",
+			channel: "+tilt",
+			payload: "#tilt mode entered",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMessage(tt.channel, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMessage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMessage() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -25,12 +26,27 @@ type RedisClusterCheck interface {
 	CheckSentinelNumberInMemory(sentinel string, redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) error
 	CheckSentinelSlavesNumberInMemory(sentinel string, redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) error
 	CheckSentinelMonitor(sentinel string, monitor string, auth *util.AuthConfig) error
+	CheckSentinelMasterStatus(sentinel string, auth *util.AuthConfig) error
 	GetMasterIP(redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) (string, error)
 	GetNumberMasters(redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) (int, error)
 	GetRedisesIPs(redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) ([]string, error)
 	GetSentinelsIPs(redisCluster *redisv1beta1.RedisCluster) ([]string, error)
 	GetMinimumRedisPodTime(redisCluster *redisv1beta1.RedisCluster) (time.Duration, error)
 	CheckRedisConfig(redisCluster *redisv1beta1.RedisCluster, addr string, auth *util.AuthConfig) error
+	CheckReadReplicaConfig(redisCluster *redisv1beta1.RedisCluster, addr string, auth *util.AuthConfig) error
+	CheckReadReplicasFromMaster(master string, redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) error
+	GetReadReplicasIPs(redisCluster *redisv1beta1.RedisCluster) ([]string, error)
+	CheckRoleLabels(master string, redisCluster *redisv1beta1.RedisCluster) error
+	CheckReplicaSynced(addr string, auth *util.AuthConfig) error
+	CheckSmokeCommand(addr string, command []string, auth *util.AuthConfig) error
+	GetErrorReplies(addr string, auth *util.AuthConfig) (int64, error)
+	GetSaveStatus(addr string, auth *util.AuthConfig) (*redis.SaveStatus, error)
+	CheckActiveReplicaPeers(addr string, peers []string, auth *util.AuthConfig) error
+	GetLoadingStatus(addr string, auth *util.AuthConfig) (bool, float64, error)
+	GetKeyCount(addr string, auth *util.AuthConfig) (int64, error)
+	GetSentinelMaster(sentinel string, auth *util.AuthConfig) (string, error)
+	GetReplicaMaster(addr string, auth *util.AuthConfig) (string, error)
+	GetReplicaMasterAddr(addr string, auth *util.AuthConfig) (string, error)
 }
 
 var parseConfigMap = map[string]int8{
@@ -69,6 +85,15 @@ func NewRedisClusterChecker(k8sService k8s.Services, redisClient redis.Client, l
 
 // CheckRedisConfig check current redis config is same as custom config
 func (r *RedisClusterChecker) CheckRedisConfig(redisCluster *redisv1beta1.RedisCluster, addr string, auth *util.AuthConfig) error {
+	return r.checkRedisConfig(redisCluster.Spec.Config, addr, auth)
+}
+
+// CheckReadReplicaConfig check current read replica config is same as custom config
+func (r *RedisClusterChecker) CheckReadReplicaConfig(redisCluster *redisv1beta1.RedisCluster, addr string, auth *util.AuthConfig) error {
+	return r.checkRedisConfig(getReadReplicaConfig(redisCluster), addr, auth)
+}
+
+func (r *RedisClusterChecker) checkRedisConfig(expectConfig map[string]string, addr string, auth *util.AuthConfig) error {
 	client := goredis.NewClient(&goredis.Options{
 		Addr:     net.JoinHostPort(addr, "6379"),
 		Password: auth.Password,
@@ -80,7 +105,13 @@ func (r *RedisClusterChecker) CheckRedisConfig(redisCluster *redisv1beta1.RedisC
 		return err
 	}
 
-	for key, value := range redisCluster.Spec.Config {
+	// the unsupported configs are only reported once the others are applied
+	var unsupported []string
+	for key, value := range expectConfig {
+		// set in the config file, a change restarts the redis
+		if redis.IsRestartConfig(key) {
+			continue
+		}
 		var err error
 		if _, ok := parseConfigMap[key]; ok {
 			value, err = util.ParseRedisMemConf(value)
@@ -89,10 +120,23 @@ func (r *RedisClusterChecker) CheckRedisConfig(redisCluster *redisv1beta1.RedisC
 				continue
 			}
 		}
-		if value != configs[key] {
-			return fmt.Errorf("%s configs conflict, expect: %s, current: %s", key, value, configs[key])
+		// the config may be named after the other side of the slave/replica renaming
+		current, ok := configs[key]
+		if !ok {
+			current, ok = configs[redis.ConfigAlias(key)]
+		}
+		if !ok {
+			unsupported = append(unsupported, key)
+			continue
+		}
+		if value != current {
+			return fmt.Errorf("%s configs conflict, expect: %s, current: %s", key, value, current)
 		}
 	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return &redis.UnsupportedConfigError{Configs: unsupported, Addr: addr}
+	}
 	return nil
 }
 
@@ -102,10 +146,12 @@ func (r *RedisClusterChecker) CheckRedisNumber(rc *redisv1beta1.RedisCluster) er
 	if err != nil {
 		return err
 	}
-	if rc.Spec.Size != *ss.Spec.Replicas {
+	// a suspended cluster is intentionally down
+	size := *getReplicas(rc, rc.Spec.Size)
+	if size != *ss.Spec.Replicas {
 		return errors.New("number of redis pods differ from specification")
 	}
-	if rc.Spec.Size != ss.Status.ReadyReplicas {
+	if size != ss.Status.ReadyReplicas {
 		return errors.New("waiting all of redis pods become ready")
 	}
 	return nil
@@ -117,7 +163,7 @@ func (r *RedisClusterChecker) CheckSentinelNumber(rc *redisv1beta1.RedisCluster)
 	if err != nil {
 		return err
 	}
-	if rc.Spec.Sentinel.Replicas != *d.Spec.Replicas {
+	if *getReplicas(rc, rc.Spec.Sentinel.Replicas) != *d.Spec.Replicas {
 		return errors.New("number of sentinel pods differ from specification")
 	}
 	return nil
@@ -129,7 +175,7 @@ func (r *RedisClusterChecker) CheckSentinelReadyReplicas(rc *redisv1beta1.RedisC
 	if err != nil {
 		return err
 	}
-	if rc.Spec.Sentinel.Replicas != d.Status.ReadyReplicas {
+	if *getReplicas(rc, rc.Spec.Sentinel.Replicas) != d.Status.ReadyReplicas {
 		return errors.New("waiting all of sentinel pods become ready")
 	}
 	return nil
@@ -146,13 +192,133 @@ func (r *RedisClusterChecker) CheckAllSlavesFromMaster(master string, rc *redisv
 		if err != nil {
 			return err
 		}
-		if slave != "" && slave != master {
+		// the master of a standby replicates from the external master
+		if rip != master && slave != "" && slave != master {
 			return fmt.Errorf("slave %s don't have the master %s, has %s", rip, master, slave)
 		}
 	}
 	return nil
 }
 
+// CheckReadReplicasFromMaster controls that all read replicas replicate from the real master
+func (r *RedisClusterChecker) CheckReadReplicasFromMaster(master string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	rips, err := r.GetReadReplicasIPs(rc)
+	if err != nil {
+		return err
+	}
+	for _, rip := range rips {
+		slave, err := r.redisClient.GetSlaveMasterIP(rip, auth)
+		if err != nil {
+			return err
+		}
+		if slave != master {
+			return fmt.Errorf("read replica %s don't have the master %s, has %s", rip, master, slave)
+		}
+	}
+	return nil
+}
+
+// CheckRoleLabels controls that the redis pods are labeled with the role they have in the replication
+func (r *RedisClusterChecker) CheckRoleLabels(master string, rc *redisv1beta1.RedisCluster) error {
+	rps, err := r.k8sService.GetStatefulSetPods(rc.Namespace, util.GetRedisName(rc))
+	if err != nil {
+		return err
+	}
+	for _, rp := range rps.Items {
+		if rp.Status.PodIP == "" {
+			continue
+		}
+		if role := getPodRole(rc, rp.Status.PodIP, master); rp.Labels[redisv1beta1.LabelRoleKey] != role {
+			return fmt.Errorf("pod %s is %s, labeled as %q", rp.Name, role, rp.Labels[redisv1beta1.LabelRoleKey])
+		}
+	}
+	return nil
+}
+
+// CheckReplicaSynced controls that the redis replica is connected to its master and has finished the sync
+func (r *RedisClusterChecker) CheckReplicaSynced(addr string, auth *util.AuthConfig) error {
+	synced, err := r.redisClient.IsReplicaSynced(addr, auth)
+	if err != nil {
+		return err
+	}
+	if !synced {
+		return fmt.Errorf("redis %s is not synced with its master", addr)
+	}
+	return nil
+}
+
+// CheckSmokeCommand controls that the command runs without error on the redis
+func (r *RedisClusterChecker) CheckSmokeCommand(addr string, command []string, auth *util.AuthConfig) error {
+	if err := r.redisClient.RunCommand(addr, command, auth); err != nil {
+		return fmt.Errorf("smoke command %v failed on %s: %s", command, addr, err)
+	}
+	return nil
+}
+
+// GetLoadingStatus returns true and the loaded percentage while the redis loads its data from disk
+func (r *RedisClusterChecker) GetLoadingStatus(addr string, auth *util.AuthConfig) (bool, float64, error) {
+	return r.redisClient.GetLoadingStatus(addr, auth)
+}
+
+// GetKeyCount returns the number of keys of the redis
+func (r *RedisClusterChecker) GetKeyCount(addr string, auth *util.AuthConfig) (int64, error) {
+	return r.redisClient.GetKeyCount(addr, auth)
+}
+
+// GetSentinelMaster returns the master monitored by the sentinel
+func (r *RedisClusterChecker) GetSentinelMaster(sentinel string, auth *util.AuthConfig) (string, error) {
+	return r.redisClient.GetSentinelMonitor(sentinel, auth)
+}
+
+// GetReplicaMaster returns the master the redis replicates from, or an empty string if it is a master
+func (r *RedisClusterChecker) GetReplicaMaster(addr string, auth *util.AuthConfig) (string, error) {
+	return r.redisClient.GetSlaveMasterIP(addr, auth)
+}
+
+// GetReplicaMasterAddr returns the host:port of the master the redis replicates from, or an empty string if it is a master
+func (r *RedisClusterChecker) GetReplicaMasterAddr(addr string, auth *util.AuthConfig) (string, error) {
+	return r.redisClient.GetSlaveMasterAddr(addr, auth)
+}
+
+// GetErrorReplies returns the number of error replies the redis has sent
+func (r *RedisClusterChecker) GetErrorReplies(addr string, auth *util.AuthConfig) (int64, error) {
+	return r.redisClient.GetErrorCount(addr, auth)
+}
+
+// GetSaveStatus returns the state of the background saves of the redis
+func (r *RedisClusterChecker) GetSaveStatus(addr string, auth *util.AuthConfig) (*redis.SaveStatus, error) {
+	return r.redisClient.GetSaveStatus(addr, auth)
+}
+
+// CheckActiveReplicaPeers controls that the active replica replicates from all its peers, and only from them
+func (r *RedisClusterChecker) CheckActiveReplicaPeers(addr string, peers []string, auth *util.AuthConfig) error {
+	masters, err := r.redisClient.GetReplicaMasterIPs(addr, auth)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool, len(masters))
+	for _, m := range masters {
+		current[m] = true
+	}
+	if len(current) != len(peers) {
+		return fmt.Errorf("active replica %s replicates from %v, expect %v", addr, masters, peers)
+	}
+	for _, peer := range peers {
+		if !current[peer] {
+			return fmt.Errorf("active replica %s replicates from %v, expect %v", addr, masters, peers)
+		}
+	}
+	return nil
+}
+
+func getPodRole(rc *redisv1beta1.RedisCluster, ip, master string) string {
+	// active replicas all accept writes
+	if ip == master || rc.IsActiveReplica() {
+		return redisv1beta1.RoleMaster
+	}
+	return redisv1beta1.RoleReplica
+}
+
 // CheckSentinelNumberInMemory controls that sentinels have only the living sentinels on its memory.
 func (r *RedisClusterChecker) CheckSentinelNumberInMemory(sentinel string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
 	nSentinels, err := r.redisClient.GetNumberSentinelsInMemory(sentinel, auth)
@@ -169,7 +335,13 @@ func (r *RedisClusterChecker) CheckSentinelSlavesNumberInMemory(sentinel string,
 	nSlaves, err := r.redisClient.GetNumberSentinelSlavesInMemory(sentinel, auth)
 	if err != nil {
 		return err
-	} else if nSlaves != rc.Spec.Size-1 {
+	}
+	expected := rc.Spec.Size - 1
+	if rc.IsStandby() {
+		// the replicas of a standby have the priority 0 and are not counted, like the read replicas
+		expected = 0
+	}
+	if nSlaves != expected {
 		return errors.New("sentinel's slaves in memory mismatch")
 	}
 	return nil
@@ -187,6 +359,18 @@ func (r *RedisClusterChecker) CheckSentinelMonitor(sentinel string, monitor stri
 	return nil
 }
 
+// CheckSentinelMasterStatus controls that the sentinel does not flag the master as down
+func (r *RedisClusterChecker) CheckSentinelMasterStatus(sentinel string, auth *util.AuthConfig) error {
+	down, err := r.redisClient.IsSentinelMasterDown(sentinel, auth)
+	if err != nil {
+		return err
+	}
+	if down {
+		return errors.New("the sentinel flags the master as down")
+	}
+	return nil
+}
+
 // GetMasterIP connects to all redis and returns the master of the redis cluster
 func (r *RedisClusterChecker) GetMasterIP(rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) (string, error) {
 	rips, err := r.GetRedisesIPs(rc, auth)
@@ -244,6 +428,24 @@ func (r *RedisClusterChecker) GetRedisesIPs(rc *redisv1beta1.RedisCluster, auth
 	return redises, nil
 }
 
+// GetReadReplicasIPs returns the IPs of the read replica nodes
+func (r *RedisClusterChecker) GetReadReplicasIPs(rc *redisv1beta1.RedisCluster) ([]string, error) {
+	replicas := []string{}
+	if !hasReadReplicas(rc) {
+		return replicas, nil
+	}
+	rps, err := r.k8sService.GetStatefulSetPods(rc.Namespace, util.GetReadReplicaName(rc))
+	if err != nil {
+		return nil, err
+	}
+	for _, rp := range rps.Items {
+		if rp.Status.Phase == corev1.PodRunning { // Only work with running pods
+			replicas = append(replicas, rp.Status.PodIP)
+		}
+	}
+	return replicas, nil
+}
+
 // GetSentinelsIPs returns the IPs of the Sentinel nodes
 func (r *RedisClusterChecker) GetSentinelsIPs(rc *redisv1beta1.RedisCluster) ([]string, error) {
 	sentinels := []string{}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"reflect"
+
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -23,9 +25,12 @@ type RedisClusterClient interface {
 	EnsureSentinelStatefulset(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
 	EnsureRedisStatefulset(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
 	EnsureRedisService(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
+	EnsureRedisRoleServices(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
 	EnsureRedisShutdownConfigMap(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
 	EnsureRedisConfigMap(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
 	EnsureNotPresentRedisService(redisCluster *redisv1beta1.RedisCluster) error
+	EnsureReadReplicaService(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
+	EnsureReadReplicaStatefulset(redisCluster *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error
 }
 
 // RedisClusterKubeClient implements the required methods to talk with kubernetes
@@ -53,25 +58,25 @@ func generateSelectorLabels(component, name string) map[string]string {
 // EnsureSentinelService makes sure the sentinel service exists
 func (r *RedisClusterKubeClient) EnsureSentinelService(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	svc := generateSentinelService(rc, labels, ownerRefs)
-	return r.K8SService.CreateIfNotExistsService(rc.Namespace, svc)
+	return r.ensureService(svc)
 }
 
 // EnsureSentinelHeadlessService makes sure the sentinel headless service exists
 func (r *RedisClusterKubeClient) EnsureSentinelHeadlessService(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	svc := newHeadLessSvcForCR(rc, labels, ownerRefs)
-	return r.K8SService.CreateIfNotExistsService(rc.Namespace, svc)
+	return r.ensureService(svc)
 }
 
 // EnsureSentinelConfigMap makes sure the sentinel configmap exists
 func (r *RedisClusterKubeClient) EnsureSentinelConfigMap(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	cm := generateSentinelConfigMap(rc, labels, ownerRefs)
-	return r.K8SService.CreateIfNotExistsConfigMap(rc.Namespace, cm)
+	return r.ensureConfigMap(cm)
 }
 
 // EnsureSentinelConfigMap makes sure the sentinel configmap exists
 func (r *RedisClusterKubeClient) EnsureSentinelProbeConfigMap(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	cm := generateSentinelReadinessProbeConfigMap(rc, labels, ownerRefs)
-	return r.K8SService.CreateIfNotExistsConfigMap(rc.Namespace, cm)
+	return r.ensureConfigMap(cm)
 }
 
 // EnsureSentinelStatefulset makes sure the sentinel deployment exists in the desired state
@@ -80,21 +85,8 @@ func (r *RedisClusterKubeClient) EnsureSentinelStatefulset(rc *redisv1beta1.Redi
 		return err
 	}
 
-	oldSs, err := r.K8SService.GetStatefulSet(rc.Namespace, util.GetSentinelName(rc))
-	if err != nil {
-		// If no resource we need to create.
-		if errors.IsNotFound(err) {
-			ss := generateSentinelStatefulSet(rc, labels, ownerRefs)
-			return r.K8SService.CreateStatefulSet(rc.Namespace, ss)
-		}
-		return err
-	}
-
-	if shouldUpdateRedis(rc.Spec.Sentinel.Resources, oldSs.Spec.Template.Spec.Containers[0].Resources, rc.Spec.Sentinel.Replicas, *oldSs.Spec.Replicas) {
-		ss := generateSentinelStatefulSet(rc, labels, ownerRefs)
-		return r.K8SService.UpdateStatefulSet(rc.Namespace, ss)
-	}
-	return nil
+	ss := generateSentinelStatefulSet(rc, labels, ownerRefs)
+	return r.ensureStatefulSet(ss)
 }
 
 // EnsureRedisStatefulset makes sure the redis statefulset exists in the desired state
@@ -103,66 +95,14 @@ func (r *RedisClusterKubeClient) EnsureRedisStatefulset(rc *redisv1beta1.RedisCl
 		return err
 	}
 
-	oldSs, err := r.K8SService.GetStatefulSet(rc.Namespace, util.GetRedisName(rc))
-	if err != nil {
-		// If no resource we need to create.
-		if errors.IsNotFound(err) {
-			ss := generateRedisStatefulSet(rc, labels, ownerRefs)
-			return r.K8SService.CreateStatefulSet(rc.Namespace, ss)
-		}
-		return err
-	}
-
-	if shouldUpdateRedis(rc.Spec.Resources, oldSs.Spec.Template.Spec.Containers[0].Resources,
-		rc.Spec.Size, *oldSs.Spec.Replicas) || exporterChanged(rc, oldSs) {
-		ss := generateRedisStatefulSet(rc, labels, ownerRefs)
-		return r.K8SService.UpdateStatefulSet(rc.Namespace, ss)
-	}
-
-	return nil
-}
-
-func exporterChanged(rc *redisv1beta1.RedisCluster, sts *appsv1.StatefulSet) bool {
-	if rc.Spec.Exporter.Enabled {
-		for _, container := range sts.Spec.Template.Spec.Containers {
-			if container.Name == exporterContainerName {
-				return false
-			}
-		}
-		return true
-	} else {
-		for _, container := range sts.Spec.Template.Spec.Containers {
-			if container.Name == exporterContainerName {
-				return true
-			}
-		}
-		return false
-	}
-}
-
-func shouldUpdateRedis(expectResource, containterResource corev1.ResourceRequirements, expectSize, replicas int32) bool {
-	if expectSize != replicas {
-		return true
-	}
-	if result := containterResource.Requests.Cpu().Cmp(*expectResource.Requests.Cpu()); result != 0 {
-		return true
-	}
-	if result := containterResource.Requests.Memory().Cmp(*expectResource.Requests.Memory()); result != 0 {
-		return true
-	}
-	if result := containterResource.Limits.Cpu().Cmp(*expectResource.Limits.Cpu()); result != 0 {
-		return true
-	}
-	if result := containterResource.Limits.Memory().Cmp(*expectResource.Limits.Memory()); result != 0 {
-		return true
-	}
-	return false
+	ss := generateRedisStatefulSet(rc, labels, ownerRefs)
+	return r.ensureStatefulSet(ss)
 }
 
-// EnsureRedisConfigMap makes sure the sentinel configmap exists
+// EnsureRedisConfigMap makes sure the redis configmap exists
 func (r *RedisClusterKubeClient) EnsureRedisConfigMap(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	cm := generateRedisConfigMap(rc, labels, ownerRefs)
-	return r.K8SService.CreateIfNotExistsConfigMap(rc.Namespace, cm)
+	return r.ensureConfigMap(cm)
 }
 
 // EnsureRedisShutdownConfigMap makes sure the redis configmap with shutdown script exists
@@ -173,7 +113,7 @@ func (r *RedisClusterKubeClient) EnsureRedisShutdownConfigMap(rc *redisv1beta1.R
 		}
 	} else {
 		cm := generateRedisShutdownConfigMap(rc, labels, ownerRefs)
-		return r.K8SService.CreateIfNotExistsConfigMap(rc.Namespace, cm)
+		return r.ensureConfigMap(cm)
 	}
 	return nil
 }
@@ -181,7 +121,18 @@ func (r *RedisClusterKubeClient) EnsureRedisShutdownConfigMap(rc *redisv1beta1.R
 // EnsureRedisService makes sure the redis statefulset exists
 func (r *RedisClusterKubeClient) EnsureRedisService(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	svc := generateRedisService(rc, labels, ownerRefs)
-	return r.K8SService.CreateIfNotExistsService(rc.Namespace, svc)
+	return r.ensureService(svc)
+}
+
+// EnsureRedisRoleServices makes sure the master and replica services exist
+func (r *RedisClusterKubeClient) EnsureRedisRoleServices(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	for _, role := range []string{redisv1beta1.RoleMaster, redisv1beta1.RoleReplica} {
+		svc := generateRedisRoleService(rc, role, labels, ownerRefs)
+		if err := r.ensureService(svc); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // EnsureNotPresentRedisService makes sure the redis service is not present
@@ -195,6 +146,39 @@ func (r *RedisClusterKubeClient) EnsureNotPresentRedisService(rc *redisv1beta1.R
 	return nil
 }
 
+// EnsureReadReplicaService makes sure the read replica service exists, or is removed
+// when no read replicas are requested
+func (r *RedisClusterKubeClient) EnsureReadReplicaService(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	if !hasReadReplicas(rc) {
+		name := util.GetReadReplicaName(rc)
+		if _, err := r.K8SService.GetService(rc.Namespace, name); err == nil {
+			return r.K8SService.DeleteService(rc.Namespace, name)
+		}
+		return nil
+	}
+	svc := generateReadReplicaService(rc, labels, ownerRefs)
+	return r.ensureService(svc)
+}
+
+// EnsureReadReplicaStatefulset makes sure the read replica statefulset exists in the desired state,
+// or is removed when no read replicas are requested
+func (r *RedisClusterKubeClient) EnsureReadReplicaStatefulset(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
+	if !hasReadReplicas(rc) {
+		name := util.GetReadReplicaName(rc)
+		if _, err := r.K8SService.GetStatefulSet(rc.Namespace, name); err == nil {
+			return r.K8SService.DeleteStatefulSet(rc.Namespace, name)
+		}
+		return nil
+	}
+	ss := generateReadReplicaStatefulSet(rc, labels, ownerRefs)
+	return r.ensureStatefulSet(ss)
+}
+
+func hasReadReplicas(rc *redisv1beta1.RedisCluster) bool {
+	// the read replicas have no data of their own, they are removed while the cluster is suspended
+	return rc.Spec.ReadReplicas != nil && rc.Spec.ReadReplicas.Replicas > 0 && !rc.IsSuspended()
+}
+
 // EnsureRedisStatefulset makes sure the pdb exists in the desired state
 func (r *RedisClusterKubeClient) ensurePodDisruptionBudget(rc *redisv1beta1.RedisCluster, name string, component string, labels map[string]string, ownerRefs []metav1.OwnerReference) error {
 	name = util.GenerateName(name, rc.Name)
@@ -205,5 +189,133 @@ func (r *RedisClusterKubeClient) ensurePodDisruptionBudget(rc *redisv1beta1.Redi
 
 	pdb := generatePodDisruptionBudget(name, namespace, labels, ownerRefs, minAvailable)
 
-	return r.K8SService.CreateIfNotExistsPodDisruptionBudget(namespace, pdb)
+	oldPdb, err := r.K8SService.GetPodDisruptionBudget(namespace, name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if err := util.SetSpecHash(&pdb.ObjectMeta, pdb.Spec); err != nil {
+				return err
+			}
+			return r.K8SService.CreatePodDisruptionBudget(namespace, pdb)
+		}
+		return err
+	}
+	// the pods keep the labels selected when they were created, a change of the cluster labels does not change the selector
+	pdb.Spec.Selector = oldPdb.Spec.Selector
+	if err := util.SetSpecHash(&pdb.ObjectMeta, pdb.Spec); err != nil {
+		return err
+	}
+	if !util.SpecHashChanged(oldPdb, pdb) {
+		return nil
+	}
+	// the spec of a policy/v1beta1 pdb can not be updated, it is recreated
+	if err := r.K8SService.DeletePodDisruptionBudget(namespace, name); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return r.K8SService.CreatePodDisruptionBudget(namespace, pdb)
+}
+
+// ensureService creates the service, or updates it when its spec differs from the generated one
+func (r *RedisClusterKubeClient) ensureService(svc *corev1.Service) error {
+	oldSvc, err := r.K8SService.GetService(svc.Namespace, svc.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if err := util.SetSpecHash(&svc.ObjectMeta, svc.Spec); err != nil {
+				return err
+			}
+			return r.K8SService.CreateService(svc.Namespace, svc)
+		}
+		return err
+	}
+	// the selector is kept so that the service keeps selecting the pods of the statefulsets, their selector can not change
+	if oldSvc.Spec.Selector != nil {
+		svc.Spec.Selector = oldSvc.Spec.Selector
+	}
+	if err := util.SetSpecHash(&svc.ObjectMeta, svc.Spec); err != nil {
+		return err
+	}
+	if !util.SpecHashChanged(oldSvc, svc) {
+		return nil
+	}
+	// the cluster ip is allocated by kubernetes and can not be changed
+	svc.Spec.ClusterIP = oldSvc.Spec.ClusterIP
+	svc.ResourceVersion = oldSvc.ResourceVersion
+	return r.K8SService.UpdateService(svc.Namespace, svc)
+}
+
+// ensureConfigMap creates the configmap, or updates it when its data differs from the generated one
+func (r *RedisClusterKubeClient) ensureConfigMap(cm *corev1.ConfigMap) error {
+	if err := util.SetSpecHash(&cm.ObjectMeta, cm.Data); err != nil {
+		return err
+	}
+	oldCm, err := r.K8SService.GetConfigMap(cm.Namespace, cm.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.K8SService.CreateConfigMap(cm.Namespace, cm)
+		}
+		return err
+	}
+	if !util.SpecHashChanged(oldCm, cm) {
+		return nil
+	}
+	cm.ResourceVersion = oldCm.ResourceVersion
+	return r.K8SService.UpdateConfigMap(cm.Namespace, cm)
+}
+
+// ensureStatefulSet creates the statefulset, or updates it when its spec differs from the generated one.
+// The volumeClaimTemplates can not be updated, so they are not part of the hash and are kept as they are.
+// The selector can not be updated either, it is carried forward and the pods keep the labels it selects.
+func (r *RedisClusterKubeClient) ensureStatefulSet(ss *appsv1.StatefulSet) error {
+	oldSs, err := r.K8SService.GetStatefulSet(ss.Namespace, ss.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if err := setStatefulSetSpecHash(ss); err != nil {
+				return err
+			}
+			return r.K8SService.CreateStatefulSet(ss.Namespace, ss)
+		}
+		return err
+	}
+	if oldSs.Spec.Selector != nil {
+		keepSelector(ss, oldSs.Spec.Selector)
+	}
+	if err := setStatefulSetSpecHash(ss); err != nil {
+		return err
+	}
+	if !util.SpecHashChanged(oldSs, ss) {
+		return nil
+	}
+	ss.Spec.VolumeClaimTemplates = oldSs.Spec.VolumeClaimTemplates
+	ss.ResourceVersion = oldSs.ResourceVersion
+	return r.K8SService.UpdateStatefulSet(ss.Namespace, ss)
+}
+
+func setStatefulSetSpecHash(ss *appsv1.StatefulSet) error {
+	spec := ss.Spec.DeepCopy()
+	spec.VolumeClaimTemplates = nil
+	return util.SetSpecHash(&ss.ObjectMeta, spec)
+}
+
+// keepSelector makes the statefulset use the given selector, the pod labels and the anti-affinity
+// terms that selected the pods of the statefulset select them with it too
+func keepSelector(ss *appsv1.StatefulSet, selector *metav1.LabelSelector) {
+	generated := ss.Spec.Selector
+	ss.Spec.Selector = selector
+	ss.Spec.Template.Labels = util.MergeLabels(ss.Spec.Template.Labels, selector.MatchLabels)
+
+	affinity := ss.Spec.Template.Spec.Affinity
+	if generated == nil || affinity == nil || affinity.PodAntiAffinity == nil {
+		return
+	}
+	for i := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		term := &affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+		if reflect.DeepEqual(term.LabelSelector, generated) {
+			term.LabelSelector = selector.DeepCopy()
+		}
+	}
+	for i := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		term := &affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[i].PodAffinityTerm
+		if reflect.DeepEqual(term.LabelSelector, generated) {
+			term.LabelSelector = selector.DeepCopy()
+		}
+	}
 }
@@ -12,3 +12,74 @@ const (
 
 	redisPasswordEnv = "REDIS_PASSWORD"
 )
+
+// the restore only runs on the first pod with an empty data volume, the RDB or the AOF files are
+// downloaded to a temporary location so a failed download is never loaded
+const (
+	restoreScript = `set -e
+if [ "$POD_NAME" != "$RESTORE_POD" ]; then
+  echo "skipped, not the restored pod" > /dev/termination-log
+  exit 0
+fi
+if [ -e /data/dump.rdb ] || [ -e /data/appendonly.aof ] || [ -e /data/appendonlydir ]; then
+  echo "skipped, /data is not empty" > /dev/termination-log
+  exit 0
+fi
+%s
+`
+	restoreRDBLoad = `
+mv /data/dump.rdb.tmp /data/dump.rdb
+echo "restored $(wc -c < /data/dump.rdb) bytes from $RESTORE_URL" > /dev/termination-log`
+	restoreS3Fetch = `mc alias set restore "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY"
+mc cp "restore/$S3_BUCKET/$S3_KEY" /data/dump.rdb.tmp` + restoreRDBLoad
+	restoreHTTPFetch = `curl -fsSL -o /data/dump.rdb.tmp "$RESTORE_URL"` + restoreRDBLoad
+	// the last manifest uploaded before the point in time lists the AOF files to download, they are
+	// truncated to the point in time by the restore-aof init container
+	restoreAOFFetch = `mc alias set restore "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY"
+manifest=$(mc ls "restore/$S3_BUCKET/$S3_KEY/manifests/" | awk '{print $NF}' | awk -F- -v t="$RESTORE_TIMESTAMP" '$1 <= t' | sort -n | tail -1)
+if [ -z "$manifest" ]; then
+  echo "no AOF manifest uploaded before $RESTORE_POINT_IN_TIME" > /dev/termination-log
+  exit 1
+fi
+source=${manifest#*-}
+source=${source%.manifest}
+rm -rf /data/appendonlydir.tmp
+mkdir -p /data/appendonlydir.tmp
+mc cp "restore/$S3_BUCKET/$S3_KEY/manifests/$manifest" /data/appendonlydir.tmp/appendonly.aof.manifest
+for file in $(awk '{print $2}' /data/appendonlydir.tmp/appendonly.aof.manifest); do
+  mc cp "restore/$S3_BUCKET/$S3_KEY/$source/$file" "/data/appendonlydir.tmp/$file"
+done
+touch /data/appendonlydir.tmp/.truncate
+mv /data/appendonlydir.tmp /data/appendonlydir
+echo "restored the AOF of $source from $RESTORE_URL" > /dev/termination-log`
+	// the marker is removed once truncated, so a restarted pod never truncates the AOF again
+	restoreAOFTruncateScript = `set -e
+if [ ! -e /data/appendonlydir/.truncate ]; then
+  exit 0
+fi
+echo y | %s --truncate-to-timestamp "$RESTORE_TIMESTAMP" /data/appendonlydir/appendonly.aof.manifest
+rm /data/appendonlydir/.truncate
+echo "AOF truncated to $RESTORE_POINT_IN_TIME" > /dev/termination-log
+`
+)
+
+// the AOF files are shipped by a sidecar of every redis pod, it only uploads while the pod is labeled
+// as the master. The manifest is copied first so all the files it lists are uploaded before it.
+const (
+	podInfoVolumeName = "podinfo"
+	aofShipperScript  = `mc alias set pitr "$S3_ENDPOINT" "$AWS_ACCESS_KEY_ID" "$AWS_SECRET_ACCESS_KEY"
+last=""
+while true; do
+  if grep -qx "$MASTER_LABEL" /podinfo/labels && [ -e /data/appendonlydir/appendonly.aof.manifest ]; then
+    cp /data/appendonlydir/appendonly.aof.manifest /tmp/appendonly.aof.manifest
+    sum=$(md5sum /tmp/appendonly.aof.manifest | cut -d' ' -f1)
+    if mc mirror --overwrite --exclude "*.manifest" /data/appendonlydir "pitr/$S3_BUCKET/$S3_PREFIX/$POD_NAME" && [ "$sum" != "$last" ]; then
+      mc cp /tmp/appendonly.aof.manifest "pitr/$S3_BUCKET/$S3_PREFIX/manifests/$(date +%s)-$POD_NAME.manifest" && last=$sum
+    fi
+  else
+    last=""
+  fi
+  sleep "$INTERVAL_SECONDS"
+done
+`
+)
@@ -2,7 +2,11 @@ package service
 
 import (
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -12,10 +16,12 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/redis"
 	"github.com/ucloud/redis-operator/pkg/util"
 )
 
 const (
+	redisConfigurationVolumeName         = "redis-config"
 	redisShutdownConfigurationVolumeName = "redis-shutdown-config"
 	redisStorageVolumeName               = "redis-data"
 
@@ -79,6 +85,68 @@ func generateRedisService(rc *redisv1beta1.RedisCluster, labels map[string]strin
 	}
 }
 
+// generateRedisRoleService creates a service that selects the redis pods labeled with the given role
+func generateRedisRoleService(rc *redisv1beta1.RedisCluster, role string, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.Service {
+	name := util.GetRedisReplicaName(rc)
+	if role == redisv1beta1.RoleMaster {
+		name = util.GetRedisMasterName(rc)
+	}
+	namespace := rc.Namespace
+
+	labels = util.MergeLabels(labels, generateSelectorLabels(util.RedisRoleName, rc.Name), map[string]string{
+		redisv1beta1.LabelRoleKey: role,
+	})
+	redisTargetPort := intstr.FromInt(6379)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{
+					Port:       6379,
+					Protocol:   corev1.ProtocolTCP,
+					Name:       "redis",
+					TargetPort: redisTargetPort,
+				},
+			},
+			Selector: labels,
+		},
+	}
+}
+
+func generateReadReplicaService(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.Service {
+	name := util.GetReadReplicaName(rc)
+	namespace := rc.Namespace
+
+	labels = util.MergeLabels(labels, generateSelectorLabels(util.ReadReplicaRoleName, rc.Name))
+	redisTargetPort := intstr.FromInt(6379)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{
+					Port:       6379,
+					Protocol:   corev1.ProtocolTCP,
+					Name:       "redis",
+					TargetPort: redisTargetPort,
+				},
+			},
+			Selector: labels,
+		},
+	}
+}
+
 func generateSentinelConfigMap(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.ConfigMap {
 	name := util.GetSentinelName(rc)
 	namespace := rc.Namespace
@@ -92,6 +160,13 @@ sentinel parallel-syncs mymaster 2`
 	if rc.Spec.Password != "" {
 		sentinelConfigFileContent = fmt.Sprintf("%s\nsentinel auth-pass mymaster %s\n", sentinelConfigFileContent, rc.Spec.Password)
 	}
+	// the configs refused by SENTINEL SET are only read when the sentinel starts
+	for _, config := range rc.Spec.Sentinel.CustomConfig {
+		if name := strings.SplitN(config, " ", 2)[0]; redis.IsSentinelRuntimeConfig(name) {
+			continue
+		}
+		sentinelConfigFileContent = fmt.Sprintf("%s\nsentinel %s", sentinelConfigFileContent, config)
+	}
 
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -106,18 +181,25 @@ sentinel parallel-syncs mymaster 2`
 	}
 }
 
+// generateRedisConfigMap returns the config file of the redis, with the custom configs that can only be
+// changed by a restart. The other configs are passed on the command line or set at runtime.
 func generateRedisConfigMap(rc *redisv1beta1.RedisCluster, labels map[string]string, ownerRefs []metav1.OwnerReference) *corev1.ConfigMap {
 	name := util.GetRedisName(rc)
 	namespace := rc.Namespace
 
 	labels = util.MergeLabels(labels, generateSelectorLabels(util.RedisRoleName, rc.Name))
-	redisConfigFileContent := `slaveof 127.0.0.1 6379
-tcp-keepalive 60
-save 900 1
-save 300 10`
-	if rc.Spec.Password != "" {
-		redisConfigFileContent = fmt.Sprintf("%s\nrequirepass %s\nmasterauth %s\n", redisConfigFileContent, rc.Spec.Password, rc.Spec.Password)
+	var lines []string
+	for key, value := range rc.Spec.Config {
+		if !redis.IsRestartConfig(key) {
+			continue
+		}
+		if value == "" {
+			value = `""`
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", key, value))
 	}
+	sort.Strings(lines)
+	redisConfigFileContent := strings.Join(lines, "\n")
 
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -139,23 +221,30 @@ func generateRedisShutdownConfigMap(rc *redisv1beta1.RedisCluster, labels map[st
 	labels = util.MergeLabels(labels, generateSelectorLabels(util.RedisRoleName, rc.Name))
 	envSentinelHost := fmt.Sprintf("REDIS_SENTINEL_%s_SERVICE_HOST", strings.ToUpper(rc.Name))
 	envSentinelPort := fmt.Sprintf("REDIS_SENTINEL_%s_SERVICE_PORT_SENTINEL", strings.ToUpper(rc.Name))
+	cli := rc.GetEngineProfile().CliBinary
 	shutdownContent := fmt.Sprintf(`#!/usr/bin/env sh
 master=""
 response_code=""
 while [ "$master" = "" ]; do
 	echo "Asking sentinel who is master..."
-	master=$(redis-cli -h ${%s} -p ${%s} --csv SENTINEL get-master-addr-by-name mymaster | tr ',' ' ' | tr -d '\"' |cut -d' ' -f1)
+	master=$(%s -h ${%s} -p ${%s} --csv SENTINEL get-master-addr-by-name mymaster | tr ',' ' ' | tr -d '\"' |cut -d' ' -f1)
 	sleep 1
 done
 echo "Master is $master, doing redis save..."
-redis-cli SAVE
+%s SAVE
 if [ $master = $(hostname -i) ]; then
 	while [ ! "$response_code" = "OK" ]; do
-  		response_code=$(redis-cli -h ${%s} -p ${%s} SENTINEL failover mymaster)
+  		response_code=$(%s -h ${%s} -p ${%s} SENTINEL failover mymaster)
 		echo "after failover with code $response_code"
 		sleep 1
 	done
-fi`, envSentinelHost, envSentinelPort, envSentinelHost, envSentinelPort)
+fi`, cli, envSentinelHost, envSentinelPort, cli, cli, envSentinelHost, envSentinelPort)
+	if rc.IsActiveReplica() {
+		// all the nodes are masters, there is nothing to fail over
+		shutdownContent = fmt.Sprintf(`#!/usr/bin/env sh
+echo "doing redis save..."
+%s SAVE`, cli)
+	}
 
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -175,18 +264,19 @@ func generateSentinelReadinessProbeConfigMap(rc *redisv1beta1.RedisCluster, labe
 	namespace := rc.Namespace
 
 	labels = util.MergeLabels(labels, generateSelectorLabels(util.RedisRoleName, rc.Name))
-	checkContent := `#!/usr/bin/env sh
+	cli := rc.GetEngineProfile().CliBinary
+	checkContent := fmt.Sprintf(`#!/usr/bin/env sh
 set -eou pipefail
-redis-cli -h $(hostname) -p 26379 ping
-slaves=$(redis-cli -h $(hostname) -p 26379 info sentinel|grep master0| grep -Eo 'slaves=[0-9]+' | awk -F= '{print $2}')
-status=$(redis-cli -h $(hostname) -p 26379 info sentinel|grep master0| grep -Eo 'status=\w+' | awk -F= '{print $2}')
+%s -h $(hostname) -p 26379 ping
+slaves=$(%s -h $(hostname) -p 26379 info sentinel|grep master0| grep -Eo 'slaves=[0-9]+' | awk -F= '{print $2}')
+status=$(%s -h $(hostname) -p 26379 info sentinel|grep master0| grep -Eo 'status=\w+' | awk -F= '{print $2}')
 if [ "$status" != "ok" ]; then 
     exit 1
 fi
 if [ $slaves -le 1 ]; then
 	exit 1
 fi
-`
+`, cli, cli, cli)
 
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -207,16 +297,22 @@ func generateRedisStatefulSet(rc *redisv1beta1.RedisCluster, labels map[string]s
 	namespace := rc.Namespace
 
 	spec := rc.Spec
-	redisCommand := getRedisCommand(rc)
 	labels = util.MergeLabels(labels, generateSelectorLabels(util.RedisRoleName, rc.Name))
-	volumeMounts := getRedisVolumeMounts(rc)
 	volumes := getRedisVolumes(rc)
 
-	probeArg := "redis-cli -h $(hostname)"
-	if spec.Password != "" {
-		probeArg = fmt.Sprintf("%s -a '%s' ping", probeArg, spec.Password)
-	} else {
-		probeArg = fmt.Sprintf("%s ping", probeArg)
+	// a change of the configs that are only read at start restarts the redis, in the order of an upgrade
+	configHash, _ := util.SpecHash(generateRedisConfigMap(rc, labels, ownerRefs).Data)
+	annotations := util.MergeLabels(rc.Spec.Annotations, map[string]string{
+		util.AnnotationConfigHash: configHash,
+	})
+
+	redisContainer := createRedisContainer(rc, getRedisCommand(rc), getRedisVolumeMounts(rc), rc.Spec.Resources)
+	redisContainer.Lifecycle = &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "/redis-shutdown/shutdown.sh"},
+			},
+		},
 	}
 
 	ss := &appsv1.StatefulSet{
@@ -228,9 +324,10 @@ func generateRedisStatefulSet(rc *redisv1beta1.RedisCluster, labels map[string]s
 		},
 		Spec: appsv1.StatefulSetSpec{
 			ServiceName: name,
-			Replicas:    &spec.Size,
+			Replicas:    getReplicas(rc, spec.Size),
+			// the pods are restarted by the operator, replicas first and the master last
 			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
-				Type: "RollingUpdate",
+				Type: appsv1.OnDeleteStatefulSetStrategyType,
 			},
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
@@ -238,7 +335,7 @@ func generateRedisStatefulSet(rc *redisv1beta1.RedisCluster, labels map[string]s
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      labels,
-					Annotations: rc.Spec.Annotations,
+					Annotations: annotations,
 				},
 				Spec: corev1.PodSpec{
 					Affinity:         getAffinity(rc.Spec.Affinity, labels),
@@ -247,54 +344,7 @@ func generateRedisStatefulSet(rc *redisv1beta1.RedisCluster, labels map[string]s
 					SecurityContext:  getSecurityContext(rc.Spec.SecurityContext),
 					ImagePullSecrets: rc.Spec.ImagePullSecrets,
 					Containers: []corev1.Container{
-						{
-							Name:            "redis",
-							Image:           rc.Spec.Image,
-							ImagePullPolicy: pullPolicy(rc.Spec.ImagePullPolicy),
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "redis",
-									ContainerPort: 6379,
-									Protocol:      corev1.ProtocolTCP,
-								},
-							},
-							VolumeMounts: volumeMounts,
-							Command:      redisCommand,
-							ReadinessProbe: &corev1.Probe{
-								InitialDelaySeconds: graceTime,
-								TimeoutSeconds:      5,
-								Handler: corev1.Handler{
-									Exec: &corev1.ExecAction{
-										Command: []string{
-											"sh",
-											"-c",
-											probeArg,
-										},
-									},
-								},
-							},
-							LivenessProbe: &corev1.Probe{
-								InitialDelaySeconds: graceTime,
-								TimeoutSeconds:      5,
-								Handler: corev1.Handler{
-									Exec: &corev1.ExecAction{
-										Command: []string{
-											"sh",
-											"-c",
-											probeArg,
-										},
-									},
-								},
-							},
-							Resources: rc.Spec.Resources,
-							Lifecycle: &corev1.Lifecycle{
-								PreStop: &corev1.Handler{
-									Exec: &corev1.ExecAction{
-										Command: []string{"/bin/sh", "/redis-shutdown/shutdown.sh"},
-									},
-								},
-							},
-						},
+						redisContainer,
 					},
 					Volumes: volumes,
 				},
@@ -317,6 +367,98 @@ func generateRedisStatefulSet(rc *redisv1beta1.RedisCluster, labels map[string]s
 		ss.Spec.Template.Spec.Containers = append(ss.Spec.Template.Spec.Containers, exporter)
 	}
 
+	if rc.Spec.PITR != nil {
+		shipper := createAOFShipperContainer(rc)
+		ss.Spec.Template.Spec.Containers = append(ss.Spec.Template.Spec.Containers, shipper)
+	}
+
+	if rc.Spec.Restore != nil {
+		ss.Spec.Template.Spec.InitContainers = []corev1.Container{createRestoreContainer(rc)}
+		if rc.IsPointInTimeRestore() {
+			ss.Spec.Template.Spec.InitContainers = append(ss.Spec.Template.Spec.InitContainers, createRestoreAOFContainer(rc))
+		}
+	}
+
+	return ss
+}
+
+func generateReadReplicaStatefulSet(rc *redisv1beta1.RedisCluster, labels map[string]string,
+	ownerRefs []metav1.OwnerReference) *appsv1.StatefulSet {
+	name := util.GetReadReplicaName(rc)
+	namespace := rc.Namespace
+
+	spec := rc.Spec.ReadReplicas
+	labels = util.MergeLabels(labels, generateSelectorLabels(util.ReadReplicaRoleName, rc.Name))
+
+	// the read replicas read the config file of the redis
+	configHash, _ := util.SpecHash(generateRedisConfigMap(rc, labels, ownerRefs).Data)
+	annotations := util.MergeLabels(spec.Annotations, map[string]string{
+		util.AnnotationConfigHash: configHash,
+	})
+
+	redisContainer := createRedisContainer(rc, getReadReplicaCommand(rc), []corev1.VolumeMount{
+		{
+			Name:      redisConfigurationVolumeName,
+			MountPath: "/redis",
+		},
+		{
+			Name:      redisStorageVolumeName,
+			MountPath: "/data",
+		},
+	}, spec.Resources)
+
+	ss := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			Labels:          labels,
+			OwnerReferences: ownerRefs,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName:         name,
+			Replicas:            &spec.Replicas,
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			// the pods are restarted by the operator, after the redis and one at a time
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.OnDeleteStatefulSetStrategyType,
+			},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: annotations,
+				},
+				Spec: corev1.PodSpec{
+					Affinity:         getAffinity(spec.Affinity, labels),
+					Tolerations:      spec.ToleRations,
+					NodeSelector:     spec.NodeSelector,
+					SecurityContext:  getSecurityContext(rc.Spec.SecurityContext),
+					ImagePullSecrets: rc.Spec.ImagePullSecrets,
+					Containers: []corev1.Container{
+						redisContainer,
+					},
+					// read replicas always full sync from the master, so the data is never persisted
+					Volumes: []corev1.Volume{
+						getRedisConfigVolume(rc),
+						{
+							Name: redisStorageVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if rc.Spec.Exporter.Enabled {
+		exporter := createRedisExporterContainer(rc)
+		ss.Spec.Template.Spec.Containers = append(ss.Spec.Template.Spec.Containers, exporter)
+	}
+
 	return ss
 }
 
@@ -329,6 +471,13 @@ func generateSentinelStatefulSet(rc *redisv1beta1.RedisCluster, labels map[strin
 	sentinelCommand := getSentinelCommand(rc)
 	labels = util.MergeLabels(labels, generateSelectorLabels(util.SentinelRoleName, rc.Name))
 
+	// a change of the config file restarts the sentinels, one at a time, the configs accepted
+	// by SENTINEL SET are applied at runtime and left out of the hash
+	configHash, _ := util.SpecHash(generateSentinelConfigMap(rc, labels, ownerRefs).Data)
+	annotations := util.MergeLabels(rc.Spec.Sentinel.Annotations, map[string]string{
+		util.AnnotationConfigHash: configHash,
+	})
+
 	return &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            name,
@@ -338,14 +487,18 @@ func generateSentinelStatefulSet(rc *redisv1beta1.RedisCluster, labels map[strin
 		},
 		Spec: appsv1.StatefulSetSpec{
 			ServiceName: util.GetSentinelHeadlessSvc(rc),
-			Replicas:    &spec.Sentinel.Replicas,
+			Replicas:    getReplicas(rc, spec.Sentinel.Replicas),
+			// the pods are restarted by the operator, without losing the quorum
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.OnDeleteStatefulSetStrategyType,
+			},
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      labels,
-					Annotations: rc.Spec.Sentinel.Annotations,
+					Annotations: annotations,
 				},
 				Spec: corev1.PodSpec{
 					Affinity:         getAffinity(rc.Spec.Sentinel.Affinity, labels),
@@ -430,7 +583,7 @@ func generateSentinelStatefulSet(rc *redisv1beta1.RedisCluster, labels map[strin
 										Command: []string{
 											"sh",
 											"-c",
-											"redis-cli -h $(hostname) -p 26379 ping",
+											fmt.Sprintf("%s -h $(hostname) -p 26379 ping", rc.GetEngineProfile().CliBinary),
 										},
 									},
 								},
@@ -489,6 +642,59 @@ func generatePodDisruptionBudget(name string, namespace string, labels map[strin
 	}
 }
 
+// createRedisContainer returns the redis server container, shared by the redis and the read replicas
+func createRedisContainer(rc *redisv1beta1.RedisCluster, command []string, volumeMounts []corev1.VolumeMount,
+	resources corev1.ResourceRequirements) corev1.Container {
+	probeArg := fmt.Sprintf("%s -h $(hostname)", rc.GetEngineProfile().CliBinary)
+	if rc.Spec.Password != "" {
+		probeArg = fmt.Sprintf("%s -a '%s' ping", probeArg, rc.Spec.Password)
+	} else {
+		probeArg = fmt.Sprintf("%s ping", probeArg)
+	}
+
+	return corev1.Container{
+		Name:            "redis",
+		Image:           rc.Spec.Image,
+		ImagePullPolicy: pullPolicy(rc.Spec.ImagePullPolicy),
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "redis",
+				ContainerPort: 6379,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		VolumeMounts: volumeMounts,
+		Command:      command,
+		ReadinessProbe: &corev1.Probe{
+			InitialDelaySeconds: graceTime,
+			TimeoutSeconds:      5,
+			Handler: corev1.Handler{
+				Exec: &corev1.ExecAction{
+					Command: []string{
+						"sh",
+						"-c",
+						probeArg,
+					},
+				},
+			},
+		},
+		LivenessProbe: &corev1.Probe{
+			InitialDelaySeconds: graceTime,
+			TimeoutSeconds:      5,
+			Handler: corev1.Handler{
+				Exec: &corev1.ExecAction{
+					Command: []string{
+						"sh",
+						"-c",
+						probeArg,
+					},
+				},
+			},
+		},
+		Resources: resources,
+	}
+}
+
 func generateResourceList(cpu string, memory string) corev1.ResourceList {
 	resources := corev1.ResourceList{}
 	if cpu != "" {
@@ -542,6 +748,126 @@ func createRedisExporterContainer(rc *redisv1beta1.RedisCluster) corev1.Containe
 	return container
 }
 
+// createRestoreContainer returns the init container downloading the RDB into the empty data volume
+// of the first redis pod, redis loads it when it starts
+func createRestoreContainer(rc *redisv1beta1.RedisCluster) corev1.Container {
+	from := rc.Spec.Restore.From
+	env := []corev1.EnvVar{
+		{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		},
+		{Name: "RESTORE_POD", Value: util.GetFirstRedisPodName(rc)},
+		{Name: "RESTORE_URL", Value: from.URL},
+	}
+	fetch := restoreHTTPFetch
+	if u, err := url.Parse(from.URL); err == nil && u.Scheme == "s3" {
+		fetch = restoreS3Fetch
+		if rc.IsPointInTimeRestore() {
+			fetch = restoreAOFFetch
+			env = append(env, getRestoreTimestampEnv(rc)...)
+		}
+		env = append(env,
+			corev1.EnvVar{Name: "S3_ENDPOINT", Value: from.Endpoint},
+			corev1.EnvVar{Name: "S3_BUCKET", Value: u.Host},
+			corev1.EnvVar{Name: "S3_KEY", Value: strings.TrimPrefix(u.Path, "/")},
+		)
+		env = append(env, util.S3CredentialsEnv(from.CredentialsSecret)...)
+	}
+
+	return corev1.Container{
+		Name:            util.RestoreContainerName,
+		Image:           rc.Spec.Restore.Image,
+		ImagePullPolicy: pullPolicy(rc.Spec.Restore.ImagePullPolicy),
+		Command:         []string{"sh", "-c", fmt.Sprintf(restoreScript, fetch)},
+		Env:             env,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      getRedisDataVolumeName(rc),
+				MountPath: "/data",
+			},
+		},
+		// a failed download shows up in the restore status
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}
+}
+
+// createRestoreAOFContainer returns the init container truncating the AOF files downloaded by the
+// restore container to the point in time
+func createRestoreAOFContainer(rc *redisv1beta1.RedisCluster) corev1.Container {
+	return corev1.Container{
+		Name:            util.RestoreAOFContainerName,
+		Image:           rc.Spec.Image,
+		ImagePullPolicy: pullPolicy(rc.Spec.ImagePullPolicy),
+		Command:         []string{"sh", "-c", fmt.Sprintf(restoreAOFTruncateScript, rc.GetEngineProfile().CheckAOFBinary)},
+		Env:             getRestoreTimestampEnv(rc),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      getRedisDataVolumeName(rc),
+				MountPath: "/data",
+			},
+		},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}
+}
+
+func getRestoreTimestampEnv(rc *redisv1beta1.RedisCluster) []corev1.EnvVar {
+	// checked by Validate
+	t, _ := time.Parse(time.RFC3339, rc.Spec.Restore.PointInTime)
+	return []corev1.EnvVar{
+		{Name: "RESTORE_POINT_IN_TIME", Value: rc.Spec.Restore.PointInTime},
+		{Name: "RESTORE_TIMESTAMP", Value: strconv.FormatInt(t.Unix(), 10)},
+	}
+}
+
+// createAOFShipperContainer returns the sidecar uploading the AOF files of the master
+func createAOFShipperContainer(rc *redisv1beta1.RedisCluster) corev1.Container {
+	pitr := rc.Spec.PITR
+	return corev1.Container{
+		Name:            util.AOFShipperContainerName,
+		Image:           pitr.Image,
+		ImagePullPolicy: pullPolicy(pitr.ImagePullPolicy),
+		Command:         []string{"sh", "-c", aofShipperScript},
+		Env: append([]corev1.EnvVar{
+			{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.name",
+					},
+				},
+			},
+			{Name: "MASTER_LABEL", Value: fmt.Sprintf("%s=%q", redisv1beta1.LabelRoleKey, redisv1beta1.RoleMaster)},
+			{Name: "INTERVAL_SECONDS", Value: strconv.Itoa(int(pitr.IntervalSeconds))},
+			{Name: "MC_CONFIG_DIR", Value: "/tmp/.mc"},
+			{Name: "S3_ENDPOINT", Value: pitr.Storage.S3.Endpoint},
+			{Name: "S3_BUCKET", Value: pitr.Storage.S3.Bucket},
+			{Name: "S3_PREFIX", Value: util.GetPITRPrefix(rc)},
+		}, util.S3CredentialsEnv(pitr.Storage.S3.CredentialsSecret)...),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      getRedisDataVolumeName(rc),
+				MountPath: "/data",
+				ReadOnly:  true,
+			},
+			{
+				Name:      podInfoVolumeName,
+				MountPath: "/podinfo",
+			},
+		},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(exporterDefaultRequestCPU),
+				corev1.ResourceMemory: resource.MustParse(exporterDefaultRequestMemory),
+			},
+		},
+	}
+}
+
 func createPodAntiAffinity(hard bool, labels map[string]string) *corev1.PodAntiAffinity {
 	if hard {
 		// Return a HARD anti-affinity (no same pods on one node)
@@ -587,10 +913,10 @@ func getQuorum(rc *redisv1beta1.RedisCluster) int32 {
 
 func getRedisVolumeMounts(rc *redisv1beta1.RedisCluster) []corev1.VolumeMount {
 	volumeMounts := []corev1.VolumeMount{
-		//{
-		//	Name:      redisConfigurationVolumeName,
-		//	MountPath: "/redis",
-		//},
+		{
+			Name:      redisConfigurationVolumeName,
+			MountPath: "/redis",
+		},
 		{
 			Name:      redisShutdownConfigurationVolumeName,
 			MountPath: "/redis-shutdown",
@@ -609,6 +935,7 @@ func getRedisVolumes(rc *redisv1beta1.RedisCluster) []corev1.Volume {
 
 	executeMode := int32(0744)
 	volumes := []corev1.Volume{
+		getRedisConfigVolume(rc),
 		{
 			Name: redisShutdownConfigurationVolumeName,
 			VolumeSource: corev1.VolumeSource{
@@ -627,9 +954,47 @@ func getRedisVolumes(rc *redisv1beta1.RedisCluster) []corev1.Volume {
 		volumes = append(volumes, *dataVolume)
 	}
 
+	if rc.Spec.PITR != nil {
+		// the shipper reads the role label of its pod, it changes on failover
+		volumes = append(volumes, corev1.Volume{
+			Name: podInfoVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				DownwardAPI: &corev1.DownwardAPIVolumeSource{
+					Items: []corev1.DownwardAPIVolumeFile{
+						{
+							Path:     "labels",
+							FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels"},
+						},
+					},
+				},
+			},
+		})
+	}
+
 	return volumes
 }
 
+func getRedisConfigVolume(rc *redisv1beta1.RedisCluster) corev1.Volume {
+	return corev1.Volume{
+		Name: redisConfigurationVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: util.GetRedisName(rc),
+				},
+			},
+		},
+	}
+}
+
+// getReplicas returns the number of pods of a statefulset, there is none while the cluster is suspended
+func getReplicas(rc *redisv1beta1.RedisCluster, replicas int32) *int32 {
+	if rc.IsSuspended() {
+		replicas = 0
+	}
+	return &replicas
+}
+
 func getRedisDataVolume(rc *redisv1beta1.RedisCluster) *corev1.Volume {
 	// This will find the volumed desired by the user. If no volume defined
 	// an EmptyDir will be used by default
@@ -669,31 +1034,59 @@ func getRedisCommand(rc *redisv1beta1.RedisCluster) []string {
 		return rc.Spec.Command
 	}
 
+	configFile := fmt.Sprintf("/redis/%s", util.RedisConfigFileName)
 	cmds := []string{
-		"redis-server",
+		rc.GetEngineProfile().ServerBinary,
+		configFile,
 		"--slaveof 127.0.0.1 6379",
 		"--tcp-keepalive 60",
 		"--save 900 1",
 		"--save 300 10",
 	}
+	if rc.IsActiveReplica() {
+		// every node starts as a master, the operator makes them replicate each other
+		cmds = []string{
+			rc.GetEngineProfile().ServerBinary,
+			configFile,
+			"--active-replica yes",
+			"--multi-master yes",
+			"--tcp-keepalive 60",
+			"--save 900 1",
+			"--save 300 10",
+		}
+	}
 
 	if rc.Spec.Password != "" {
 		cmds = append(cmds, fmt.Sprintf("--requirepass '%s'", rc.Spec.Password),
 			fmt.Sprintf("--masterauth '%s'", rc.Spec.Password))
 	}
 
+	if rc.IsPointInTimeRestore() {
+		// the restored AOF files are only loaded if the AOF is enabled when redis starts
+		cmds = append(cmds, "--appendonly yes")
+	}
+
 	return cmds
 }
 
+func getReadReplicaCommand(rc *redisv1beta1.RedisCluster) []string {
+	cmds := getRedisCommand(rc)
+	if len(rc.Spec.Command) > 0 {
+		return cmds
+	}
+	return append(cmds, "--slave-priority 0", "--slave-read-only yes")
+}
+
 func getSentinelCommand(rc *redisv1beta1.RedisCluster) []string {
 	if len(rc.Spec.Sentinel.Command) > 0 {
 		return rc.Spec.Sentinel.Command
 	}
-	return []string{
-		"redis-server",
+	profile := rc.GetEngineProfile()
+	cmds := []string{
+		profile.SentinelBinary,
 		fmt.Sprintf("/redis/%s", util.SentinelConfigFileName),
-		"--sentinel",
 	}
+	return append(cmds, profile.SentinelArgs...)
 }
 
 func getAffinity(affinity *corev1.Affinity, labels map[string]string) *corev1.Affinity {
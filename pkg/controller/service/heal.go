@@ -23,6 +23,14 @@ type RedisClusterHeal interface {
 	RestoreSentinel(ip string, auth *util.AuthConfig) error
 	SetSentinelCustomConfig(ip string, redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) error
 	SetRedisCustomConfig(ip string, redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) error
+	SetReadReplicaCustomConfig(ip string, redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) error
+	SetMasterOnReadReplicas(masterIP string, redisCluster *redisv1beta1.RedisCluster, auth *util.AuthConfig) error
+	SetRoleLabels(masterIP string, redisCluster *redisv1beta1.RedisCluster) error
+	SentinelFailover(sentinel string, auth *util.AuthConfig) error
+	SetActiveReplicaPeers(ip string, peers []string, auth *util.AuthConfig) error
+	SetExternalMaster(ip string, host string, port string, masterAuth *util.AuthConfig, auth *util.AuthConfig) error
+	DetachExternalMaster(ip string, auth *util.AuthConfig) error
+	BgSave(ip string, auth *util.AuthConfig) error
 }
 
 // RedisClusterHealer is our implementation of RedisClusterCheck intercace
@@ -45,6 +53,48 @@ func (r *RedisClusterHealer) MakeMaster(ip string, auth *util.AuthConfig) error
 	return r.redisClient.MakeMaster(ip, auth)
 }
 
+// SentinelFailover asks the sentinel to promote one of the replicas
+func (r *RedisClusterHealer) SentinelFailover(sentinel string, auth *util.AuthConfig) error {
+	return r.redisClient.SentinelFailover(sentinel, auth)
+}
+
+// SetActiveReplicaPeers makes the active replica replicate from all its peers
+func (r *RedisClusterHealer) SetActiveReplicaPeers(ip string, peers []string, auth *util.AuthConfig) error {
+	// forget the old peers, with multi master each replicaof adds one more master
+	if err := r.redisClient.MakeMaster(ip, auth); err != nil {
+		return err
+	}
+	for _, peer := range peers {
+		r.logger.V(2).Info(fmt.Sprintf("making active replica %s replicate from %s", ip, peer))
+		if err := r.redisClient.MakeSlaveOf(ip, peer, auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetExternalMaster makes the redis replicate from a master outside of the cluster, with the password of that master
+func (r *RedisClusterHealer) SetExternalMaster(ip string, host string, port string, masterAuth *util.AuthConfig, auth *util.AuthConfig) error {
+	if err := r.redisClient.SetCustomRedisConfig(ip, map[string]string{"masterauth": masterAuth.Password}, auth); err != nil {
+		return err
+	}
+	r.logger.V(2).Info(fmt.Sprintf("making redis %s replicate from %s:%s", ip, host, port))
+	return r.redisClient.MakeSlaveOfAddr(ip, host, port, auth)
+}
+
+// DetachExternalMaster makes the redis a master again, with the masterauth of its own cluster
+func (r *RedisClusterHealer) DetachExternalMaster(ip string, auth *util.AuthConfig) error {
+	if err := r.redisClient.MakeMaster(ip, auth); err != nil {
+		return err
+	}
+	return r.redisClient.SetCustomRedisConfig(ip, map[string]string{"masterauth": auth.Password}, auth)
+}
+
+// BgSave starts a background save of the RDB of the redis
+func (r *RedisClusterHealer) BgSave(ip string, auth *util.AuthConfig) error {
+	return r.redisClient.BgSave(ip, auth)
+}
+
 // SetOldestAsMaster puts all redis to the same master, choosen by order of appearance
 func (r *RedisClusterHealer) SetOldestAsMaster(rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
 	ssp, err := r.k8sService.GetStatefulSetPods(rc.Namespace, util.GetRedisName(rc))
@@ -86,6 +136,10 @@ func (r *RedisClusterHealer) SetMasterOnAll(masterIP string, rc *redisv1beta1.Re
 	}
 	for _, pod := range ssp.Items {
 		if pod.Status.PodIP == masterIP {
+			if rc.IsStandby() {
+				// the master of a standby keeps replicating from the external master
+				continue
+			}
 			r.logger.V(2).Info(fmt.Sprintf("ensure pod %s is master", pod.Name))
 			if err := r.redisClient.MakeMaster(masterIP, auth); err != nil {
 				return err
@@ -100,6 +154,62 @@ func (r *RedisClusterHealer) SetMasterOnAll(masterIP string, rc *redisv1beta1.Re
 	return nil
 }
 
+// SetMasterOnReadReplicas puts all read replicas as a slave of a given master
+func (r *RedisClusterHealer) SetMasterOnReadReplicas(masterIP string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	if !hasReadReplicas(rc) {
+		return nil
+	}
+	ssp, err := r.k8sService.GetStatefulSetPods(rc.Namespace, util.GetReadReplicaName(rc))
+	if err != nil {
+		return err
+	}
+	for _, pod := range ssp.Items {
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		// make sure the replica can never be promoted before it is known by the sentinels
+		if err := r.SetReadReplicaCustomConfig(pod.Status.PodIP, rc, auth); err != nil {
+			return err
+		}
+		r.logger.V(2).Info(fmt.Sprintf("making read replica %s slave of %s", pod.Name, masterIP))
+		if err := r.redisClient.MakeSlaveOf(pod.Status.PodIP, masterIP, auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRoleLabels labels every redis pod with the role it has, so the master and replica services
+// always select the current topology. The stale masters are relabeled first, the master service
+// never selects two pods during a failover.
+func (r *RedisClusterHealer) SetRoleLabels(masterIP string, rc *redisv1beta1.RedisCluster) error {
+	ssp, err := r.k8sService.GetStatefulSetPods(rc.Namespace, util.GetRedisName(rc))
+	if err != nil {
+		return err
+	}
+	for _, demote := range []bool{true, false} {
+		for _, pod := range ssp.Items {
+			if pod.Status.PodIP == "" {
+				continue
+			}
+			role := getPodRole(rc, pod.Status.PodIP, masterIP)
+			if pod.Labels[redisv1beta1.LabelRoleKey] == role || demote == (role == redisv1beta1.RoleMaster) {
+				continue
+			}
+			r.logger.V(2).Info(fmt.Sprintf("labeling pod %s as %s", pod.Name, role))
+			pod := pod.DeepCopy()
+			if pod.Labels == nil {
+				pod.Labels = map[string]string{}
+			}
+			pod.Labels[redisv1beta1.LabelRoleKey] = role
+			if err := r.k8sService.UpdatePod(rc.Namespace, pod); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // NewSentinelMonitor changes the master that Sentinel has to monitor
 func (r *RedisClusterHealer) NewSentinelMonitor(ip string, monitor string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
 	r.logger.V(2).Info("sentinel is not monitoring the correct master, changing...")
@@ -137,3 +247,22 @@ func (r *RedisClusterHealer) SetRedisCustomConfig(ip string, rc *redisv1beta1.Re
 
 	return r.redisClient.SetCustomRedisConfig(ip, rc.Spec.Config, auth)
 }
+
+// SetReadReplicaCustomConfig will call read replica to set the configuration given in config
+func (r *RedisClusterHealer) SetReadReplicaCustomConfig(ip string, rc *redisv1beta1.RedisCluster, auth *util.AuthConfig) error {
+	config := getReadReplicaConfig(rc)
+	r.logger.V(2).Info(fmt.Sprintf("setting the custom config on read replica %s: %v", ip, config))
+	return r.redisClient.SetCustomRedisConfig(ip, config, auth)
+}
+
+// getReadReplicaConfig returns the custom config with the settings that keep a read replica
+// out of the sentinel failover election
+func getReadReplicaConfig(rc *redisv1beta1.RedisCluster) map[string]string {
+	config := make(map[string]string, len(rc.Spec.Config)+2)
+	for k, v := range rc.Spec.Config {
+		config[k] = v
+	}
+	config["slave-priority"] = "0"
+	config["slave-read-only"] = "yes"
+	return config
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
+	"github.com/ucloud/redis-operator/pkg/client/k8s"
+)
+
+// fakeServices serves the redis pods of a test and records the labels they are updated with,
+// the methods a test does not expect panic on the nil embedded interface
+type fakeServices struct {
+	k8s.Services
+	pods    []corev1.Pod
+	updates []string
+}
+
+func (f *fakeServices) GetStatefulSetPods(namespace, name string) (*corev1.PodList, error) {
+	return &corev1.PodList{Items: f.pods}, nil
+}
+
+func (f *fakeServices) UpdatePod(namespace string, pod *corev1.Pod) error {
+	f.updates = append(f.updates, pod.Name+"="+pod.Labels[redisv1beta1.LabelRoleKey])
+	return nil
+}
+
+func newRolePod(name, ip, role string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.PodStatus{PodIP: ip},
+	}
+	if role != "" {
+		pod.Labels = map[string]string{redisv1beta1.LabelRoleKey: role}
+	}
+	return pod
+}
+
+func TestRedisClusterHealer_SetRoleLabels(t *testing.T) {
+	tests := []struct {
+		name          string
+		activeReplica bool
+		pods          []corev1.Pod
+		want          []string
+	}{
+		{
+			name: "labels in sync",
+			pods: []corev1.Pod{
+				newRolePod("redis-0", "10.0.0.1", redisv1beta1.RoleMaster),
+				newRolePod("redis-1", "10.0.0.2", redisv1beta1.RoleReplica),
+			},
+		},
+		{
+			name: "the old master is demoted before the new one is labeled",
+			pods: []corev1.Pod{
+				newRolePod("redis-0", "10.0.0.1", redisv1beta1.RoleReplica),
+				newRolePod("redis-1", "10.0.0.2", redisv1beta1.RoleMaster),
+				newRolePod("redis-2", "10.0.0.3", ""),
+			},
+			want: []string{"redis-1=replica", "redis-2=replica", "redis-0=master"},
+		},
+		{
+			name: "pods without an ip are skipped",
+			pods: []corev1.Pod{
+				newRolePod("redis-0", "", ""),
+				newRolePod("redis-1", "10.0.0.1", ""),
+			},
+			want: []string{"redis-1=master"},
+		},
+		{
+			name:          "active replicas are all masters",
+			activeReplica: true,
+			pods: []corev1.Pod{
+				newRolePod("redis-0", "10.0.0.1", redisv1beta1.RoleMaster),
+				newRolePod("redis-1", "10.0.0.2", redisv1beta1.RoleReplica),
+			},
+			want: []string{"redis-1=master"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := &fakeServices{pods: tt.pods}
+			r := NewRedisClusterHealer(services, nil, logf.NullLogger{})
+			rc := &redisv1beta1.RedisCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       redisv1beta1.RedisClusterSpec{ActiveReplica: tt.activeReplica},
+			}
+
+			if err := r.SetRoleLabels("10.0.0.1", rc); err != nil {
+				t.Fatalf("SetRoleLabels() error = %v", err)
+			}
+			if !reflect.DeepEqual(services.updates, tt.want) {
+				t.Errorf("SetRoleLabels() updates = %v, want %v", services.updates, tt.want)
+			}
+		})
+	}
+}
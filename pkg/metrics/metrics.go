@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -15,12 +17,20 @@ type Instrumenter interface {
 	SetClusterOK(namespace string, name string)
 	SetClusterError(namespace string, name string)
 	DeleteCluster(namespace string, name string)
+	SetClusterSuspended(namespace string, name string, suspended bool)
+	SetBackupLastSuccess(namespace string, name string, t time.Time)
+	DeleteBackupSchedule(namespace string, name string)
+	SetMigrationLag(namespace string, name string, lag int64)
+	DeleteMigration(namespace string, name string)
 }
 
 // PromMetrics implements the instrumenter so the metrics can be managed by Prometheus.
 type PromMetrics struct {
 	// Metrics fields.
-	clusterHealthy *prometheus.GaugeVec // clusterOk is the status of a cluster
+	clusterHealthy    *prometheus.GaugeVec // clusterOk is the status of a cluster
+	clusterSuspended  *prometheus.GaugeVec // clusterSuspended is 1 while a cluster is suspended
+	backupLastSuccess *prometheus.GaugeVec // backupLastSuccess is the completion time of the last backup of a schedule
+	migrationLag      *prometheus.GaugeVec // migrationLag is the replication lag of a migration
 
 	// Instrumentation fields.
 	registry prometheus.Registerer
@@ -36,7 +46,31 @@ func InitPrometheusMetrics(namespace string, registry *prometheus.Registry) {
 		Help:      "Status of redis clusters managed by the operator.",
 	}, []string{"namespace", "name"})
 
+	clusterSuspended := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: promControllerSubsystem,
+		Name:      "cluster_suspended",
+		Help:      "Suspension of redis clusters managed by the operator, a suspended cluster has no healthy status.",
+	}, []string{"namespace", "name"})
+
+	backupLastSuccess := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: promControllerSubsystem,
+		Name:      "backup_last_success_timestamp_seconds",
+		Help:      "Completion time of the last successful backup of the redis backup schedules.",
+	}, []string{"namespace", "name"})
+
+	migrationLag := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: promControllerSubsystem,
+		Name:      "migration_lag_bytes",
+		Help:      "Replication lag in bytes of the redis migrations.",
+	}, []string{"namespace", "name"})
+
 	ClusterMetrics.clusterHealthy = clusterHealthy
+	ClusterMetrics.clusterSuspended = clusterSuspended
+	ClusterMetrics.backupLastSuccess = backupLastSuccess
+	ClusterMetrics.migrationLag = migrationLag
 	ClusterMetrics.registry = registry
 
 	// Register metrics on prometheus.
@@ -46,6 +80,9 @@ func InitPrometheusMetrics(namespace string, registry *prometheus.Registry) {
 // register will register all the required prometheus metrics on the Prometheus collector.
 func (p *PromMetrics) register() {
 	p.registry.MustRegister(p.clusterHealthy)
+	p.registry.MustRegister(p.clusterSuspended)
+	p.registry.MustRegister(p.backupLastSuccess)
+	p.registry.MustRegister(p.migrationLag)
 }
 
 // SetClusterOK set the cluster status to OK
@@ -61,4 +98,36 @@ func (p *PromMetrics) SetClusterError(namespace string, name string) {
 // DeleteCluster set the cluster status to Error
 func (p *PromMetrics) DeleteCluster(namespace string, name string) {
 	p.clusterHealthy.DeleteLabelValues(namespace, name)
+	p.clusterSuspended.DeleteLabelValues(namespace, name)
+}
+
+// SetClusterSuspended set the suspension of the cluster, a suspended cluster is intentionally down
+// and is neither healthy nor in error
+func (p *PromMetrics) SetClusterSuspended(namespace string, name string, suspended bool) {
+	if suspended {
+		p.clusterHealthy.DeleteLabelValues(namespace, name)
+		p.clusterSuspended.WithLabelValues(namespace, name).Set(1)
+		return
+	}
+	p.clusterSuspended.WithLabelValues(namespace, name).Set(0)
+}
+
+// SetBackupLastSuccess set the completion time of the last successful backup of a schedule
+func (p *PromMetrics) SetBackupLastSuccess(namespace string, name string, t time.Time) {
+	p.backupLastSuccess.WithLabelValues(namespace, name).Set(float64(t.Unix()))
+}
+
+// DeleteBackupSchedule delete the metrics of a backup schedule
+func (p *PromMetrics) DeleteBackupSchedule(namespace string, name string) {
+	p.backupLastSuccess.DeleteLabelValues(namespace, name)
+}
+
+// SetMigrationLag set the replication lag of a migration
+func (p *PromMetrics) SetMigrationLag(namespace string, name string, lag int64) {
+	p.migrationLag.WithLabelValues(namespace, name).Set(float64(lag))
+}
+
+// DeleteMigration delete the metrics of a migration
+func (p *PromMetrics) DeleteMigration(namespace string, name string) {
+	p.migrationLag.DeleteLabelValues(namespace, name)
 }
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists ranges and steps", expr: "*/15 2-4,22 1-15/2 * 1-5"},
+		{name: "descriptor", expr: "@daily"},
+		{name: "sunday as 7", expr: "0 0 * * 7"},
+		{name: "missing field", expr: "0 0 * *", wantErr: true},
+		{name: "out of range", expr: "60 0 * * *", wantErr: true},
+		{name: "reversed range", expr: "0 5-2 * * *", wantErr: true},
+		{name: "invalid step", expr: "*/0 * * * *", wantErr: true},
+		{name: "not a number", expr: "a * * * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); (err != nil) != tt.wantErr {
+				t.Errorf("ParseCron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// 2019-08-01 is a thursday
+	from := time.Date(2019, 8, 1, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{
+			name: "every minute",
+			expr: "* * * * *",
+			want: time.Date(2019, 8, 1, 10, 8, 0, 0, time.UTC),
+		},
+		{
+			name: "every 15 minutes",
+			expr: "*/15 * * * *",
+			want: time.Date(2019, 8, 1, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "daily",
+			expr: "@daily",
+			want: time.Date(2019, 8, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "later today",
+			expr: "30 2,22 * * *",
+			want: time.Date(2019, 8, 1, 22, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday",
+			expr: "0 3 * * 7",
+			want: time.Date(2019, 8, 4, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 0 15 * 1",
+			want: time.Date(2019, 8, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next year",
+			expr: "0 0 1 1 *",
+			want: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			want: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never",
+			expr: "0 0 31 2 *",
+			want: time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron() error = %v", err)
+			}
+			if got := c.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AnnotationSpecHash annotation name for the hash of the spec the operator generated for a resource.
+	AnnotationSpecHash = "redis.kun/spec-hash"
+	// AnnotationConfigHash annotation name for the hash of the configuration the pods are started with.
+	AnnotationConfigHash = "redis.kun/config-hash"
+)
+
+// SpecHash returns a hash of the json representation of the given spec
+func SpecHash(spec interface{}) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	hasher := fnv.New64a()
+	hasher.Write(data)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// SetSpecHash stores the hash of spec in the annotations of the object
+func SetSpecHash(meta *metav1.ObjectMeta, spec interface{}) error {
+	hash, err := SpecHash(spec)
+	if err != nil {
+		return err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[AnnotationSpecHash] = hash
+	return nil
+}
+
+// SpecHashChanged returns true when the stored object was not generated from the same spec as the expected one
+func SpecHashChanged(stored, expect metav1.Object) bool {
+	return stored.GetAnnotations()[AnnotationSpecHash] != expect.GetAnnotations()[AnnotationSpecHash]
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSpecHash(t *testing.T) {
+	spec := corev1.ServiceSpec{
+		Type:     corev1.ServiceTypeClusterIP,
+		Selector: map[string]string{"app": "redis"},
+	}
+	hash1, err := SpecHash(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash2, err := SpecHash(*spec.DeepCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash1 != hash2 {
+		t.Errorf("SpecHash() of equal specs got %s and %s", hash1, hash2)
+	}
+
+	spec.Selector["app"] = "sentinel"
+	hash3, err := SpecHash(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash1 == hash3 {
+		t.Errorf("SpecHash() of different specs got the same hash %s", hash1)
+	}
+}
+
+func TestSpecHashChanged(t *testing.T) {
+	spec := corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}
+	stored := &metav1.ObjectMeta{}
+	expect := &metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}
+	if err := SetSpecHash(expect, spec); err != nil {
+		t.Fatal(err)
+	}
+	if expect.Annotations["foo"] != "bar" {
+		t.Errorf("SetSpecHash() removed the existing annotations")
+	}
+	if !SpecHashChanged(stored, expect) {
+		t.Errorf("SpecHashChanged() = false for an object without hash")
+	}
+	if err := SetSpecHash(stored, spec); err != nil {
+		t.Fatal(err)
+	}
+	if SpecHashChanged(stored, expect) {
+		t.Errorf("SpecHashChanged() = true for objects generated from the same spec")
+	}
+}
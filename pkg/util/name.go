@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"path"
 
 	redisv1beta1 "github.com/ucloud/redis-operator/pkg/apis/redis/v1beta1"
 )
@@ -15,10 +16,24 @@ const (
 	RedisName              = "-cluster"
 	RedisShutdownName      = "r-s"
 	RedisRoleName          = "redis"
+	RedisMasterName        = "-master"
+	RedisReplicaName       = "-replica"
+	ReadReplicaName        = "-read-replica"
+	ReadReplicaRoleName    = "read-replica"
+	BackupName             = "-backup"
+	BackupCleanupName      = "-backup-cleanup"
+	RestoreContainerName   = "restore"
 	AppLabel               = "redis-cluster"
 	HostnameTopologyKey    = "kubernetes.io/hostname"
 )
 
+// the containers and the object names of the point-in-time recovery
+const (
+	RestoreAOFContainerName = "restore-aof"
+	AOFShipperContainerName = "aof-shipper"
+	PITRName                = "aof"
+)
+
 // GetRedisShutdownConfigMapName returns the name for redis configmap
 func GetRedisShutdownConfigMapName(rc *redisv1beta1.RedisCluster) string {
 	if rc.Spec.ShutdownConfigMap != "" {
@@ -32,6 +47,21 @@ func GetRedisName(rc *redisv1beta1.RedisCluster) string {
 	return GenerateName(RedisName, rc.Name)
 }
 
+// GetRedisMasterName returns the name for the service of the current redis master
+func GetRedisMasterName(rc *redisv1beta1.RedisCluster) string {
+	return GenerateName(RedisMasterName, rc.Name)
+}
+
+// GetRedisReplicaName returns the name for the service of the current redis replicas
+func GetRedisReplicaName(rc *redisv1beta1.RedisCluster) string {
+	return GenerateName(RedisReplicaName, rc.Name)
+}
+
+// GetReadReplicaName returns the name for read replica resources
+func GetReadReplicaName(rc *redisv1beta1.RedisCluster) string {
+	return GenerateName(ReadReplicaName, rc.Name)
+}
+
 // GetRedisShutdownName returns the name for redis resources
 func GetRedisShutdownName(rc *redisv1beta1.RedisCluster) string {
 	return GenerateName(RedisShutdownName, rc.Name)
@@ -42,6 +72,27 @@ func GetSentinelName(rc *redisv1beta1.RedisCluster) string {
 	return GenerateName(SentinelName, rc.Name)
 }
 
+// GetFirstRedisPodName returns the name of the first redis pod, the one loaded from the RDB of a restore
+// or replicating from the source of a clone
+func GetFirstRedisPodName(rc *redisv1beta1.RedisCluster) string {
+	return fmt.Sprintf("%s-0", GetRedisName(rc))
+}
+
+// GetPITRPrefix returns where the AOF files of the cluster are shipped in the bucket
+func GetPITRPrefix(rc *redisv1beta1.RedisCluster) string {
+	return path.Join(rc.Spec.PITR.Storage.S3.Prefix, rc.Namespace, rc.Name, PITRName)
+}
+
+// GetBackupJobName returns the name for the job uploading a backup
+func GetBackupJobName(backup *redisv1beta1.RedisBackup) string {
+	return GenerateName(BackupName, backup.Name)
+}
+
+// GetBackupCleanupJobName returns the name for the job deleting the snapshot of a backup
+func GetBackupCleanupJobName(backup *redisv1beta1.RedisBackup) string {
+	return GenerateName(BackupCleanupName, backup.Name)
+}
+
 func GenerateName(typeName, metaName string) string {
 	return fmt.Sprintf("%s%s-%s", BaseName, typeName, metaName)
 }
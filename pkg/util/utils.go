@@ -3,6 +3,9 @@ package util
 import (
 	"strconv"
 	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -25,6 +28,70 @@ func SetClusterScoped(namespace string) {
 	}
 }
 
+// ShouldManage returns false if the object is ignored (not managed) by this operator,
+// a cluster-scoped operator only manages the objects with the cluster-scoped annotation
+func ShouldManage(meta metav1.Object) bool {
+	if v, ok := meta.GetAnnotations()[AnnotationScope]; ok {
+		if IsClusterScoped() {
+			return v == AnnotationClusterScoped
+		}
+	} else {
+		if !IsClusterScoped() {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsString returns true when the slice contains the string, like a finalizer
+func ContainsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveString returns the slice without the string
+func RemoveString(slice []string, s string) []string {
+	result := make([]string, 0, len(slice))
+	for _, item := range slice {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// S3CredentialsEnv returns the env of the S3 credentials used by mc, read from the keys
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY of the given secret
+func S3CredentialsEnv(secret string) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		env = append(env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+					Key:                  key,
+				},
+			},
+		})
+	}
+	return env
+}
+
+// IsPodReady returns true if the pod has the Ready condition
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
 func ParseRedisMemConf(p string) (string, error) {
 	var mul int64 = 1
 	u := strings.ToLower(p)
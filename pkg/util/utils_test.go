@@ -114,3 +114,16 @@ func TestParseRedisMemConf(t *testing.T) {
 		})
 	}
 }
+
+func TestS3CredentialsEnv(t *testing.T) {
+	env := S3CredentialsEnv("s3-creds")
+	if len(env) != 2 {
+		t.Fatalf("S3CredentialsEnv() returned %d vars, want 2", len(env))
+	}
+	for i, want := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		ref := env[i].ValueFrom.SecretKeyRef
+		if env[i].Name != want || ref.Name != "s3-creds" || ref.Key != want {
+			t.Errorf("S3CredentialsEnv()[%d] = %s from %s/%s, want %s from s3-creds/%s", i, env[i].Name, ref.Name, ref.Key, want, want)
+		}
+	}
+}